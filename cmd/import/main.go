@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/importer"
+	"github.com/fgiudicatti-meli/web-server/internal/inventory"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/sheet"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+// import da de alta en el store los productos de una planilla csv o xlsx,
+// con las mismas validaciones que POST /products/import. Escribe el reporte
+// fila por fila como json en la salida estandar y termina con error si
+// alguna fila no se pudo agregar.
+//
+//	go run ./cmd/import -file productos.xlsx -columns name=Nombre,code_value=SKU -dry-run
+//
+// Con -movements las altas quedan en el libro de inventario; si no, el
+// server las registra como ajuste al reconciliar el stock cuando arranca.
+func main() {
+	file := flag.String("file", "", "csv or xlsx file to import")
+	columns := flag.String("columns", "", "column mapping as field=header,... (by default the header is the field name)")
+	dryRun := flag.Bool("dry-run", false, "only validate the rows")
+	atomic := flag.Bool("atomic", false, "import every row or none")
+	backend := flag.String("backend", "json", "store backend: json, sqlite, ...")
+	dsn := flag.String("dsn", "products.json", "store dsn")
+	movements := flag.String("movements", "", "inventory movements file to record the new stock in")
+	currency := flag.String("currency", "", "currency of the prices (default "+money.DefaultCurrency()+")")
+	actor := flag.String("actor", "import", "actor recorded in the inventory movements")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+	if *currency != "" {
+		if err := money.SetDefaultCurrency(*currency); err != nil {
+			log.Fatal("Error in -currency: ", err)
+		}
+	}
+	mapping, err := importer.ParseMapping(*columns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows, err := sheet.Read(f, sheet.Limits{})
+	f.Close()
+	if err != nil {
+		log.Fatal("Error reading ", *file, ": ", err)
+	}
+
+	storage, err := store.Open(*backend, store.Config{DSN: *dsn})
+	if err != nil {
+		log.Fatal("Error opening store: ", err)
+	}
+	repo := product.NewRepository(storage)
+	if *movements != "" {
+		repo = inventory.NewService(repo, store.NewJournal[domain.Movement](*movements)).Products()
+	}
+	ctx := inventory.WithActor(context.Background(), *actor)
+	report, err := importer.NewService(product.NewService(repo)).Import(ctx, rows, importer.Options{
+		Mapping: mapping,
+		DryRun:  *dryRun,
+		Atomic:  *atomic,
+	})
+	if err != nil {
+		log.Fatal("Error importing ", *file, ": ", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d rows: %d valid, %d invalid, %d conflicts, %d created", report.Rows, report.Valid, report.Invalid, report.Conflicts, report.Created)
+	if report.Valid < report.Rows || (!report.DryRun && report.Created < report.Rows) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+// migrate importa un products.json existente en una base sqlite,
+// conservando los ids de cada producto.
+//
+//	go run ./cmd/migrate -from products.json -to products.db
+//
+// Con -dates reescribe en el lugar las fechas de expiracion de -from en el
+// formato -date-format, y falla listando las fechas invalidas si las hay.
+//
+//	go run ./cmd/migrate -from products.json -dates -date-format yyyy-mm-dd
+func main() {
+	from := flag.String("from", "products.json", "json file to import")
+	to := flag.String("to", "products.db", "sqlite database to create or fill")
+	dates := flag.Bool("dates", false, "rewrite the expiration dates of -from instead of importing")
+	dateFormat := flag.String("date-format", "dd/mm/yyyy", "format for -dates: dd/mm/yyyy, yyyy-mm-dd or rfc3339")
+	flag.Parse()
+
+	if *dates {
+		if err := domain.SetDateFormat(*dateFormat); err != nil {
+			log.Fatal(err)
+		}
+		n, err := store.MigrateJSONDates(*from)
+		if err != nil {
+			log.Fatal("Error migrating dates in ", *from, ": ", err)
+		}
+		log.Printf("rewrote the expiration of %d products in %s as %s", n, *from, *dateFormat)
+		return
+	}
+
+	ctx := context.Background()
+	products, err := store.NewStore(*from).GetAll(ctx)
+	if err != nil {
+		log.Fatal("Error reading ", *from, ": ", err)
+	}
+
+	db, err := store.NewSQLiteStore(*to)
+	if err != nil {
+		log.Fatal("Error opening ", *to, ": ", err)
+	}
+	importer, ok := db.(store.Importer)
+	if !ok {
+		log.Fatal("sqlite store can't import products")
+	}
+	if err := importer.Import(ctx, products); err != nil {
+		log.Fatal("Error importing products: ", err)
+	}
+	log.Printf("imported %d products from %s into %s", len(products), *from, *to)
+}
@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxBulkItems acota la cantidad de items de un request en lote
+const maxBulkItems = 1000
+
+// BulkUpdateRequest es un item de PATCH /products/bulk: los campos de
+// Request que vienen se aplican sobre el producto Id. Con Version distinto
+// de 0 el producto tiene que seguir en esa version.
+type BulkUpdateRequest struct {
+	Id      int `json:"id"`
+	Version int `json:"version,omitempty"`
+	Request
+}
+
+// BulkItemResult es el resultado de un item: Status es el que tendria el
+// request individual equivalente
+type BulkItemResult struct {
+	Index   int             `json:"index"`
+	Status  int             `json:"status"`
+	Id      int             `json:"id,omitempty"`
+	Product *domain.Product `json:"product,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	// Code y Errors son los mismos que tendria la respuesta de error del
+	// request individual
+	Code   string              `json:"code,omitempty"`
+	Errors []domain.FieldError `json:"errors,omitempty"`
+}
+
+// fail registra en el resultado el error del item
+func (r *BulkItemResult) fail(status int, err error) {
+	r.Status, r.Error, r.Code = status, err.Error(), web.ErrorCode(status, err)
+	if e, ok := domain.AsError(err); ok {
+		r.Errors = e.Fields
+	}
+}
+
+// BulkReport es la respuesta de los endpoints en lote
+type BulkReport struct {
+	Atomic    bool             `json:"atomic"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// bulkRequest lee el parametro atomic y el arreglo de items del body; si
+// algo no es valido ya respondio 400
+func bulkRequest(ctx *gin.Context) (bool, []json.RawMessage, bool) {
+	atomic := false
+	if value := ctx.Query("atomic"); value != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(value); err != nil {
+			web.Failure(ctx, http.StatusBadRequest, errors.New("atomic must be true or false"))
+			return false, nil, false
+		}
+	}
+	var items []json.RawMessage
+	if err := ctx.ShouldBindJSON(&items); err != nil {
+		web.Failure(ctx, http.StatusBadRequest, errors.New("invalid json: the body must be an array"))
+		return false, nil, false
+	}
+	if len(items) == 0 || len(items) > maxBulkItems {
+		web.Failure(ctx, http.StatusBadRequest, fmt.Errorf("the body must have between 1 and %d items", maxBulkItems))
+		return false, nil, false
+	}
+	return atomic, items, true
+}
+
+// bulkItemStatus devuelve el status de un item que fallo: el del error,
+// salvo los que no se aplicaron porque fallo otro item del lote atomico
+func bulkItemStatus(err error) int {
+	if errors.Is(err, product.ErrAborted) {
+		return http.StatusFailedDependency
+	}
+	return web.Status(err)
+}
+
+// bulk arma el reporte de un request en lote. invalid tiene el error de
+// validacion de cada item (nil si es valido); apply recibe las posiciones
+// de los validos y devuelve sus resultados en el mismo orden. Con atomic y
+// algun item invalido no se aplica nada.
+func bulk(ctx *gin.Context, atomic bool, invalid []error, status int, apply func(valid []int) ([]product.BulkResult, error)) {
+	report := BulkReport{Atomic: atomic, Results: make([]BulkItemResult, len(invalid))}
+	var valid []int
+	for i, err := range invalid {
+		report.Results[i].Index = i
+		if err != nil {
+			report.Results[i].fail(http.StatusBadRequest, err)
+			continue
+		}
+		valid = append(valid, i)
+	}
+	var results []product.BulkResult
+	if atomic && len(valid) < len(invalid) {
+		results = make([]product.BulkResult, len(valid))
+		for j := range results {
+			results[j].Err = product.ErrAborted
+		}
+	} else if len(valid) > 0 {
+		var err error
+		if results, err = apply(valid); err != nil {
+			web.Error(ctx, err)
+			return
+		}
+	}
+	for j, r := range results {
+		item := &report.Results[valid[j]]
+		if r.Err != nil {
+			item.fail(bulkItemStatus(r.Err), r.Err)
+			continue
+		}
+		p := r.Product
+		item.Status, item.Id, item.Product = status, p.Id, &p
+	}
+	for _, item := range report.Results {
+		if item.Error == "" {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	switch {
+	case report.Failed == 0:
+	case report.Succeeded == 0:
+		status = http.StatusUnprocessableEntity
+	default:
+		status = http.StatusMultiStatus
+	}
+	web.Success(ctx, status, report)
+}
+
+// BulkCreate documentation with Swagger
+// BulkCreate godoc
+// @Summary Create many products
+// @Tags Products
+// @Description create every product of the array in a single store write; each item is validated like POST /products and code_value must be unique also within the batch. The response has one result per item; with atomic=true no product is created if any item fails.
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param atomic query bool false "All or nothing"
+// @Param products body []domain.Product true "Products"
+// @Success 201 {object} web.Response{data=BulkReport}
+// @Success 207 {object} web.Response{data=BulkReport}
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 422 {object} web.Response{data=BulkReport}
+// @Router /products/bulk [post]
+func (h *productHandler) BulkCreate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		atomic, items, ok := bulkRequest(ctx)
+		if !ok {
+			return
+		}
+		products := make([]domain.Product, len(items))
+		invalid := make([]error, len(items))
+		for i, raw := range items {
+			if err := binding.JSON.BindBody(raw, &products[i]); err != nil {
+				invalid[i] = bindError(err)
+				continue
+			}
+			invalid[i] = product.Validate(products[i])
+		}
+		bulk(ctx, atomic, invalid, http.StatusCreated, func(valid []int) ([]product.BulkResult, error) {
+			batch := make([]domain.Product, len(valid))
+			for j, i := range valid {
+				batch[j] = products[i]
+			}
+			return h.service.CreateMany(ctx.Request.Context(), batch, atomic)
+		})
+	}
+}
+
+// BulkUpdate documentation with Swagger
+// BulkUpdate godoc
+// @Summary Partially update many products
+// @Tags Products
+// @Description apply each item like PATCH /products/{id} in a single store write; version, if present, must match the stored one. With atomic=true no product is updated if any item fails.
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param atomic query bool false "All or nothing"
+// @Param updates body []BulkUpdateRequest true "Updates"
+// @Success 200 {object} web.Response{data=BulkReport}
+// @Success 207 {object} web.Response{data=BulkReport}
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 422 {object} web.Response{data=BulkReport}
+// @Router /products/bulk [patch]
+func (h *productHandler) BulkUpdate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		atomic, items, ok := bulkRequest(ctx)
+		if !ok {
+			return
+		}
+		updates := make([]domain.Product, len(items))
+		invalid := make([]error, len(items))
+		for i, raw := range items {
+			var r BulkUpdateRequest
+			if err := binding.JSON.BindBody(raw, &r); err != nil {
+				invalid[i] = bindError(err)
+				continue
+			}
+			updates[i] = domain.Product{
+				Id:          r.Id,
+				Name:        r.Name,
+				Quantity:    r.Quantity,
+				CodeValue:   r.CodeValue,
+				IsPublished: r.IsPublished,
+				Expiration:  r.Expiration,
+				Price:       r.Price,
+				Version:     r.Version,
+			}
+			if r.Id <= 0 {
+				invalid[i] = invalidField("id", "required", "id is required")
+				continue
+			}
+			invalid[i] = product.ValidateChanges(updates[i])
+		}
+		bulk(ctx, atomic, invalid, http.StatusOK, func(valid []int) ([]product.BulkResult, error) {
+			batch := make([]domain.Product, len(valid))
+			for j, i := range valid {
+				batch[j] = updates[i]
+			}
+			return h.service.UpdateMany(ctx.Request.Context(), batch, atomic)
+		})
+	}
+}
+
+// BulkDelete documentation with Swagger
+// BulkDelete godoc
+// @Summary Delete many products
+// @Tags Products
+// @Description delete the products with the ids of the array in a single store write; products with reserved units fail with 409. With atomic=true no product is deleted if any item fails.
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param atomic query bool false "All or nothing"
+// @Param ids body []int true "Product ids"
+// @Success 200 {object} web.Response{data=BulkReport}
+// @Success 207 {object} web.Response{data=BulkReport}
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 422 {object} web.Response{data=BulkReport}
+// @Router /products/bulk [delete]
+func (h *productHandler) BulkDelete() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		atomic, items, ok := bulkRequest(ctx)
+		if !ok {
+			return
+		}
+		ids := make([]int, len(items))
+		invalid := make([]error, len(items))
+		for i, raw := range items {
+			if err := json.Unmarshal(raw, &ids[i]); err != nil || ids[i] <= 0 {
+				invalid[i] = errors.New("invalid id")
+			}
+		}
+		bulk(ctx, atomic, invalid, http.StatusOK, func(valid []int) ([]product.BulkResult, error) {
+			batch := make([]int, len(valid))
+			for j, i := range valid {
+				batch[j] = ids[i]
+			}
+			return h.service.DeleteMany(ctx.Request.Context(), batch, atomic)
+		})
+	}
+}
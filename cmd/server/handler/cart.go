@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/fgiudicatti-meli/web-server/internal/cart"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type cartHandler struct {
+	service cart.Service
+}
+
+// NewCartHandler crea un nuevo controller de carritos
+func NewCartHandler(s cart.Service) *cartHandler {
+	return &cartHandler{
+		service: s,
+	}
+}
+
+type CartRequest struct {
+	Lines []cart.Line `json:"lines"`
+	Promo string      `json:"promo,omitempty"`
+}
+
+type CartLineRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+type CartPromoRequest struct {
+	Code string `json:"code"`
+}
+
+// checkToken valida el header token; si no es valido ya respondio 401
+func checkToken(ctx *gin.Context) bool {
+	if ctx.Request.Header.Get("token") != os.Getenv("TOKEN") {
+		web.Failure(ctx, http.StatusUnauthorized, errors.New("invalid token"))
+		return false
+	}
+	return true
+}
+
+// intParam lee un parametro de ruta numerico; si no es valido ya respondio
+// 400
+func intParam(ctx *gin.Context, name string) (int, bool) {
+	n, err := strconv.Atoi(ctx.Param(name))
+	if err != nil {
+		web.Failure(ctx, http.StatusBadRequest, errors.New("invalid "+name))
+		return 0, false
+	}
+	return n, true
+}
+
+// Create documentation with Swagger
+// Create godoc
+// @Summary Create a cart
+// @Tags Carts
+// @Description create an open cart, optionally with lines and a promo code; every line must reference a published, non-expired product with enough stock
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param cart body CartRequest false "Initial lines and promo code"
+// @Success 201 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /carts [post]
+func (h *cartHandler) Create() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		var req CartRequest
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.ShouldBindJSON(&req); err != nil {
+				web.Failure(ctx, http.StatusBadRequest, errInvalidJSON)
+				return
+			}
+		}
+		c, err := h.service.Create(ctx.Request.Context(), req.Lines, req.Promo)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusCreated, c)
+	}
+}
+
+// Get documentation with Swagger
+// Get godoc
+// @Summary Get a cart
+// @Tags Carts
+// @Description get a cart; open carts are re-priced with the current prices and rules, locked carts keep their quote
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Cart id"
+// @Success 200 {object} web.Response
+// @Failure 404 {object} web.ErrorResponse
+// @Router /carts/{id} [get]
+func (h *cartHandler) Get() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		c, err := h.service.Get(ctx.Request.Context(), id)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, c)
+	}
+}
+
+// SetLine documentation with Swagger
+// SetLine godoc
+// @Summary Set the units of a product in a cart
+// @Tags Carts
+// @Description add a product to an open cart or change its units; quantity 0 removes it
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Cart id"
+// @Param product_id path int true "Product id"
+// @Param line body CartLineRequest true "Units"
+// @Success 200 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /carts/{id}/lines/{product_id} [put]
+func (h *cartHandler) SetLine() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		productId, ok := intParam(ctx, "product_id")
+		if !ok {
+			return
+		}
+		var req CartLineRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			web.Failure(ctx, http.StatusBadRequest, errInvalidJSON)
+			return
+		}
+		c, err := h.service.SetLine(ctx.Request.Context(), id, productId, req.Quantity)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, c)
+	}
+}
+
+// RemoveLine documentation with Swagger
+// RemoveLine godoc
+// @Summary Remove a product from a cart
+// @Tags Carts
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Cart id"
+// @Param product_id path int true "Product id"
+// @Success 200 {object} web.Response
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /carts/{id}/lines/{product_id} [delete]
+func (h *cartHandler) RemoveLine() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		productId, ok := intParam(ctx, "product_id")
+		if !ok {
+			return
+		}
+		c, err := h.service.SetLine(ctx.Request.Context(), id, productId, 0)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, c)
+	}
+}
+
+// SetPromo documentation with Swagger
+// SetPromo godoc
+// @Summary Set the promo code of a cart
+// @Tags Carts
+// @Description an empty code removes the promo
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Cart id"
+// @Param promo body CartPromoRequest true "Promo code"
+// @Success 200 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /carts/{id}/promo [put]
+func (h *cartHandler) SetPromo() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		var req CartPromoRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			web.Failure(ctx, http.StatusBadRequest, errInvalidJSON)
+			return
+		}
+		c, err := h.service.SetPromo(ctx.Request.Context(), id, req.Code)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, c)
+	}
+}
+
+// Lock documentation with Swagger
+// Lock godoc
+// @Summary Lock the quote of a cart
+// @Tags Carts
+// @Description validate every line and freeze the quote until locked_until; a locked cart can't be modified until it is ordered or the lock expires
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Cart id"
+// @Success 200 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /carts/{id}/lock [post]
+func (h *cartHandler) Lock() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		c, err := h.service.Lock(ctx.Request.Context(), id)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, c)
+	}
+}
+
+// Checkout documentation with Swagger
+// Checkout godoc
+// @Summary Convert a locked cart into an order
+// @Tags Carts
+// @Description the lines are validated again against the current stock; the response includes the order id
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Cart id"
+// @Success 200 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /carts/{id}/checkout [post]
+func (h *cartHandler) Checkout() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		c, err := h.service.Checkout(ctx.Request.Context(), id)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, c)
+	}
+}
+
+// Delete documentation with Swagger
+// Delete godoc
+// @Summary Delete a cart
+// @Tags Carts
+// @Param token header string true "token"
+// @Param id path int true "Cart id"
+// @Success 204
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /carts/{id} [delete]
+func (h *cartHandler) Delete() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		if err := h.service.Delete(ctx.Request.Context(), id); err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+// etag arma el ETag de un producto a partir de su version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag publica la version del producto en el header ETag
+func setETag(ctx *gin.Context, p domain.Product) {
+	ctx.Header("ETag", etag(p.Version))
+}
+
+// checkIfMatch devuelve la version de If-Match, o false si ya respondio 412 o 428
+func checkIfMatch(ctx *gin.Context, current domain.Product) (int, bool) {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		if os.Getenv("REQUIRE_IF_MATCH") == "true" {
+			web.Failure(ctx, http.StatusPreconditionRequired, errors.New("If-Match header is required"))
+			return 0, false
+		}
+		return 0, true
+	}
+	want := etag(current.Version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == want {
+			return current.Version, true
+		}
+	}
+	web.Failure(ctx, http.StatusPreconditionFailed, fmt.Errorf("%w, current version is %s", product.ErrVersionMismatch, want))
+	return 0, false
+}
+
+// validators identifican una representacion para los GET condicionales
+type validators struct {
+	etag         string
+	lastModified time.Time
+}
+
+// set escribe ETag y Last-Modified en la respuesta
+func (v validators) set(ctx *gin.Context) {
+	if v.etag != "" {
+		ctx.Header("ETag", v.etag)
+	}
+	if !v.lastModified.IsZero() {
+		ctx.Header("Last-Modified", v.lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified evalua If-None-Match y, si no viene, If-Modified-Since. Cuando
+// el cliente ya tiene la representacion actual responde 304 y devuelve true.
+func notModified(ctx *gin.Context, v validators) bool {
+	v.set(ctx)
+	if header := ctx.GetHeader("If-None-Match"); header != "" {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == strings.TrimPrefix(v.etag, "W/") {
+				ctx.AbortWithStatus(http.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+	if header := ctx.GetHeader("If-Modified-Since"); header != "" && !v.lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		if err == nil && !v.lastModified.Truncate(time.Second).After(since) {
+			ctx.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// collectionETag arma el ETag de un listado a partir de la revision del
+// store y la query, ya que distintos parametros dan distintos cuerpos
+func collectionETag(revision, query string) string {
+	sum := sha256.Sum256([]byte(revision + "?" + query))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// contentETag arma el ETag hasheando el contenido; se usa cuando el store no
+// informa su revision
+func contentETag(data any) string {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(bytes)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// errInvalidJSON indica un body que no se puede leer como json
+	errInvalidJSON = domain.Invalid("invalid_json", "invalid json")
+	// errNoResults indica una busqueda sin resultados
+	errNoResults = domain.NotFound("product_not_found", "product not found")
+)
+
+// invalidField crea un error de validacion de producto para un campo
+func invalidField(field, code, message string) error {
+	return product.ErrInvalidProduct.WithFields(domain.FieldError{Field: field, Code: code, Message: message})
+}
+
+// bindError describe el error de ShouldBindJSON: las fechas y precios
+// invalidos, los campos con un tipo equivocado y los desconocidos se
+// informan como errores de ese campo y el resto como json invalido. Las
+// reglas de cada campo las aplica product.Validate despues.
+func bindError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, domain.ErrInvalidDate):
+		return invalidField("expiration", "invalid_date", "invalid expiration: "+err.Error())
+	case errors.Is(err, money.ErrInvalid):
+		return invalidField("price", "invalid_price", "invalid price: "+err.Error())
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return invalidField(typeErr.Field, "invalid_type", typeErr.Field+" must be "+jsonType(typeErr.Type))
+	}
+	// encoding/json no tiene un tipo para los campos desconocidos
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if field, err := strconv.Unquote(name); err == nil {
+			return invalidField(field, "unknown", "unknown field "+field)
+		}
+	}
+	return errInvalidJSON
+}
+
+// jsonType nombra el tipo json que corresponde a un tipo de Go
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// stockFailure responde el error de una operacion que cambia el stock por su
+// cuenta (carritos, ordenes y movimientos). Ahi un cambio de version no es
+// una precondicion que mando el cliente sino otro cambio concurrente, asi
+// que es un 409 y no un 412.
+func stockFailure(ctx *gin.Context, err error) {
+	if errors.Is(err, product.ErrVersionMismatch) {
+		web.Failure(ctx, http.StatusConflict, err)
+		return
+	}
+	web.Error(ctx, err)
+}
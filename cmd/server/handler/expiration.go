@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+// maxExpiringDays acota la ventana de GET /products/expiring
+const maxExpiringDays = 3650
+
+// Expired documentation with Swagger
+// Expired godoc
+// @Summary List expired products
+// @Tags Products
+// @Description products whose expiration date is before today; accepts the same filter, pagination, sort and fields parameters as GET /products
+// @Produce json
+// @Param token header string true "token"
+// @Param filter query string false "Filter expression"
+// @Param limit query int false "Page size (max 1000)"
+// @Param cursor query string false "Opaque cursor from pagination.next_cursor"
+// @Param sort query string false "Sort keys, - for descending (e.g. expiration,-name)"
+// @Success 200 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Router /products/expired [get]
+func (h *productHandler) Expired() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.Request.Header.Get("token")
+		if token != os.Getenv("TOKEN") {
+			web.Failure(ctx, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		q, err := query.Parse(ctx.Request.URL.Query())
+		if err != nil {
+			web.Failure(ctx, http.StatusBadRequest, err)
+			return
+		}
+		page, err := h.service.ListExpired(ctx.Request.Context(), domain.Today(), q)
+		respondPage(ctx, q, page, err)
+	}
+}
+
+// Expiring documentation with Swagger
+// Expiring godoc
+// @Summary List products expiring soon
+// @Tags Products
+// @Description products not expired yet whose expiration date is within the next days; accepts the same filter, pagination, sort and fields parameters as GET /products
+// @Produce json
+// @Param token header string true "token"
+// @Param days query int true "Days from today (1 to 3650)"
+// @Param filter query string false "Filter expression"
+// @Param limit query int false "Page size (max 1000)"
+// @Param cursor query string false "Opaque cursor from pagination.next_cursor"
+// @Param sort query string false "Sort keys, - for descending (e.g. expiration,-name)"
+// @Success 200 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Router /products/expiring [get]
+func (h *productHandler) Expiring() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.Request.Header.Get("token")
+		if token != os.Getenv("TOKEN") {
+			web.Failure(ctx, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		days, err := strconv.Atoi(ctx.Query("days"))
+		if err != nil || days < 1 || days > maxExpiringDays {
+			web.Failure(ctx, http.StatusBadRequest, errors.New("days must be a number between 1 and 3650"))
+			return
+		}
+		q, err := query.Parse(ctx.Request.URL.Query())
+		if err != nil {
+			web.Failure(ctx, http.StatusBadRequest, err)
+			return
+		}
+		page, err := h.service.ListExpiring(ctx.Request.Context(), domain.Today(), days, q)
+		respondPage(ctx, q, page, err)
+	}
+}
+
+// Expire documentation with Swagger
+// Expire godoc
+// @Summary Run the expiration job now
+// @Tags Products
+// @Description unpublishes (or only flags) the expired products that were not processed yet, recording the reason in lifecycle_reason. The default action comes from EXPIRATION_ACTION.
+// @Produce json
+// @Param token header string true "token"
+// @Param action query string false "unpublish or flag"
+// @Success 200 {object} web.Response{data=product.ExpireReport}
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 500 {object} web.ErrorResponse
+// @Router /products/expire [post]
+func (h *productHandler) Expire() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.Request.Header.Get("token")
+		if token != os.Getenv("TOKEN") {
+			web.Failure(ctx, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		name := ctx.Query("action")
+		if name == "" {
+			name = os.Getenv("EXPIRATION_ACTION")
+		}
+		action, err := product.ParseExpireAction(name)
+		if err != nil {
+			web.Failure(ctx, http.StatusBadRequest, err)
+			return
+		}
+		report, err := h.service.ExpireProducts(ctx.Request.Context(), domain.Today(), action)
+		if err != nil {
+			web.Error(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, report)
+	}
+}
+
+// respondPage responde una pagina de productos con su paginacion y links
+func respondPage(ctx *gin.Context, q query.Query, page query.Page, err error) {
+	if errors.Is(err, query.ErrInvalid) {
+		web.Failure(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		web.Error(ctx, err)
+		return
+	}
+	pagination := web.Pagination{Total: page.Total, Limit: q.Limit, Offset: q.Offset, NextCursor: page.NextCursor}
+	web.SuccessPage(ctx, http.StatusOK, projectFields(page.Items, q.Fields), pagination, pageLinks(ctx.Request.URL, q, page))
+}
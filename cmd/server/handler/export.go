@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/importer"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+// exportFields son las columnas de un csv exportado sin fields; los nombres
+// son los mismos que acepta la importacion
+var exportFields = []string{"id", "name", "quantity", "code_value", "is_published", "expiration", "price", "version", "lifecycle_reason", "reserved"}
+
+// exportErrorTrailer es el trailer que informa un error despues de haber
+// empezado a enviar los productos, cuando ya no se puede cambiar el status
+const exportErrorTrailer = "X-Export-Error"
+
+// exportWriter escribe los productos de una exportacion en un formato
+type exportWriter interface {
+	write(p domain.Product) error
+	// close completa el documento; no cierra el writer de abajo
+	close() error
+}
+
+// exportFormats son los formatos de GET /products/export con su content
+// type
+var exportFormats = map[string]struct {
+	contentType string
+	writer      func(w io.Writer, fields []string) exportWriter
+}{
+	"csv":    {"text/csv; charset=utf-8", newCSVExport},
+	"ndjson": {"application/x-ndjson", newNDJSONExport},
+	"json":   {"application/json; charset=utf-8", newJSONExport},
+}
+
+type csvExport struct {
+	w      *csv.Writer
+	fields []string
+	header bool
+}
+
+func newCSVExport(w io.Writer, fields []string) exportWriter {
+	if len(fields) == 0 {
+		fields = exportFields
+	}
+	return &csvExport{w: csv.NewWriter(w), fields: fields}
+}
+
+func (e *csvExport) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.w.Write(e.fields)
+}
+
+func (e *csvExport) write(p domain.Product) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	record := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		record[i] = csvValue(query.Value(p, field))
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExport) close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvValue escribe un campo como lo lee la importacion: fechas en el
+// formato configurado y precios en decimal, con la moneda si no es la de
+// por defecto. Los textos que una planilla tomaria como formula se escriben
+// con un ' adelante.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		if v != "" && strings.ContainsRune(importer.FormulaPrefixes, rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case domain.Date:
+		if v.IsZero() {
+			return ""
+		}
+		return v.String()
+	case money.Money:
+		if v.Currency() == "" || v.Currency() == money.DefaultCurrency() {
+			return v.Decimal()
+		}
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+type ndjsonExport struct {
+	encoder *json.Encoder
+	fields  []string
+}
+
+func newNDJSONExport(w io.Writer, fields []string) exportWriter {
+	return &ndjsonExport{encoder: json.NewEncoder(w), fields: fields}
+}
+
+func (e *ndjsonExport) write(p domain.Product) error {
+	if len(e.fields) > 0 {
+		return e.encoder.Encode(query.Project(p, e.fields))
+	}
+	return e.encoder.Encode(p)
+}
+
+func (e *ndjsonExport) close() error {
+	return nil
+}
+
+// jsonExport escribe un arreglo json item por item
+type jsonExport struct {
+	w      io.Writer
+	fields []string
+	count  int
+}
+
+func newJSONExport(w io.Writer, fields []string) exportWriter {
+	return &jsonExport{w: w, fields: fields}
+}
+
+func (e *jsonExport) write(p domain.Product) error {
+	var item any = p
+	if len(e.fields) > 0 {
+		item = query.Project(p, e.fields)
+	}
+	bytes, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if e.count == 0 {
+		sep = "["
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(bytes)
+	return err
+}
+
+func (e *jsonExport) close() error {
+	end := "]"
+	if e.count == 0 {
+		end = "[]"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// exportStream envia la respuesta a medida que llegan los productos. Los
+// headers salen con el primer producto (o al terminar, si no hay), asi un
+// error antes de eso todavia se responde con su status.
+type exportStream struct {
+	ctx         *gin.Context
+	contentType string
+	filename    string
+	buf         *bufio.Writer
+	out         exportWriter
+	started     bool
+}
+
+func (s *exportStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.ctx.Header("Content-Type", s.contentType)
+	s.ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.filename))
+	s.ctx.Header("Trailer", exportErrorTrailer)
+	s.ctx.Status(http.StatusOK)
+}
+
+func (s *exportStream) write(p domain.Product) error {
+	s.start()
+	return s.out.write(p)
+}
+
+// finish completa la respuesta. Si err ocurrio con la respuesta empezada se
+// informa en el trailer X-Export-Error; el documento queda incompleto.
+func (s *exportStream) finish(err error) {
+	if err == nil {
+		s.start()
+		if err = s.out.close(); err == nil {
+			err = s.buf.Flush()
+		}
+	}
+	switch {
+	case err == nil:
+	case !s.started && errors.Is(err, query.ErrInvalid):
+		web.Failure(s.ctx, http.StatusBadRequest, err)
+	case !s.started:
+		web.Error(s.ctx, err)
+	default:
+		_ = s.buf.Flush()
+		log.Printf("export %s interrupted: %v", s.filename, err)
+		s.ctx.Writer.Header().Set(exportErrorTrailer, err.Error())
+	}
+}
+
+// Export documentation with Swagger
+// Export godoc
+// @Summary Export products
+// @Tags Products
+// @Description stream every product that matches the same q, filter and priceGt parameters as /products/search (all of them if none is given) as a csv, ndjson or json download. Rows are sent as they are read from the store; limit, offset and cursor don't apply. fields chooses the columns (csv) or properties (json, ndjson). If the store fails after the first row was sent the document is left incomplete and the X-Export-Error trailer has the error.
+// @Produce text/csv
+// @Produce json
+// @Produce application/x-ndjson
+// @Param token header string true "token"
+// @Param format query string false "csv (default), ndjson or json"
+// @Param q query string false "Words to search in product names"
+// @Param filter query string false "Filter expression"
+// @Param priceGt query number false "Price greater than"
+// @Param sort query string false "Sort keys, - for descending (e.g. price,-name) (not with q)"
+// @Param fields query string false "Fields to export (e.g. id,name,price)"
+// @Success 200 {file} file
+// @Failure 400 {object} web.ErrorResponse
+// @Router /products/export [get]
+func (h *productHandler) Export() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		name := ctx.DefaultQuery("format", "csv")
+		format, ok := exportFormats[name]
+		if !ok {
+			web.Failure(ctx, http.StatusBadRequest, errors.New("format must be csv, ndjson or json"))
+			return
+		}
+		q, ok := searchQuery(ctx)
+		if !ok {
+			return
+		}
+		text := ctx.Query("q")
+		if text != "" && len(q.Sort) > 0 {
+			web.Failure(ctx, http.StatusBadRequest, errors.New("q results are sorted by relevance, sort can't be used"))
+			return
+		}
+
+		buf := bufio.NewWriterSize(ctx.Writer, 32<<10)
+		stream := &exportStream{
+			ctx:         ctx,
+			contentType: format.contentType,
+			filename:    "products-" + time.Now().Format("20060102") + "." + name,
+			buf:         buf,
+			out:         format.writer(buf, q.Fields),
+		}
+		if text == "" {
+			stream.finish(h.service.Each(ctx.Request.Context(), q, stream.write))
+			return
+		}
+		matches, err := h.service.SearchText(ctx.Request.Context(), text)
+		for _, m := range matches {
+			if !query.Match(q.Filter, m.Product) {
+				continue
+			}
+			if err = stream.write(m.Product); err != nil {
+				break
+			}
+		}
+		stream.finish(err)
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/importer"
+	"github.com/fgiudicatti-meli/web-server/pkg/sheet"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxImportSize acota el tamanio del archivo a importar
+	maxImportSize = 10 << 20
+	// maxImportRows acota las filas de una importacion, sin los encabezados
+	maxImportRows = 10000
+	// maxImportColumns acota las columnas de una importacion; alcanza para
+	// los campos del producto y las columnas que no se importan
+	maxImportColumns = 100
+)
+
+type importHandler struct {
+	service importer.Service
+}
+
+// NewImportHandler crea un nuevo controller de importaciones
+func NewImportHandler(s importer.Service) *importHandler {
+	return &importHandler{
+		service: s,
+	}
+}
+
+// importFile devuelve el archivo del request: el campo file de un form
+// multipart o el body entero
+func importFile(ctx *gin.Context) (io.ReadCloser, error) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportSize)
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		header, err := ctx.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		return header.Open()
+	}
+	return ctx.Request.Body, nil
+}
+
+// boolQuery lee un parametro booleano opcional; si no es valido ya
+// respondio 400
+func boolQuery(ctx *gin.Context, name string) (bool, bool) {
+	value := ctx.Query(name)
+	if value == "" {
+		return false, true
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		web.Failure(ctx, http.StatusBadRequest, fmt.Errorf("%s must be true or false", name))
+		return false, false
+	}
+	return b, true
+}
+
+// Import documentation with Swagger
+// Import godoc
+// @Summary Import products from a spreadsheet
+// @Tags Products
+// @Description create the products of a CSV (comma or semicolon separated) or XLSX file, sent as the "file" field of a multipart form or as the whole body. The first row has the headers; columns maps each product field to its header (by default the header is the field name). Every row is validated like POST /products and code_value must not exist in the store nor repeat in the file. With dry_run=true nothing is created and the report says which rows are valid; otherwise the valid rows are created in a single store write, or none of them with atomic=true if any row fails.
+// @Accept text/csv
+// @Accept mpfd
+// @Produce json
+// @Param token header string true "token"
+// @Param columns query string false "Column mapping, e.g. name=Nombre,code_value=SKU,price=Precio"
+// @Param dry_run query bool false "Only validate"
+// @Param atomic query bool false "All or nothing"
+// @Param file formData file false "CSV or XLSX file"
+// @Success 200 {object} web.Response{data=importer.Report}
+// @Success 201 {object} web.Response{data=importer.Report}
+// @Success 207 {object} web.Response{data=importer.Report}
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 413 {object} web.ErrorResponse
+// @Failure 422 {object} web.Response{data=importer.Report}
+// @Router /products/import [post]
+func (h *importHandler) Import() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		var opts importer.Options
+		var ok bool
+		if opts.DryRun, ok = boolQuery(ctx, "dry_run"); !ok {
+			return
+		}
+		if opts.Atomic, ok = boolQuery(ctx, "atomic"); !ok {
+			return
+		}
+		var err error
+		if opts.Mapping, err = importer.ParseMapping(ctx.Query("columns")); err != nil {
+			web.Failure(ctx, http.StatusBadRequest, err)
+			return
+		}
+
+		var rows [][]string
+		file, err := importFile(ctx)
+		if err == nil {
+			defer file.Close()
+			rows, err = sheet.Read(file, sheet.Limits{Rows: maxImportRows + 1, Columns: maxImportColumns})
+		}
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			web.Failure(ctx, http.StatusRequestEntityTooLarge, fmt.Errorf("the file can't be larger than %d bytes", maxImportSize))
+			return
+		case errors.Is(err, sheet.ErrTooManyRows):
+			web.Failure(ctx, http.StatusBadRequest, fmt.Errorf("the file can't have more than %d rows", maxImportRows))
+			return
+		case errors.Is(err, sheet.ErrTooManyColumns):
+			web.Failure(ctx, http.StatusBadRequest, fmt.Errorf("the file can't have more than %d columns", maxImportColumns))
+			return
+		case err != nil:
+			web.Failure(ctx, http.StatusBadRequest, fmt.Errorf("invalid file: %w", err))
+			return
+		}
+
+		report, err := h.service.Import(ctx.Request.Context(), rows, opts)
+		if err != nil {
+			web.Error(ctx, err)
+			return
+		}
+		status := http.StatusCreated
+		switch {
+		case report.DryRun:
+			status = http.StatusOK
+		case report.Created == 0:
+			status = http.StatusUnprocessableEntity
+		case report.Created < report.Rows:
+			status = http.StatusMultiStatus
+		}
+		web.Success(ctx, status, report)
+	}
+}
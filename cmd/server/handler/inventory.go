@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/inventory"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type inventoryHandler struct {
+	service inventory.Service
+}
+
+// NewInventoryHandler crea un nuevo controller del libro de inventario
+func NewInventoryHandler(s inventory.Service) *inventoryHandler {
+	return &inventoryHandler{
+		service: s,
+	}
+}
+
+type MovementRequest struct {
+	Kind     domain.MovementKind `json:"kind" binding:"required" example:"receipt"`
+	Quantity int                 `json:"quantity" binding:"required"`
+	Reason   string              `json:"reason"`
+}
+
+type StockResponse struct {
+	ProductId int         `json:"product_id"`
+	Date      domain.Date `json:"date" swaggertype:"string" example:"15/12/2021"`
+	Quantity  int         `json:"quantity"`
+}
+
+// Movements documentation with Swagger
+// Movements godoc
+// @Summary List the stock movements of a product
+// @Tags Inventory
+// @Description every change of quantity in chronological order, with the resulting balance; movements of deleted products are kept
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Product id"
+// @Success 200 {object} web.Response
+// @Failure 404 {object} web.ErrorResponse
+// @Router /products/{id}/movements [get]
+func (h *inventoryHandler) Movements() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		movements, err := h.service.Movements(ctx.Request.Context(), id)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, movements)
+	}
+}
+
+// Record documentation with Swagger
+// Record godoc
+// @Summary Record a stock movement
+// @Tags Inventory
+// @Description change the quantity of a product: receipts are positive, sales and write-offs negative, adjustments of either sign and with a reason. The actor is taken from the X-Actor header.
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param X-Actor header string false "Who makes the change"
+// @Param id path int true "Product id"
+// @Param movement body MovementRequest true "Movement"
+// @Success 201 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /products/{id}/movements [post]
+func (h *inventoryHandler) Record() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		var req MovementRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			web.Failure(ctx, http.StatusBadRequest, errInvalidJSON)
+			return
+		}
+		m, err := h.service.Record(ctx.Request.Context(), id, domain.Movement{Kind: req.Kind, Quantity: req.Quantity, Reason: req.Reason})
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusCreated, m)
+	}
+}
+
+// Stock documentation with Swagger
+// Stock godoc
+// @Summary Get the stock of a product as of a date
+// @Tags Inventory
+// @Description the quantity at the end of the given day, computed from the movements ledger; without date it is today
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Product id"
+// @Param date query string false "Date (dd/mm/yyyy, yyyy-mm-dd or RFC3339)"
+// @Success 200 {object} web.Response{data=StockResponse}
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 404 {object} web.ErrorResponse
+// @Router /products/{id}/stock [get]
+func (h *inventoryHandler) Stock() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		date := domain.Today()
+		if value := ctx.Query("date"); value != "" {
+			var err error
+			if date, err = domain.ParseDate(value); err != nil {
+				web.Failure(ctx, http.StatusBadRequest, err)
+				return
+			}
+		}
+		quantity, err := h.service.StockAt(ctx.Request.Context(), id, date)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, StockResponse{ProductId: id, Date: date, Quantity: quantity})
+	}
+}
+
+// Reconcile documentation with Swagger
+// Reconcile godoc
+// @Summary Reconcile stock against the ledger
+// @Tags Inventory
+// @Description record an adjustment for every product whose quantity differs from the sum of its movements; returns the adjustments
+// @Produce json
+// @Param token header string true "token"
+// @Success 200 {object} web.Response
+// @Router /products/reconcile [post]
+func (h *inventoryHandler) Reconcile() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		adjustments, err := h.service.Reconcile(ctx.Request.Context())
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, adjustments)
+	}
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/fgiudicatti-meli/web-server/internal/order"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type orderHandler struct {
+	service order.Service
+}
+
+// NewOrderHandler crea un nuevo controller de ordenes
+func NewOrderHandler(s order.Service) *orderHandler {
+	return &orderHandler{
+		service: s,
+	}
+}
+
+type OrderRequest struct {
+	Lines []OrderLineRequest `json:"lines"`
+	Promo string             `json:"promo,omitempty"`
+}
+
+type OrderLineRequest struct {
+	ProductId int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// GetAll documentation with Swagger
+// GetAll godoc
+// @Summary List orders
+// @Tags Orders
+// @Produce json
+// @Param token header string true "token"
+// @Success 200 {object} web.Response
+// @Router /orders [get]
+func (h *orderHandler) GetAll() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		orders, err := h.service.GetAll(ctx.Request.Context())
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, orders)
+	}
+}
+
+// GetByID documentation with Swagger
+// GetByID godoc
+// @Summary Get an order
+// @Tags Orders
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Order id"
+// @Success 200 {object} web.Response
+// @Failure 404 {object} web.ErrorResponse
+// @Router /orders/{id} [get]
+func (h *orderHandler) GetByID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		o, err := h.service.GetByID(ctx.Request.Context(), id)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, o)
+	}
+}
+
+// Place documentation with Swagger
+// Place godoc
+// @Summary Place an order
+// @Tags Orders
+// @Description price the lines with the pricing rules and create a pending order; the units of every line are reserved, or none if any product doesn't have enough available units
+// @Accept json
+// @Produce json
+// @Param token header string true "token"
+// @Param order body OrderRequest true "Lines and promo code"
+// @Success 201 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /orders [post]
+func (h *orderHandler) Place() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		var req OrderRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			web.Failure(ctx, http.StatusBadRequest, errInvalidJSON)
+			return
+		}
+		items := make([]pricing.Item, len(req.Lines))
+		for i, l := range req.Lines {
+			items[i] = pricing.Item{ProductId: l.ProductId, Quantity: l.Quantity}
+		}
+		o, err := h.service.Place(ctx.Request.Context(), items, req.Promo)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusCreated, o)
+	}
+}
+
+// Confirm documentation with Swagger
+// Confirm godoc
+// @Summary Confirm an order
+// @Tags Orders
+// @Description decrement the stock of a pending order by its reserved units
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Order id"
+// @Success 200 {object} web.Response
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /orders/{id}/confirm [post]
+func (h *orderHandler) Confirm() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		o, err := h.service.Confirm(ctx.Request.Context(), id)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, o)
+	}
+}
+
+// Cancel documentation with Swagger
+// Cancel godoc
+// @Summary Cancel an order
+// @Tags Orders
+// @Description release the units reserved by a pending order
+// @Produce json
+// @Param token header string true "token"
+// @Param id path int true "Order id"
+// @Success 200 {object} web.Response
+// @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Router /orders/{id}/cancel [post]
+func (h *orderHandler) Cancel() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !checkToken(ctx) {
+			return
+		}
+		id, ok := intParam(ctx, "id")
+		if !ok {
+			return
+		}
+		o, err := h.service.Cancel(ctx.Request.Context(), id)
+		if err != nil {
+			stockFailure(ctx, err)
+			return
+		}
+		web.Success(ctx, http.StatusOK, o)
+	}
+}
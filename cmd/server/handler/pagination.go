@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+)
+
+// projectFields devuelve los productos completos o, si se pidieron fields,
+// solo esos campos de cada uno
+func projectFields(products []domain.Product, fields []string) any {
+	if len(fields) == 0 {
+		return products
+	}
+	projected := make([]any, len(products))
+	for i, p := range products {
+		projected[i] = query.Project(p, fields)
+	}
+	return projected
+}
+
+// pageLinks arma los links first, prev y next conservando el resto de los
+// parametros del request
+func pageLinks(current *url.URL, q query.Query, page query.Page) []web.Link {
+	link := func(set map[string]string) string {
+		values := current.Query()
+		values.Del("cursor")
+		values.Del("offset")
+		for k, v := range set {
+			values.Set(k, v)
+		}
+		u := url.URL{Path: current.Path, RawQuery: values.Encode()}
+		return u.String()
+	}
+
+	links := []web.Link{{Rel: "first", URL: link(nil)}}
+	if q.Limit > 0 && q.Offset > 0 {
+		prev := q.Offset - q.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, web.Link{Rel: "prev", URL: link(map[string]string{"offset": strconv.Itoa(prev)})})
+	}
+	switch {
+	case page.NextCursor != "":
+		links = append(links, web.Link{Rel: "next", URL: link(map[string]string{"cursor": page.NextCursor})})
+	case q.Cursor == "" && q.Limit > 0 && q.Offset+q.Limit < page.Total:
+		// listados sin cursor, como los ordenados por relevancia
+		links = append(links, web.Link{Rel: "next", URL: link(map[string]string{"offset": strconv.Itoa(q.Offset + q.Limit)})})
+	}
+	return links
+}
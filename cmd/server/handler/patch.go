@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/patch"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// mergePatchType es el content type de JSON Merge Patch (RFC 7396)
+	mergePatchType = "application/merge-patch+json"
+	// jsonPatchType es el content type de JSON Patch (RFC 6902)
+	jsonPatchType = "application/json-patch+json"
+	// maxPatchSize acota el tamanio del body de un patch
+	maxPatchSize = 1 << 20
+)
+
+// patchStatus devuelve el status de un error al aplicar un patch
+func patchStatus(err error) int {
+	switch {
+	case errors.Is(err, patch.ErrTestFailed):
+		return http.StatusConflict
+	case errors.Is(err, patch.ErrPath):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// patchDocument aplica el merge patch o json patch del body sobre current
+func (h *productHandler) patchDocument(ctx *gin.Context, current domain.Product) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPatchSize)
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			web.Failure(ctx, http.StatusRequestEntityTooLarge, fmt.Errorf("the patch can't be larger than %d bytes", maxPatchSize))
+			return
+		}
+		web.Failure(ctx, http.StatusBadRequest, err)
+		return
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		web.Failure(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if ctx.ContentType() == mergePatchType {
+		doc, err = patch.MergePatch(doc, body)
+	} else {
+		doc, err = patch.Apply(doc, body)
+	}
+	if err != nil {
+		web.Failure(ctx, patchStatus(err), err)
+		return
+	}
+
+	var p domain.Product
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		web.Failure(ctx, http.StatusUnprocessableEntity, bindError(err))
+		return
+	}
+	var readOnly []domain.FieldError
+	checkReadOnly := func(field string, changed bool) {
+		if changed {
+			readOnly = append(readOnly, domain.FieldError{Field: field, Code: "read_only", Message: field + " can't be modified"})
+		}
+	}
+	checkReadOnly("id", p.Id != current.Id)
+	checkReadOnly("reserved", p.Reserved != current.Reserved)
+	checkReadOnly("lifecycle_reason", p.LifecycleReason != current.LifecycleReason)
+	switch {
+	case len(readOnly) > 0:
+		web.Failure(ctx, http.StatusUnprocessableEntity, product.ErrInvalidProduct.WithFields(readOnly...))
+		return
+	case p.Version != current.Version:
+		web.Error(ctx, product.ErrVersionMismatch)
+		return
+	}
+
+	updated, err := h.service.Replace(ctx.Request.Context(), current.Id, p)
+	switch {
+	case errors.Is(err, product.ErrInvalidProduct):
+		web.Failure(ctx, http.StatusUnprocessableEntity, err)
+	case err != nil:
+		web.Error(ctx, err)
+	default:
+		setETag(ctx, updated)
+		web.Success(ctx, http.StatusOK, updated)
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type pricingHandler struct {
+	service pricing.Service
+}
+
+// NewPricingHandler crea un nuevo controller de precios
+func NewPricingHandler(s pricing.Service) *pricingHandler {
+	return &pricingHandler{
+		service: s,
+	}
+}
+
+// ConsumerPrice documentation with Swagger
+// ConsumerPrice godoc
+// @Summary Consumer price of a list of products
+// @Tags Products
+// @Description price breakdown per product with the configured markups, taxes, quantity discounts, promo codes and caps; every product must be published and appear once
+// @Produce json
+// @Param token header string true "Token"
+// @Param list query string true "Product ids, optionally with units as id:quantity (e.g. 1,2:3)"
+// @Param promo query string false "Promo code"
+// @Success 200 {object} web.Response
+// @Failure 400 {object} web.ErrorResponse
+// @Router /products/consumer_price [get]
+func (h *pricingHandler) ConsumerPrice() gin.HandlerFunc {
+	type response struct {
+		Products []domain.Product `json:"products"`
+		pricing.Quote
+	}
+	return func(ctx *gin.Context) {
+		token := ctx.Request.Header.Get("token")
+		if token != os.Getenv("TOKEN") {
+			web.Failure(ctx, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+
+		list := ctx.Query("list")
+		if list == "" {
+			web.Failure(ctx, http.StatusBadRequest, errors.New("invalid query param"))
+			return
+		}
+		items, err := parseItems(list)
+		if err != nil {
+			web.Failure(ctx, http.StatusBadRequest, err)
+			return
+		}
+
+		quote, err := h.service.Quote(ctx.Request.Context(), items, ctx.Query("promo"))
+		switch {
+		case errors.Is(err, pricing.ErrInvalidItems), errors.Is(err, pricing.ErrProductNotFound),
+			errors.Is(err, pricing.ErrNotPublished), errors.Is(err, pricing.ErrInvalidPromo):
+			web.Failure(ctx, http.StatusBadRequest, err)
+			return
+		case err != nil:
+			// monedas distintas o importes fuera de rango
+			web.Failure(ctx, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		resp := response{Products: make([]domain.Product, len(quote.Lines)), Quote: quote}
+		for i, line := range quote.Lines {
+			resp.Products[i] = line.Product
+		}
+		web.Success(ctx, http.StatusOK, resp)
+	}
+}
+
+// parseItems interpreta una lista como "1,2:3": ids separados por coma,
+// cada uno con sus unidades despues de ":" (1 si no se indican)
+func parseItems(list string) ([]pricing.Item, error) {
+	var items []pricing.Item
+	for _, part := range strings.Split(list, ",") {
+		id, quantity, found := strings.Cut(strings.TrimSpace(part), ":")
+		item := pricing.Item{Quantity: 1}
+		var err error
+		if item.ProductId, err = strconv.Atoi(id); err != nil {
+			return nil, errors.New("list of ids invalid")
+		}
+		if found {
+			if item.Quantity, err = strconv.Atoi(quantity); err != nil || item.Quantity < 1 {
+				return nil, errors.New("quantities must be positive numbers")
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
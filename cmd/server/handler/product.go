@@ -5,10 +5,11 @@ import (
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 
 	"github.com/fgiudicatti-meli/web-server/internal/domain"
 	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
 	"github.com/fgiudicatti-meli/web-server/pkg/web"
 	"github.com/gin-gonic/gin"
 )
@@ -25,12 +26,12 @@ func NewProductHandler(s product.Service) *productHandler {
 }
 
 type Request struct {
-	Name        string  `json:"name,omitempty"`
-	Quantity    int     `json:"quantity,omitempty"`
-	CodeValue   string  `json:"code_value,omitempty"`
-	IsPublished bool    `json:"is_published,omitempty"`
-	Expiration  string  `json:"expiration,omitempty"`
-	Price       float64 `json:"price,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	Quantity    int         `json:"quantity,omitempty"`
+	CodeValue   string      `json:"code_value,omitempty"`
+	IsPublished bool        `json:"is_published,omitempty"`
+	Expiration  domain.Date `json:"expiration,omitempty" swaggertype:"string" example:"15/12/2021"`
+	Price       money.Money `json:"price,omitempty" swaggertype:"number" example:"555.99"`
 }
 
 // GetAll documentation with Swagger
@@ -40,7 +41,17 @@ type Request struct {
 // @Description get products
 // @Produce json
 // @Param token header string true "token"
+// @Param filter query string false "Filter expression, see /products/search"
+// @Param limit query int false "Page size (max 1000)"
+// @Param offset query int false "Items to skip"
+// @Param cursor query string false "Opaque cursor from pagination.next_cursor"
+// @Param sort query string false "Sort keys, - for descending (e.g. price,-name)"
+// @Param fields query string false "Fields to return (e.g. id,name,price)"
+// @Param If-None-Match header string false "ETag already cached by the client"
+// @Param If-Modified-Since header string false "Last-Modified already cached by the client"
 // @Success 200 {object} web.Response
+// @Success 304
+// @Failure 400 {object} web.ErrorResponse
 // @Router /products [get]
 func (h *productHandler) GetAll() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -49,12 +60,34 @@ func (h *productHandler) GetAll() gin.HandlerFunc {
 			web.Failure(ctx, http.StatusUnauthorized, errors.New("invalid token"))
 			return
 		}
-		products, err := h.service.GetAll()
+		// con la revision del store se puede responder 304 sin leer los productos
+		rev, err := h.service.Revision(ctx.Request.Context())
+		if err == nil && rev.Tag != "" {
+			v := validators{etag: collectionETag(rev.Tag, ctx.Request.URL.RawQuery), lastModified: rev.ModTime}
+			if notModified(ctx, v) {
+				return
+			}
+		}
+
+		q, err := query.Parse(ctx.Request.URL.Query())
 		if err != nil {
-			web.Failure(ctx, http.StatusNotFound, errors.New("not found products"))
+			web.Failure(ctx, http.StatusBadRequest, err)
 			return
 		}
-		web.Success(ctx, 200, products)
+		page, err := h.service.List(ctx.Request.Context(), q)
+		if errors.Is(err, query.ErrInvalid) {
+			web.Failure(ctx, http.StatusBadRequest, err)
+			return
+		}
+		if err != nil {
+			web.Error(ctx, err)
+			return
+		}
+		if rev.Tag == "" && notModified(ctx, validators{etag: contentETag(page)}) {
+			return
+		}
+		pagination := web.Pagination{Total: page.Total, Limit: q.Limit, Offset: q.Offset, NextCursor: page.NextCursor}
+		web.SuccessPage(ctx, 200, projectFields(page.Items, q.Fields), pagination, pageLinks(ctx.Request.URL, q, page))
 	}
 }
 
@@ -66,7 +99,10 @@ func (h *productHandler) GetAll() gin.HandlerFunc {
 // @Produce json
 // @Param id path int true "Product ID"
 // @Param token header string true "token"
+// @Param If-None-Match header string false "ETag already cached by the client"
+// @Param If-Modified-Since header string false "Last-Modified already cached by the client"
 // @Success 200 {object} web.Response
+// @Success 304
 // @Failure 404 {object} web.ErrorResponse
 // @Failure 400 {object} web.ErrorResponse
 // @Router /products/{id} [get]
@@ -84,9 +120,14 @@ func (h *productHandler) GetByID() gin.HandlerFunc {
 			return
 		}
 
-		productFounded, err := h.service.GetByID(id)
+		productFounded, err := h.service.GetByID(ctx.Request.Context(), id)
 		if err != nil {
-			web.Failure(ctx, http.StatusNotFound, errors.New("product not found"))
+			web.Error(ctx, err)
+			return
+		}
+		// Last-Modified es el del store: nunca es anterior al cambio del producto
+		rev, _ := h.service.Revision(ctx.Request.Context())
+		if notModified(ctx, validators{etag: etag(productFounded.Version), lastModified: rev.ModTime}) {
 			return
 		}
 		web.Success(ctx, http.StatusOK, productFounded)
@@ -95,11 +136,21 @@ func (h *productHandler) GetByID() gin.HandlerFunc {
 
 // Search documentation with Swagger
 // Search godoc
-// @Summary search products by price limit
+// @Summary search products by name or with a filter expression
 // @Tags Products
-// @Description find products that price is bigger than param
+// @Description q searches product names: words match ignoring case and accents, as prefixes or with small typos, and results are sorted by relevance (score).
+// @Description filter finds products that match an expression such as price>=100 AND is_published=true AND name~"cake".
+// @Description Operators: = != > >= < <= ~ (contains, case insensitive) ^= (starts with), combined with AND, OR, NOT and parentheses.
+// @Description Dates are compared as dates (dd/mm/yyyy or yyyy-mm-dd).
 // @Produce json
-// @Param priceGt query int true "Price"
+// @Param q query string false "Words to search in product names"
+// @Param filter query string false "Filter expression"
+// @Param priceGt query number false "Price greater than (kept for compatibility, combined with filter using AND)"
+// @Param limit query int false "Page size (max 1000)"
+// @Param offset query int false "Items to skip"
+// @Param cursor query string false "Opaque cursor from pagination.next_cursor (not with q)"
+// @Param sort query string false "Sort keys, - for descending (e.g. price,-name) (not with q)"
+// @Param fields query string false "Fields to return (e.g. id,name,price)"
 // @Param token header string true "token"
 // @Success 200 {object} web.Response
 // @Failure 404 {object} web.ErrorResponse
@@ -113,58 +164,108 @@ func (h *productHandler) Search() gin.HandlerFunc {
 			return
 		}
 
-		price, err := strconv.ParseFloat(ctx.Query("priceGt"), 64)
-		if err != nil {
-			web.Failure(ctx, http.StatusBadRequest, errors.New("invalid price"))
+		q, ok := searchQuery(ctx)
+		if !ok {
+			return
+		}
+		if text := ctx.Query("q"); text != "" {
+			h.searchText(ctx, text, q)
+			return
+		}
+		if q.Filter == nil {
+			web.Failure(ctx, http.StatusBadRequest, errors.New("q or filter is required"))
 			return
 		}
 
-		products, err := h.service.SearchPriceGt(price)
+		page, err := h.service.List(ctx.Request.Context(), q)
+		if errors.Is(err, query.ErrInvalid) {
+			web.Failure(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err != nil {
-			web.Failure(ctx, http.StatusNotFound, errors.New("product not found"))
+			web.Error(ctx, err)
 			return
 		}
-
-		web.Success(ctx, http.StatusOK, products)
+		if page.Total == 0 {
+			web.Error(ctx, errNoResults)
+			return
+		}
+		pagination := web.Pagination{Total: page.Total, Limit: q.Limit, Offset: q.Offset, NextCursor: page.NextCursor}
+		web.SuccessPage(ctx, http.StatusOK, projectFields(page.Items, q.Fields), pagination, pageLinks(ctx.Request.URL, q, page))
 	}
 }
 
-// validateEmptys valida que los campos no esten vacios
-func validateEmptys(product *domain.Product) (bool, error) {
-	switch {
-	case product.Name == "" || product.CodeValue == "" || product.Expiration == "":
-		return false, errors.New("fields can't be empty")
-	case product.Quantity <= 0 || product.Price <= 0:
-		if product.Quantity <= 0 {
-			return false, errors.New("quantity must be greater than 0")
+// searchQuery lee los parametros de busqueda: los de query.Parse y
+// priceGt, que se combina con filter usando AND. Si no son validos ya
+// respondio 400.
+func searchQuery(ctx *gin.Context) (query.Query, bool) {
+	q, err := query.Parse(ctx.Request.URL.Query())
+	if err != nil {
+		web.Failure(ctx, http.StatusBadRequest, err)
+		return query.Query{}, false
+	}
+	if priceGt := ctx.Query("priceGt"); priceGt != "" {
+		price, err := money.Parse(priceGt, money.DefaultCurrency(), money.HalfEven)
+		if err != nil {
+			web.Failure(ctx, http.StatusBadRequest, errors.New("invalid price"))
+			return query.Query{}, false
 		}
-		if product.Price <= 0 {
-			return false, errors.New("price must be greater than 0")
+		priceFilter := query.Cmp{Field: "price", Op: query.OpGt, Value: price}
+		if q.Filter == nil {
+			q.Filter = priceFilter
+		} else {
+			q.Filter = query.And{Left: q.Filter, Right: priceFilter}
 		}
 	}
-	return true, nil
+	return q, true
 }
 
-// validateExpiration valida que la fecha de expiracion sea valida
-func validateExpiration(exp string) (bool, error) {
-	dates := strings.Split(exp, "/")
-	//list := []int{}
-	var list []int
-	if len(dates) != 3 {
-		return false, errors.New("invalid expiration date, must be in format: dd/mm/yyyy")
+// searchText responde una busqueda por nombre. Los resultados van por
+// relevancia, asi que solo se pagina por offset; filter restringe el
+// resultado y fields agrega siempre el score.
+func (h *productHandler) searchText(ctx *gin.Context, text string, q query.Query) {
+	if q.Cursor != "" || len(q.Sort) > 0 {
+		web.Failure(ctx, http.StatusBadRequest, errors.New("q results are sorted by relevance, use offset instead of sort or cursor"))
+		return
 	}
-	for value := range dates {
-		number, err := strconv.Atoi(dates[value])
-		if err != nil {
-			return false, errors.New("invalid expiration date, must be numbers")
+	matches, err := h.service.SearchText(ctx.Request.Context(), text)
+	if err != nil {
+		web.Error(ctx, err)
+		return
+	}
+	filtered := matches[:0]
+	for _, m := range matches {
+		if query.Match(q.Filter, m.Product) {
+			filtered = append(filtered, m)
 		}
-		list = append(list, number)
 	}
-	condition := (list[0] < 1 || list[0] > 31) && (list[1] < 1 || list[1] > 12) && (list[2] < 1 || list[2] > 9999)
-	if condition {
-		return false, errors.New("invalid expiration date, date must be between 1 and 31/12/9999")
+	if len(filtered) == 0 {
+		web.Error(ctx, errNoResults)
+		return
+	}
+
+	pagination := web.Pagination{Total: len(filtered), Limit: q.Limit, Offset: q.Offset}
+	start, end := q.Offset, len(filtered)
+	if start > end {
+		start = end
+	}
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
 	}
-	return true, nil
+	page := filtered[start:end]
+
+	var data any = page
+	if len(q.Fields) > 0 {
+		projected := make([]map[string]any, len(page))
+		for i, m := range page {
+			projected[i] = map[string]any{"score": m.Score}
+			for field, value := range query.Project(m.Product, q.Fields) {
+				projected[i][field] = value
+			}
+		}
+		data = projected
+	}
+	web.SuccessPage(ctx, http.StatusOK, data, pagination, pageLinks(ctx.Request.URL, q, query.Page{Total: len(filtered)}))
 }
 
 // AddProduct documentation swagger
@@ -179,6 +280,7 @@ func validateExpiration(exp string) (bool, error) {
 // @Success 201 {object} web.Response
 // @Failure 400 {object} web.ErrorResponse
 // @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
 // @Router /products/new [post]
 func (h *productHandler) AddProduct() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -191,25 +293,20 @@ func (h *productHandler) AddProduct() gin.HandlerFunc {
 		var newProduct domain.Product
 		err := ctx.ShouldBindJSON(&newProduct)
 		if err != nil {
-			web.Failure(ctx, http.StatusBadRequest, errors.New("invalid json"))
+			web.Failure(ctx, http.StatusBadRequest, bindError(err))
 			return
 		}
-		valid, err := validateEmptys(&newProduct)
-		if !valid {
-			web.Failure(ctx, http.StatusBadRequest, err)
-			return
-		}
-		valid, err = validateExpiration(newProduct.Expiration)
-		if !valid {
-			web.Failure(ctx, http.StatusBadRequest, err)
+		if err := product.Validate(newProduct); err != nil {
+			web.Error(ctx, err)
 			return
 		}
-		createProduct, err := h.service.Create(newProduct)
+		createProduct, err := h.service.Create(ctx.Request.Context(), newProduct)
 		if err != nil {
-			web.Failure(ctx, http.StatusBadRequest, err)
+			web.Error(ctx, err)
 			return
 		}
 
+		setETag(ctx, createProduct)
 		web.Success(ctx, http.StatusCreated, createProduct)
 	}
 }
@@ -222,9 +319,13 @@ func (h *productHandler) AddProduct() gin.HandlerFunc {
 // @Produce json
 // @Param token header string true "Token"
 // @Param id path int true "ProductID"
+// @Param If-Match header string false "ETag of the version being modified"
 // @Success 204 {object} web.Response
 // @Failure 400 {object} web.ErrorResponse
 // @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Failure 412 {object} web.ErrorResponse
+// @Failure 428 {object} web.ErrorResponse
 // @Router /products/{id} [delete]
 func (h *productHandler) Delete() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -240,9 +341,24 @@ func (h *productHandler) Delete() gin.HandlerFunc {
 			return
 		}
 
-		err = h.service.Delete(id)
+		current, err := h.service.GetByID(ctx.Request.Context(), id)
 		if err != nil {
-			web.Failure(ctx, http.StatusNotFound, err)
+			web.Error(ctx, err)
+			return
+		}
+		if _, ok := checkIfMatch(ctx, current); !ok {
+			return
+		}
+
+		// se borra la version que se comparo con If-Match; si cambio en el
+		// medio, sin If-Match es un cambio concurrente y no una precondicion
+		err = h.service.Delete(ctx.Request.Context(), id, current.Version)
+		switch {
+		case errors.Is(err, product.ErrVersionMismatch) && ctx.GetHeader("If-Match") == "":
+			web.Failure(ctx, http.StatusConflict, err)
+			return
+		case err != nil:
+			web.Error(ctx, err)
 			return
 		}
 
@@ -260,9 +376,12 @@ func (h *productHandler) Delete() gin.HandlerFunc {
 // @Param token header string true "Token"
 // @Param id path int true "Product ID"
 // @Param putProduct body domain.Product true "UpdateProduct"
+// @Param If-Match header string false "ETag of the version being modified"
 // @Success 200 {object} web.Response
 // @Failure 400 {object} web.ErrorResponse
 // @Failure 404 {object} web.ErrorResponse
+// @Failure 412 {object} web.ErrorResponse
+// @Failure 428 {object} web.ErrorResponse
 // @Router /products/{id} [put]
 func (h *productHandler) Put() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -277,36 +396,36 @@ func (h *productHandler) Put() gin.HandlerFunc {
 			web.Failure(ctx, http.StatusBadRequest, errors.New("invalid id"))
 			return
 		}
-		_, err = h.service.GetByID(id)
+		current, err := h.service.GetByID(ctx.Request.Context(), id)
 		if err != nil {
-			web.Failure(ctx, http.StatusNotFound, errors.New("product not found"))
+			web.Error(ctx, err)
+			return
+		}
+		version, ok := checkIfMatch(ctx, current)
+		if !ok {
 			return
 		}
 
 		var productToUpdate domain.Product
 		err = ctx.ShouldBindJSON(&productToUpdate)
 		if err != nil {
-			web.Failure(ctx, http.StatusBadRequest, errors.New("invalid json"))
+			web.Failure(ctx, http.StatusBadRequest, bindError(err))
 			return
 		}
 
-		valid, err := validateEmptys(&productToUpdate)
-		if !valid {
-			web.Failure(ctx, http.StatusBadRequest, err)
-			return
-		}
-		valid, err = validateExpiration(productToUpdate.Expiration)
-		if !valid {
-			web.Failure(ctx, http.StatusBadRequest, err)
+		if err := product.Validate(productToUpdate); err != nil {
+			web.Error(ctx, err)
 			return
 		}
 
-		updateProduct, err := h.service.Update(id, productToUpdate)
+		productToUpdate.Version = version
+		updateProduct, err := h.service.Update(ctx.Request.Context(), id, productToUpdate)
 		if err != nil {
-			web.Failure(ctx, http.StatusConflict, err)
+			web.Error(ctx, err)
 			return
 		}
 
+		setETag(ctx, updateProduct)
 		web.Success(ctx, http.StatusOK, updateProduct)
 	}
 }
@@ -315,15 +434,22 @@ func (h *productHandler) Put() gin.HandlerFunc {
 // Patch godoc
 // @Summary Partially update a product
 // @Tags Products
-// @Description update not totally fields only some
+// @Description update not totally fields only some. With application/json only the non empty fields of the body are changed. With application/merge-patch+json (RFC 7396) null removes a field and false or 0 are applied; with application/json-patch+json (RFC 6902) the operations, test included, are applied all or nothing. In both cases the resulting product is validated; id, reserved and lifecycle_reason can't be changed.
 // @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
 // @Produce json
 // @Param token header string true "Token"
 // @Param id path int true "Product ID"
 // @Param patchBody body Request true "updateProduct"
+// @Param If-Match header string false "ETag of the version being modified"
 // @Success 200 {object} web.Response
 // @Failure 400 {object} web.ErrorResponse
 // @Failure 404 {object} web.ErrorResponse
+// @Failure 409 {object} web.ErrorResponse
+// @Failure 412 {object} web.ErrorResponse
+// @Failure 422 {object} web.ErrorResponse
+// @Failure 428 {object} web.ErrorResponse
 // @Router /products/{id} [patch]
 func (h *productHandler) Patch() gin.HandlerFunc {
 
@@ -353,13 +479,22 @@ func (h *productHandler) Patch() gin.HandlerFunc {
 			}
 			oldProduct.Id = id
 		*/
-		_, err = h.service.GetByID(id)
+		current, err := h.service.GetByID(ctx.Request.Context(), id)
 		if err != nil {
-			web.Failure(ctx, http.StatusNotFound, errors.New("product not found"))
+			web.Error(ctx, err)
+			return
+		}
+		version, ok := checkIfMatch(ctx, current)
+		if !ok {
+			return
+		}
+		switch ctx.ContentType() {
+		case mergePatchType, jsonPatchType:
+			h.patchDocument(ctx, current)
 			return
 		}
 		if err := ctx.ShouldBindJSON(&r); err != nil {
-			web.Failure(ctx, http.StatusBadRequest, errors.New("invalid json"))
+			web.Failure(ctx, http.StatusBadRequest, bindError(err))
 			return
 		}
 
@@ -370,110 +505,20 @@ func (h *productHandler) Patch() gin.HandlerFunc {
 			IsPublished: r.IsPublished,
 			Expiration:  r.Expiration,
 			Price:       r.Price,
+			Version:     version,
 		}
-		if update.Expiration != "" {
-			valid, err := validateExpiration(update.Expiration)
-			if !valid {
-				web.Failure(ctx, http.StatusBadRequest, err)
-				return
-			}
+		if err := product.ValidateChanges(update); err != nil {
+			web.Error(ctx, err)
+			return
 		}
 
-		p, err := h.service.Update(id, update)
+		p, err := h.service.Update(ctx.Request.Context(), id, update)
 		if err != nil {
-			web.Failure(ctx, http.StatusConflict, err)
+			web.Error(ctx, err)
 			return
 		}
 
+		setETag(ctx, p)
 		web.Success(ctx, http.StatusOK, p)
 	}
 }
-
-// PartialUpdate godoc
-// @Summary Partially update a product
-// @Tags Products
-// @Description Update some product fields data
-// @Accept json
-// @Produce json
-// @Param token header string true "Token"
-// @Param list query int true "Price"
-// @Success 200 {object} web.Response
-// @Failure 400 {object} web.ErrorResponse
-// @Failure 404 {object} web.ErrorResponse
-// @Router /products/consumer_price [get]
-
-func (h *productHandler) GetPriceProducts() gin.HandlerFunc {
-	type response struct {
-		Products   any     `json:"products"`
-		TotalPrice float64 `json:"total_price"`
-	}
-	return func(ctx *gin.Context) {
-		token := ctx.Request.Header.Get("token")
-		if token != os.Getenv("TOKEN") {
-			web.Failure(ctx, http.StatusUnauthorized, errors.New("invalid token"))
-			return
-		}
-
-		query := ctx.Query("list")
-		if query == "" {
-			web.Failure(ctx, http.StatusBadRequest, errors.New("invalid query param"))
-			return
-		}
-		var filterProducts []domain.Product
-		var totalPrice float64
-		ids := strings.Split(query, ",")
-		for _, idInStr := range ids {
-			id, err := strconv.Atoi(idInStr)
-			if err != nil {
-				web.Failure(ctx, http.StatusBadRequest, errors.New("list of ids invalid"))
-				return
-			}
-			prd, err := h.service.GetByID(id)
-			if err != nil {
-				web.Failure(ctx, http.StatusBadRequest, errors.New("some ids are not associate with a product"))
-				return
-			}
-			if checkValidate(prd.Id, filterProducts) {
-				web.Failure(ctx, http.StatusBadRequest, errors.New("some ids are repeated"))
-				return
-			}
-			if !prd.IsPublished {
-				web.Failure(ctx, http.StatusBadRequest, errors.New("remember ids must be a product published"))
-				return
-			}
-			filterProducts = append(filterProducts, prd)
-		}
-
-		allRecords, _ := h.service.GetAll()
-		if len(allRecords) < len(filterProducts) {
-			web.Failure(ctx, http.StatusBadRequest, errors.New("list is too much longer"))
-			return
-		}
-
-		for i := range filterProducts {
-			totalPrice += filterProducts[i].Price
-		}
-
-		switch {
-		case len(filterProducts) < 10:
-			totalPrice = totalPrice * 1.21
-		case len(filterProducts) > 10 && len(filterProducts) < 20:
-			totalPrice = totalPrice * 1.17
-		default:
-			totalPrice = totalPrice * 1.15
-		}
-		resp := response{Products: filterProducts, TotalPrice: float64(int(totalPrice*100)) / 100}
-
-		web.Success(ctx, http.StatusOK, resp)
-	}
-}
-
-func checkValidate(id int, slice []domain.Product) bool {
-	for i := range slice {
-		if slice[i].Id == id {
-			return true
-		}
-	}
-
-	return false
-}
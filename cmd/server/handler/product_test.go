@@ -3,37 +3,146 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/fgiudicatti-meli/web-server/cmd/server/middlewares"
+	"github.com/fgiudicatti-meli/web-server/internal/cart"
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/importer"
+	"github.com/fgiudicatti-meli/web-server/internal/inventory"
+	"github.com/fgiudicatti-meli/web-server/internal/order"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
 	"github.com/fgiudicatti-meli/web-server/internal/product"
 	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/fgiudicatti-meli/web-server/pkg/store/storetest"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 )
 
-func createServer() *gin.Engine {
+// testServices son los servicios de un servidor de prueba, armados sobre una
+// copia del fixture
+type testServices struct {
+	ledger   inventory.Service
+	repo     product.Repository
+	products product.Service
+	prices   pricing.Service
+	orders   order.Service
+}
 
+func newTestServices(t *testing.T) testServices {
 	_ = os.Setenv("TOKEN", "secret_321")
 
-	db := store.NewStore("./products_copy.json")
-	repo := product.NewRepository(db)
-	service := product.NewService(repo)
-	productHandler := NewProductHandler(service)
+	db := storetest.New(t, "testdata/products.json")
+	ledger := inventory.NewService(product.NewRepository(db), store.NewJournal[domain.Movement](""))
+	repo := ledger.Products()
+	products := product.NewService(repo)
+	prices := pricing.NewService(products, pricing.DefaultRules())
+	orders := order.NewService(order.NewRepository(store.NewCollection[domain.Order]("")), repo, prices)
+	return testServices{ledger: ledger, repo: repo, products: products, prices: prices, orders: orders}
+}
+
+func newTestRouter() *gin.Engine {
 	//gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
+	r.Use(middlewares.RequestID())
+	r.Use(middlewares.Actor())
+	return r
+}
+
+// productRoutes registra /products con el inventario, la importacion y los precios
+func productRoutes(r *gin.Engine, s testServices) {
+	inventoryHandler := NewInventoryHandler(s.ledger)
+	productHandler := NewProductHandler(s.products)
+	importHandler := NewImportHandler(importer.NewService(s.products))
+	pricingHandler := NewPricingHandler(s.prices)
 
 	pr := r.Group("/products")
 	{
 		pr.GET("/", productHandler.GetAll())
 		pr.GET(":id", productHandler.GetByID())
 		pr.GET("/search", productHandler.Search())
+		pr.GET("/consumer_price", pricingHandler.ConsumerPrice())
+		pr.GET("/expired", productHandler.Expired())
+		pr.GET("/expiring", productHandler.Expiring())
+		pr.POST("/expire", productHandler.Expire())
+		pr.POST("/reconcile", inventoryHandler.Reconcile())
+		pr.POST("/bulk", productHandler.BulkCreate())
+		pr.PATCH("/bulk", productHandler.BulkUpdate())
+		pr.DELETE("/bulk", productHandler.BulkDelete())
+		pr.POST("/import", importHandler.Import())
+		pr.GET("/export", productHandler.Export())
+		pr.GET(":id/movements", inventoryHandler.Movements())
+		pr.POST(":id/movements", inventoryHandler.Record())
+		pr.GET(":id/stock", inventoryHandler.Stock())
 		pr.POST("/", productHandler.AddProduct())
 		pr.DELETE(":id", productHandler.Delete())
 		pr.PATCH(":id", productHandler.Patch())
 		pr.PUT(":id", productHandler.Put())
 	}
+}
+
+// cartRoutes registra /carts, con el checkout sobre las ordenes de s
+func cartRoutes(r *gin.Engine, s testServices) {
+	cartHandler := NewCartHandler(cart.NewService(store.NewCollection[cart.Cart](""), s.products, s.prices, order.CartPlacer(s.orders), 0))
+
+	carts := r.Group("/carts")
+	{
+		carts.POST("", cartHandler.Create())
+		carts.GET(":id", cartHandler.Get())
+		carts.DELETE(":id", cartHandler.Delete())
+		carts.PUT(":id/lines/:product_id", cartHandler.SetLine())
+		carts.DELETE(":id/lines/:product_id", cartHandler.RemoveLine())
+		carts.PUT(":id/promo", cartHandler.SetPromo())
+		carts.POST(":id/lock", cartHandler.Lock())
+		carts.POST(":id/checkout", cartHandler.Checkout())
+	}
+}
+
+// orderRoutes registra /orders
+func orderRoutes(r *gin.Engine, s testServices) {
+	orderHandler := NewOrderHandler(s.orders)
+
+	orders := r.Group("/orders")
+	{
+		orders.GET("", orderHandler.GetAll())
+		orders.POST("", orderHandler.Place())
+		orders.GET(":id", orderHandler.GetByID())
+		orders.POST(":id/confirm", orderHandler.Confirm())
+		orders.POST(":id/cancel", orderHandler.Cancel())
+	}
+}
+
+// createProductServer arma un servidor con las rutas de productos
+func createProductServer(t *testing.T) *gin.Engine {
+	r := newTestRouter()
+	productRoutes(r, newTestServices(t))
+	return r
+}
+
+// createOrderServer arma un servidor de ordenes; incluye los productos para
+// ver el stock que reservan
+func createOrderServer(t *testing.T) *gin.Engine {
+	s := newTestServices(t)
+	r := newTestRouter()
+	productRoutes(r, s)
+	orderRoutes(r, s)
+	return r
+}
+
+// createCartServer arma un servidor de carritos; incluye los productos y las
+// ordenes que genera el checkout
+func createCartServer(t *testing.T) *gin.Engine {
+	s := newTestServices(t)
+	r := newTestRouter()
+	productRoutes(r, s)
+	cartRoutes(r, s)
+	orderRoutes(r, s)
 	return r
 }
 
@@ -51,7 +160,7 @@ func TestGetAllProduct_OK(t *testing.T) {
 	}
 	var respTest ObjTestResponse
 
-	r := createServer()
+	r := createProductServer(t)
 
 	req, res := createRequestTest(http.MethodGet, "/products/", "")
 
@@ -68,7 +177,7 @@ func TestGetProductById_OK(t *testing.T) {
 		Data any
 	}
 	var respTest ObjTestResponse
-	r := createServer()
+	r := createProductServer(t)
 
 	req, res := createRequestTest(http.MethodGet, "/products/1", "")
 
@@ -81,9 +190,9 @@ func TestGetProductById_OK(t *testing.T) {
 }
 
 func TestProductHandler_AddProduct(t *testing.T) {
-	data := `{"name": "TestPost321", "quantity": 155, "price": 555.99, "code_value": "TFGH312", "expiration": "11/12/1999", "is_published": true }`
+	data := `{"name": "TestPost321", "quantity": 155, "price": 555.99, "code_value": "TFGH999", "expiration": "11/12/1999", "is_published": true }`
 
-	r := createServer()
+	r := createProductServer(t)
 
 	req, res := createRequestTest(http.MethodPost, "/products/", data)
 
@@ -95,7 +204,7 @@ func TestProductHandler_AddProduct(t *testing.T) {
 func TestProductHandler_Patch(t *testing.T) {
 	data := `{"name": "nombre actualizado 4"}`
 
-	r := createServer()
+	r := createProductServer(t)
 
 	req, res := createRequestTest(http.MethodPatch, "/products/502", data)
 
@@ -107,7 +216,7 @@ func TestProductHandler_Patch(t *testing.T) {
 func TestProductHandler_Put(t *testing.T) {
 	data := `{"name": "ACTUALIZO NOMBRE", "quantity": 555, "price": 555.99, "code_value": "TFF4455", "expiration": "15/05/2015", "is_published": true }`
 
-	r := createServer()
+	r := createProductServer(t)
 
 	req, res := createRequestTest(http.MethodPatch, "/products/504", data)
 
@@ -116,13 +225,890 @@ func TestProductHandler_Put(t *testing.T) {
 	assert.Equal(t, 200, res.Code)
 }
 
+func TestProductHandler_PutReplaces(t *testing.T) {
+	type ObjTestResponse struct {
+		Data domain.Product
+	}
+	data := `{"name": "ACTUALIZO NOMBRE", "quantity": 555, "price": 555.99, "code_value": "TFF4455", "expiration": "15/05/2015"}`
+
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodPut, "/products/504", data)
+
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code)
+	var resp ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+	assert.Equal(t, "ACTUALIZO NOMBRE", resp.Data.Name)
+	assert.Equal(t, 555, resp.Data.Quantity)
+	assert.Equal(t, "TFF4455", resp.Data.CodeValue)
+
+	// PUT reemplaza el producto entero: no acepta solo algunos campos
+	req, res = createRequestTest(http.MethodPut, "/products/504", `{"name": "sin codigo"}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+}
+
 func TestProductHandler_Delete(t *testing.T) {
 
-	r := createServer()
+	r := createProductServer(t)
 
-	req, res := createRequestTest(http.MethodDelete, "/products/503", "")
+	req, res := createRequestTest(http.MethodDelete, "/products/501", "")
 
 	r.ServeHTTP(res, req)
 
 	assert.Equal(t, 204, res.Code)
+
+	req, res = createRequestTest(http.MethodGet, "/products/501", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 404, res.Code)
+}
+
+func TestProductHandler_IsolatedDataset(t *testing.T) {
+	// cada test arranca con el fixture intacto aunque otro haya borrado 501
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodGet, "/products/501", "")
+
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code)
+}
+
+func TestProductHandler_PutIfMatch(t *testing.T) {
+	data := `{"name": "ACTUALIZO NOMBRE", "quantity": 555, "price": 555.99, "code_value": "TFF4455", "expiration": "15/05/2015", "is_published": true }`
+
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodGet, "/products/504", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	current := res.Header().Get("ETag")
+
+	req, res = createRequestTest(http.MethodPut, "/products/504", data)
+	req.Header.Add("If-Match", current)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.NotEqual(t, current, res.Header().Get("ETag"))
+
+	// el mismo ETag ya quedo viejo
+	req, res = createRequestTest(http.MethodPut, "/products/504", data)
+	req.Header.Add("If-Match", current)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 412, res.Code)
+
+	req, res = createRequestTest(http.MethodDelete, "/products/504", "")
+	req.Header.Add("If-Match", current)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 412, res.Code)
+}
+
+func TestProductHandler_IfMatchRequired(t *testing.T) {
+	t.Setenv("REQUIRE_IF_MATCH", "true")
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodPatch, "/products/502", `{"name": "sin if-match"}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 428, res.Code)
+
+	req, res = createRequestTest(http.MethodPatch, "/products/502", `{"name": "con if-match"}`)
+	req.Header.Add("If-Match", "*")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+}
+
+func TestProductHandler_ConditionalGet(t *testing.T) {
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodGet, "/products/", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	listTag := res.Header().Get("ETag")
+	assert.NotEmpty(t, listTag)
+	assert.NotEmpty(t, res.Header().Get("Last-Modified"))
+
+	req, res = createRequestTest(http.MethodGet, "/products/", "")
+	req.Header.Add("If-None-Match", listTag)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 304, res.Code)
+	assert.Empty(t, res.Body.Bytes())
+
+	req, res = createRequestTest(http.MethodGet, "/products/502", "")
+	r.ServeHTTP(res, req)
+	productTag := res.Header().Get("ETag")
+	req, res = createRequestTest(http.MethodGet, "/products/502", "")
+	req.Header.Add("If-None-Match", productTag)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 304, res.Code)
+
+	// un cambio invalida ambas copias
+	req, res = createRequestTest(http.MethodPatch, "/products/502", `{"name": "otro nombre"}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+
+	req, res = createRequestTest(http.MethodGet, "/products/", "")
+	req.Header.Add("If-None-Match", listTag)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+
+	req, res = createRequestTest(http.MethodGet, "/products/502", "")
+	req.Header.Add("If-None-Match", productTag)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+}
+
+func TestProductHandler_GetAllPaginated(t *testing.T) {
+	type ObjTestResponse struct {
+		Data       []map[string]any
+		Pagination struct {
+			Total      int
+			Limit      int
+			NextCursor string `json:"next_cursor"`
+		}
+	}
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodGet, "/products/?limit=2&sort=-price&fields=id,price", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	var page ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &page))
+	assert.Len(t, page.Data, 2)
+	assert.Len(t, page.Data[0], 2)
+	assert.GreaterOrEqual(t, page.Data[0]["price"], page.Data[1]["price"])
+	assert.Equal(t, 2, page.Pagination.Limit)
+	assert.NotEmpty(t, page.Pagination.NextCursor)
+	assert.Contains(t, res.Header().Get("Link"), `rel="next"`)
+
+	req, res = createRequestTest(http.MethodGet, "/products/?limit=2&sort=-price&fields=id,price&cursor="+page.Pagination.NextCursor, "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	var next ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &next))
+	assert.NotEqual(t, page.Data[0]["id"], next.Data[0]["id"])
+
+	req, res = createRequestTest(http.MethodGet, "/products/?sort=nope", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+}
+
+func TestProductHandler_SearchFilter(t *testing.T) {
+	type ObjTestResponse struct {
+		Data []map[string]any
+	}
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodGet, `/products/search?filter=`+url.QueryEscape(`price>=500 AND is_published=true`), "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	var resp ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Data)
+	for _, p := range resp.Data {
+		assert.GreaterOrEqual(t, p["price"], 500.0)
+		assert.Equal(t, true, p["is_published"])
+	}
+
+	// priceGt sigue funcionando y se combina con el filtro
+	req, res = createRequestTest(http.MethodGet, `/products/search?priceGt=999&filter=`+url.QueryEscape(`name~"cake"`), "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 404, res.Code)
+
+	req, res = createRequestTest(http.MethodGet, `/products/search?filter=`+url.QueryEscape(`price>>1`), "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+
+	req, res = createRequestTest(http.MethodGet, `/products/search`, "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+}
+
+func TestProductHandler_SearchText(t *testing.T) {
+	type ObjTestResponse struct {
+		Data []map[string]any
+	}
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodGet, "/products/search?q=vanila+flavoring&fields=id,name", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	var resp ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Data)
+	assert.Equal(t, "Flavouring Vanilla Artificial", resp.Data[0]["name"])
+	assert.Contains(t, resp.Data[0], "score")
+
+	req, res = createRequestTest(http.MethodGet, "/products/search?q=cake&limit=2", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Header().Get("Link"), "offset=2")
+
+	req, res = createRequestTest(http.MethodGet, "/products/search?q=cake&sort=price", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+}
+
+func TestProductHandler_Expiration(t *testing.T) {
+	type ObjTestResponse struct {
+		Data map[string]any
+	}
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodPost, "/products/", `{"name": "iso", "quantity": 1, "price": 1, "code_value": "ISO1", "expiration": "2030-02-01"}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 201, res.Code)
+	var resp ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+	assert.Equal(t, "01/02/2030", resp.Data["expiration"])
+
+	for _, expiration := range []string{"31/02/2022", "99/99/2020", "2022-13-01", "tomorrow"} {
+		body := `{"name": "bad", "quantity": 1, "price": 1, "code_value": "BAD1", "expiration": "` + expiration + `"}`
+		req, res = createRequestTest(http.MethodPost, "/products/", body)
+		r.ServeHTTP(res, req)
+		assert.Equal(t, 400, res.Code, expiration)
+		assert.Contains(t, res.Body.String(), "invalid expiration", expiration)
+	}
+
+	req, res = createRequestTest(http.MethodPatch, "/products/502", `{"expiration": "30/02/2031"}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+}
+
+func TestProductHandler_ExpirationLifecycle(t *testing.T) {
+	type ObjTestResponse struct {
+		Data       []domain.Product
+		Pagination struct{ Total int }
+	}
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodGet, "/products/expired?limit=5&filter=is_published=true", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	var expired ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &expired))
+	assert.Len(t, expired.Data, 5)
+	assert.True(t, expired.Data[0].Expiration.Before(domain.Today()))
+
+	req, res = createRequestTest(http.MethodPost, "/products/expire", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	var report struct {
+		Data struct{ Expired []int }
+	}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &report))
+	assert.NotEmpty(t, report.Data.Expired)
+
+	// el fixture repite el id 499: solo se actualiza el primero de los dos
+	req, res = createRequestTest(http.MethodGet, "/products/expired?filter=is_published=true", "")
+	r.ServeHTTP(res, req)
+	var after ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &after))
+	assert.Equal(t, expired.Pagination.Total, len(report.Data.Expired)+after.Pagination.Total)
+
+	req, res = createRequestTest(http.MethodGet, "/products/expiring?days=0", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+	req, res = createRequestTest(http.MethodGet, "/products/expiring?days=3650", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	req, res = createRequestTest(http.MethodPost, "/products/expire?action=delete", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+}
+
+func TestProductHandler_ConsumerPrice(t *testing.T) {
+	type ObjTestResponse struct {
+		Data struct {
+			Products   []map[string]any
+			Lines      []map[string]any
+			TotalPrice float64 `json:"total_price"`
+		}
+	}
+	r := createProductServer(t)
+
+	// 71.42 + 352.79 + 839.02 = 1263.23, con 21% de recargo por linea
+	req, res := createRequestTest(http.MethodGet, "/products/consumer_price?list=1,2,5", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), `"total_price":1528.51`)
+	var resp ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data.Products, 3)
+	assert.Len(t, resp.Data.Lines, 3)
+	assert.Equal(t, 86.42, resp.Data.Lines[0]["total"])
+
+	req, res = createRequestTest(http.MethodGet, "/products/consumer_price?list=1:3", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), `"total_price":259.25`)
+
+	for _, list := range []string{"1,1", "1,abc", "1:0", "3", "99999"} {
+		req, res = createRequestTest(http.MethodGet, "/products/consumer_price?list="+list, "")
+		r.ServeHTTP(res, req)
+		assert.Equal(t, 400, res.Code, list)
+	}
+	req, res = createRequestTest(http.MethodGet, "/products/consumer_price?list=1&promo=NOPE", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+}
+
+func TestCartHandler_Checkout(t *testing.T) {
+	type ObjTestResponse struct {
+		Data struct {
+			Id      int
+			Status  string
+			OrderId int `json:"order_id"`
+			Quote   struct {
+				TotalPrice float64 `json:"total_price"`
+			}
+		}
+	}
+	r := createCartServer(t)
+	decode := func(res *httptest.ResponseRecorder) ObjTestResponse {
+		var resp ObjTestResponse
+		assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// los productos del fixture estan vencidos
+	ids := make([]string, 2)
+	for i, body := range []string{
+		`{"name": "cart 1", "quantity": 5, "price": 100, "code_value": "CART1", "expiration": "01/01/2099", "is_published": true}`,
+		`{"name": "cart 2", "quantity": 5, "price": 50, "code_value": "CART2", "expiration": "01/01/2099", "is_published": true}`,
+	} {
+		req, res := createRequestTest(http.MethodPost, "/products/", body)
+		r.ServeHTTP(res, req)
+		assert.Equal(t, 201, res.Code)
+		ids[i] = strconv.Itoa(decode(res).Data.Id)
+	}
+
+	req, res := createRequestTest(http.MethodPost, "/carts", `{"lines": [{"product_id": `+ids[0]+`, "quantity": 2}]}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 201, res.Code)
+	id := strconv.Itoa(decode(res).Data.Id)
+
+	// 100 x 2 + 50 = 250, con 21% de recargo
+	req, res = createRequestTest(http.MethodPut, "/carts/"+id+"/lines/"+ids[1], `{"quantity": 1}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, 302.5, decode(res).Data.Quote.TotalPrice)
+
+	// producto vencido
+	req, res = createRequestTest(http.MethodPut, "/carts/"+id+"/lines/5", `{"quantity": 1}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+	req, res = createRequestTest(http.MethodPut, "/carts/"+id+"/lines/"+ids[0], `{"quantity": 6}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 409, res.Code)
+
+	req, res = createRequestTest(http.MethodPost, "/carts/"+id+"/checkout", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 409, res.Code)
+	req, res = createRequestTest(http.MethodPost, "/carts/"+id+"/lock", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "locked", decode(res).Data.Status)
+	req, res = createRequestTest(http.MethodPost, "/carts/"+id+"/checkout", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "ordered", decode(res).Data.Status)
+	assert.NotZero(t, decode(res).Data.OrderId)
+
+	req, res = createRequestTest(http.MethodGet, "/products/"+ids[0], "")
+	r.ServeHTTP(res, req)
+	assert.Contains(t, res.Body.String(), `"quantity":5,`)
+	assert.Contains(t, res.Body.String(), `"reserved":2`)
+
+	req, res = createRequestTest(http.MethodGet, "/carts/999", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 404, res.Code)
+}
+
+func TestOrderHandler_PlaceConfirmCancel(t *testing.T) {
+	type ObjTestResponse struct {
+		Data struct {
+			Id       int
+			Status   string
+			Quantity int
+			Reserved int
+		}
+	}
+	r := createOrderServer(t)
+	decode := func(res *httptest.ResponseRecorder) ObjTestResponse {
+		var resp ObjTestResponse
+		assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+		return resp
+	}
+	stock := func(id string) (int, int) {
+		req, res := createRequestTest(http.MethodGet, "/products/"+id, "")
+		r.ServeHTTP(res, req)
+		data := decode(res).Data
+		return data.Quantity, data.Reserved
+	}
+
+	req, res := createRequestTest(http.MethodPost, "/products/", `{"name": "order", "quantity": 5, "price": 100, "code_value": "ORDER1", "expiration": "01/01/2099", "is_published": true}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 201, res.Code)
+	productId := strconv.Itoa(decode(res).Data.Id)
+	place := func(quantity int) *httptest.ResponseRecorder {
+		req, res := createRequestTest(http.MethodPost, "/orders", `{"lines": [{"product_id": `+productId+`, "quantity": `+strconv.Itoa(quantity)+`}]}`)
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	res = place(3)
+	assert.Equal(t, 201, res.Code)
+	assert.Equal(t, "pending", decode(res).Data.Status)
+	first := strconv.Itoa(decode(res).Data.Id)
+	quantity, reserved := stock(productId)
+	assert.Equal(t, 5, quantity)
+	assert.Equal(t, 3, reserved)
+
+	// quedan 2 unidades disponibles
+	assert.Equal(t, 409, place(3).Code)
+	res = place(2)
+	assert.Equal(t, 201, res.Code)
+	second := strconv.Itoa(decode(res).Data.Id)
+
+	// un producto reservado no se puede borrar
+	req, res = createRequestTest(http.MethodDelete, "/products/"+productId, "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 409, res.Code)
+
+	req, res = createRequestTest(http.MethodPost, "/orders/"+first+"/confirm", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "confirmed", decode(res).Data.Status)
+	quantity, reserved = stock(productId)
+	assert.Equal(t, 2, quantity)
+	assert.Equal(t, 2, reserved)
+
+	req, res = createRequestTest(http.MethodPost, "/orders/"+second+"/cancel", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "cancelled", decode(res).Data.Status)
+	quantity, reserved = stock(productId)
+	assert.Equal(t, 2, quantity)
+	assert.Equal(t, 0, reserved)
+
+	req, res = createRequestTest(http.MethodPost, "/orders/"+first+"/cancel", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 409, res.Code)
+	req, res = createRequestTest(http.MethodGet, "/orders/999", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 404, res.Code)
+	// producto vencido del fixture
+	req, res = createRequestTest(http.MethodPost, "/orders", `{"lines": [{"product_id": 5, "quantity": 1}]}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+}
+
+func TestInventoryHandler_Movements(t *testing.T) {
+	type Movement struct {
+		Kind     string
+		Quantity int
+		Balance  int
+		Reason   string
+		Actor    string
+		OrderId  int `json:"order_id"`
+	}
+	r := createOrderServer(t)
+
+	req, res := createRequestTest(http.MethodPost, "/products/", `{"name": "ledger", "quantity": 10, "price": 100, "code_value": "LEDGER1", "expiration": "01/01/2099", "is_published": true}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 201, res.Code)
+	var created struct{ Data struct{ Id int } }
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &created))
+	id := strconv.Itoa(created.Data.Id)
+
+	req, res = createRequestTest(http.MethodPost, "/products/"+id+"/movements", `{"kind": "write_off", "quantity": -2, "reason": "broken"}`)
+	req.Header.Add("X-Actor", "ana")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 201, res.Code)
+	req, res = createRequestTest(http.MethodPost, "/products/"+id+"/movements", `{"kind": "receipt", "quantity": -2}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+	req, res = createRequestTest(http.MethodPatch, "/products/"+id, `{"quantity": 20}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+
+	req, res = createRequestTest(http.MethodPost, "/orders", `{"lines": [{"product_id": `+id+`, "quantity": 3}]}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 201, res.Code)
+	var order struct{ Data struct{ Id int } }
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &order))
+	req, res = createRequestTest(http.MethodPost, "/orders/"+strconv.Itoa(order.Data.Id)+"/confirm", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+
+	req, res = createRequestTest(http.MethodGet, "/products/"+id+"/movements", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	var movements struct{ Data []Movement }
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &movements))
+	assert.Equal(t, []Movement{
+		{Kind: "receipt", Quantity: 10, Balance: 10, Reason: "initial stock", Actor: "api"},
+		{Kind: "write_off", Quantity: -2, Balance: 8, Reason: "broken", Actor: "ana"},
+		{Kind: "adjustment", Quantity: 12, Balance: 20, Reason: "product update", Actor: "api"},
+		{Kind: "sale", Quantity: -3, Balance: 17, Reason: "order " + strconv.Itoa(order.Data.Id), Actor: "api", OrderId: order.Data.Id},
+	}, movements.Data)
+
+	req, res = createRequestTest(http.MethodGet, "/products/"+id+"/stock", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), `"quantity":17`)
+	req, res = createRequestTest(http.MethodGet, "/products/"+id+"/stock?date=01/01/2000", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	assert.Contains(t, res.Body.String(), `"quantity":0`)
+	req, res = createRequestTest(http.MethodGet, "/products/"+id+"/stock?date=yesterday", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 400, res.Code)
+	req, res = createRequestTest(http.MethodGet, "/products/999/movements", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 404, res.Code)
+
+	// los productos del fixture no tienen movimientos hasta reconciliar
+	req, res = createRequestTest(http.MethodPost, "/products/reconcile", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+	req, res = createRequestTest(http.MethodGet, "/products/1/movements", "")
+	r.ServeHTTP(res, req)
+	assert.Contains(t, res.Body.String(), `"opening balance"`)
+}
+
+func TestProductHandler_Bulk(t *testing.T) {
+	type ObjTestResponse struct {
+		Data struct {
+			Succeeded int
+			Failed    int
+			Results   []struct {
+				Status int
+				Id     int
+				Error  string
+			}
+		}
+	}
+	r := createProductServer(t)
+	send := func(method, url, body string) (int, ObjTestResponse) {
+		req, res := createRequestTest(method, url, body)
+		r.ServeHTTP(res, req)
+		var resp ObjTestResponse
+		_ = json.Unmarshal(res.Body.Bytes(), &resp)
+		return res.Code, resp
+	}
+	statuses := func(resp ObjTestResponse) []int {
+		out := []int{}
+		for _, item := range resp.Data.Results {
+			out = append(out, item.Status)
+		}
+		return out
+	}
+
+	code, resp := send(http.MethodPost, "/products/bulk", `[
+		{"name": "bulk 1", "quantity": 5, "price": 10, "code_value": "BULK1", "expiration": "01/01/2099"},
+		{"name": "bulk 2", "quantity": 0, "price": 10, "code_value": "BULK2", "expiration": "01/01/2099"},
+		{"name": "bulk 3", "quantity": 5, "price": 10, "code_value": "BULK1", "expiration": "01/01/2099"},
+		{"name": "bulk 4", "quantity": 5, "price": 10, "code_value": "BULK4", "expiration": "31/02/2099"}
+	]`)
+	assert.Equal(t, http.StatusMultiStatus, code)
+	assert.Equal(t, []int{201, 400, 409, 400}, statuses(resp))
+	assert.Equal(t, 1, resp.Data.Succeeded)
+	id := resp.Data.Results[0].Id
+
+	// todo o nada: el item invalido aborta al valido
+	code, resp = send(http.MethodPost, "/products/bulk?atomic=true", `[
+		{"name": "bulk 5", "quantity": 5, "price": 10, "code_value": "BULK5", "expiration": "01/01/2099"},
+		{"name": "bulk 6", "quantity": 5, "price": 10, "code_value": "BULK1", "expiration": "01/01/2099"}
+	]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []int{424, 409}, statuses(resp))
+	req, res := createRequestTest(http.MethodGet, "/products/search?filter="+url.QueryEscape(`code_value = "BULK5"`), "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 404, res.Code)
+
+	code, resp = send(http.MethodPatch, "/products/bulk", `[{"id": `+strconv.Itoa(id)+`, "quantity": 7}, {"id": 9999, "name": "x"}, {"name": "no id"}]`)
+	assert.Equal(t, http.StatusMultiStatus, code)
+	assert.Equal(t, []int{200, 404, 400}, statuses(resp))
+	req, res = createRequestTest(http.MethodGet, "/products/"+strconv.Itoa(id)+"/movements", "")
+	r.ServeHTTP(res, req)
+	assert.Contains(t, res.Body.String(), `"quantity":2,"balance":7`)
+
+	code, resp = send(http.MethodDelete, "/products/bulk?atomic=true", `[`+strconv.Itoa(id)+`, 9999]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []int{424, 404}, statuses(resp))
+	code, resp = send(http.MethodDelete, "/products/bulk", `[`+strconv.Itoa(id)+`]`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{200}, statuses(resp))
+	req, res = createRequestTest(http.MethodGet, "/products/"+strconv.Itoa(id), "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, 404, res.Code)
+
+	code, _ = send(http.MethodPost, "/products/bulk", `{"name": "not an array"}`)
+	assert.Equal(t, 400, code)
+	code, _ = send(http.MethodPost, "/products/bulk?atomic=maybe", `[]`)
+	assert.Equal(t, 400, code)
+}
+
+func TestImportHandler_Import(t *testing.T) {
+	type ObjTestResponse struct {
+		Data importer.Report
+	}
+	r := createProductServer(t)
+	csv := "Nombre;Cantidad;SKU;Vence;Precio\n" +
+		"Import 1;5;IMP1;01/01/2099;10,50\n" +
+		"Import 2;5;S82254D;01/01/2099;10\n" +
+		"Import 3;0;IMP3;01/01/2099;10\n"
+	columns := "&columns=" + url.QueryEscape("name=Nombre,quantity=Cantidad,code_value=SKU,expiration=Vence,price=Precio")
+
+	// dry run con el archivo en un form multipart
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "productos.csv")
+	assert.Nil(t, err)
+	_, _ = part.Write([]byte(csv))
+	assert.Nil(t, form.Close())
+	req := httptest.NewRequest(http.MethodPost, "/products/import?dry_run=true"+columns, &body)
+	req.Header.Add("Content-Type", form.FormDataContentType())
+	req.Header.Add("token", "secret_321")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var resp ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+	assert.Equal(t, []importer.RowStatus{importer.RowValid, importer.RowConflict, importer.RowInvalid},
+		[]importer.RowStatus{resp.Data.Results[0].Status, resp.Data.Results[1].Status, resp.Data.Results[2].Status})
+	assert.Equal(t, 4, resp.Data.Results[2].Row)
+
+	// el csv como body: se agrega solo la fila valida
+	req, res = createRequestTest(http.MethodPost, "/products/import?dry_run=false"+columns, csv)
+	req.Header.Set("Content-Type", "text/csv")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusMultiStatus, res.Code)
+	resp = ObjTestResponse{}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Data.Created)
+	req, res = createRequestTest(http.MethodGet, "/products/"+strconv.Itoa(resp.Data.Results[0].Id), "")
+	r.ServeHTTP(res, req)
+	assert.Contains(t, res.Body.String(), `"price":10.5`)
+
+	req, res = createRequestTest(http.MethodPost, "/products/import", csv)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), "missing columns")
+
+	req, res = createRequestTest(http.MethodPost, "/products/import", "name"+strings.Repeat(",x", maxImportColumns)+"\n")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), "more than 100 columns")
+	req, res = createRequestTest(http.MethodPost, "/products/import", "name\n"+strings.Repeat("x\n", maxImportRows+1))
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), "more than 10000 rows")
+}
+
+func TestProductHandler_Export(t *testing.T) {
+	r := createProductServer(t)
+
+	req, res := createRequestTest(http.MethodGet, "/products/export", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="products-\d{8}\.csv"$`, res.Header().Get("Content-Disposition"))
+	lines := strings.Split(strings.TrimSpace(res.Body.String()), "\n")
+	assert.Len(t, lines, 506)
+	assert.Equal(t, "id,name,quantity,code_value,is_published,expiration,price,version,lifecycle_reason,reserved", lines[0])
+	assert.Equal(t, "1,Oil - Margarine,439,S82254D,true,15/12/2021,71.42,0,,0", lines[1])
+	assert.Empty(t, res.Result().Trailer.Get("X-Export-Error"))
+
+	req, res = createRequestTest(http.MethodGet, "/products/export?format=ndjson&priceGt=500&sort=-id&fields=id,price", "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/x-ndjson", res.Header().Get("Content-Type"))
+	lines = strings.Split(strings.TrimSpace(res.Body.String()), "\n")
+	assert.Len(t, lines, 252)
+	var first map[string]any
+	assert.Nil(t, json.Unmarshal([]byte(lines[len(lines)-1]), &first))
+	assert.Equal(t, map[string]any{"id": 5.0, "price": first["price"]}, first)
+
+	// json es un arreglo aunque no haya resultados
+	req, res = createRequestTest(http.MethodGet, "/products/export?format=json&filter="+url.QueryEscape(`code_value = "NOPE"`), "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "[]", res.Body.String())
+	req, res = createRequestTest(http.MethodGet, "/products/export?format=json&filter="+url.QueryEscape(`code_value = "S82254D"`), "")
+	r.ServeHTTP(res, req)
+	var products []domain.Product
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &products))
+	assert.Equal(t, 1, len(products))
+
+	// un nombre que empieza como formula no se ejecuta al abrir el csv
+	req, res = createRequestTest(http.MethodPost, "/products/", `{"name":"=HYPERLINK(\"http://x\")","quantity":1,"code_value":"FORMULA1","expiration":"01/01/2099","price":10}`)
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	req, res = createRequestTest(http.MethodGet, "/products/export?fields=name,code_value&filter="+url.QueryEscape(`code_value = "FORMULA1"`), "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, "name,code_value\n\"'=HYPERLINK(\"\"http://x\"\")\",FORMULA1\n", res.Body.String())
+
+	for _, query := range []string{"format=xml", "filter=price~~", "q=oil&sort=name"} {
+		req, res = createRequestTest(http.MethodGet, "/products/export?"+query, "")
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusBadRequest, res.Code, query)
+	}
+}
+
+func TestProductHandler_ExportImportRoundTrip(t *testing.T) {
+	r := createProductServer(t)
+	names := []string{`=HYPERLINK("http://x")`, "+54 11", "-5% off", "@home", "'quoted", "plain"}
+	for i, name := range names {
+		body, err := json.Marshal(map[string]any{"name": name, "quantity": 1, "code_value": "RT" + strconv.Itoa(i), "expiration": "01/01/2099", "price": 10})
+		assert.Nil(t, err)
+		req, res := createRequestTest(http.MethodPost, "/products/", string(body))
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusCreated, res.Code)
+	}
+	export := "/products/export?fields=name,quantity,code_value,is_published,expiration,price&sort=code_value&filter=" + url.QueryEscape(`code_value ~ "RT"`)
+	req, res := createRequestTest(http.MethodGet, export, "")
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	exported := res.Body.String()
+
+	// otro catalogo importa el csv exportado con los mismos valores
+	other := createProductServer(t)
+	req, res = createRequestTest(http.MethodPost, "/products/import?dry_run=false", exported)
+	req.Header.Set("Content-Type", "text/csv")
+	other.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	req, res = createRequestTest(http.MethodGet, export, "")
+	other.ServeHTTP(res, req)
+	assert.Equal(t, exported, res.Body.String())
+	req, res = createRequestTest(http.MethodGet, "/products/export?format=ndjson&fields=name&sort=code_value&filter="+url.QueryEscape(`code_value ~ "RT"`), "")
+	other.ServeHTTP(res, req)
+	var imported []string
+	for _, line := range strings.Split(strings.TrimSpace(res.Body.String()), "\n") {
+		var p struct{ Name string }
+		assert.Nil(t, json.Unmarshal([]byte(line), &p))
+		imported = append(imported, p.Name)
+	}
+	assert.Equal(t, names, imported)
+}
+
+func TestProductHandler_PatchDocuments(t *testing.T) {
+	type ObjTestResponse struct {
+		Data domain.Product
+	}
+	r := createProductServer(t)
+	send := func(contentType, body string) (int, ObjTestResponse) {
+		req, res := createRequestTest(http.MethodPatch, "/products/1", body)
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(res, req)
+		var resp ObjTestResponse
+		_ = json.Unmarshal(res.Body.Bytes(), &resp)
+		return res.Code, resp
+	}
+
+	// false y 0 se aplican, a diferencia del patch con application/json
+	code, resp := send("application/merge-patch+json", `{"is_published": false, "quantity": 0, "price": 555.99}`)
+	assert.Equal(t, 200, code)
+	assert.False(t, resp.Data.IsPublished)
+	assert.Equal(t, 0, resp.Data.Quantity)
+	assert.Equal(t, "555.99", resp.Data.Price.Decimal())
+	assert.Equal(t, "Oil - Margarine", resp.Data.Name)
+	assert.Equal(t, 1, resp.Data.Version)
+
+	// null borra el nombre y el producto resultante no es valido
+	code, _ = send("application/merge-patch+json", `{"name": null}`)
+	assert.Equal(t, 422, code)
+	code, _ = send("application/merge-patch+json", `{"color": "red"}`)
+	assert.Equal(t, 422, code)
+	code, _ = send("application/merge-patch+json", `{"reserved": 3}`)
+	assert.Equal(t, 422, code)
+	code, _ = send("application/merge-patch+json", `{"version": 0}`)
+	assert.Equal(t, 412, code)
+	code, _ = send("application/merge-patch+json", `{"name":`)
+	assert.Equal(t, 400, code)
+
+	code, resp = send("application/json-patch+json", `[{"op": "test", "path": "/version", "value": 1}, {"op": "replace", "path": "/name", "value": "Margarina"}]`)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "Margarina", resp.Data.Name)
+	assert.Equal(t, 2, resp.Data.Version)
+
+	// el test falla y el replace no se aplica
+	code, _ = send("application/json-patch+json", `[{"op": "replace", "path": "/name", "value": "Otra"}, {"op": "test", "path": "/version", "value": 1}]`)
+	assert.Equal(t, 409, code)
+	code, _ = send("application/json-patch+json", `[{"op": "remove", "path": "/color"}]`)
+	assert.Equal(t, 422, code)
+	code, _ = send("application/json-patch+json", `[{"op": "replace", "path": "/quantity", "value": -1}]`)
+	assert.Equal(t, 422, code)
+	code, _ = send("application/json-patch+json", `[{"op": "merge", "path": "/name"}]`)
+	assert.Equal(t, 400, code)
+	code, _ = send("application/merge-patch+json", `{"description": "`+strings.Repeat("a", maxPatchSize)+`"}`)
+	assert.Equal(t, 413, code)
+
+	req, res := createRequestTest(http.MethodGet, "/products/1", "")
+	r.ServeHTTP(res, req)
+	var final ObjTestResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &final))
+	assert.Equal(t, "Margarina", final.Data.Name)
+	assert.Equal(t, 2, final.Data.Version)
+}
+
+func TestProductHandler_Problem(t *testing.T) {
+	type Problem struct {
+		Type      string
+		Title     string
+		Status    int
+		Detail    string
+		Instance  string
+		Code      string
+		RequestID string `json:"request_id"`
+		Errors    []domain.FieldError
+	}
+	r := createProductServer(t)
+	send := func(method, url, body string) (*httptest.ResponseRecorder, Problem) {
+		req, res := createRequestTest(method, url, body)
+		req.Header.Set("X-Request-ID", "test-"+strconv.Itoa(len(body)))
+		r.ServeHTTP(res, req)
+		var problem Problem
+		_ = json.Unmarshal(res.Body.Bytes(), &problem)
+		return res, problem
+	}
+
+	res, problem := send(http.MethodGet, "/products/99999", "")
+	assert.Equal(t, 404, res.Code)
+	assert.Equal(t, "application/problem+json", res.Header().Get("Content-Type"))
+	assert.Equal(t, "test-0", res.Header().Get("X-Request-ID"))
+	assert.Equal(t, Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "product not found", Instance: "/products/99999", Code: "product_not_found", RequestID: "test-0"}, problem)
+
+	body := `{"quantity": 0, "price": 10, "code_value": "NEW1", "expiration": "01/01/2099"}`
+	res, problem = send(http.MethodPost, "/products/", body)
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, "invalid_product", problem.Code)
+	assert.Equal(t, []domain.FieldError{
+		{Field: "name", Code: "required", Message: "name is required"},
+		{Field: "quantity", Code: "not_positive", Message: "quantity must be greater than 0"},
+	}, problem.Errors)
+
+	res, problem = send(http.MethodPut, "/products/1", `{"name": "x", "quantity": 1, "price": -1, "code_value": "S82254D", "expiration": "01/01/2099"}`)
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, []domain.FieldError{{Field: "price", Code: "not_positive", Message: "price must be greater than 0"}}, problem.Errors)
+
+	res, problem = send(http.MethodPost, "/products/", `{"name": "x", "quantity": "3"}`)
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, []domain.FieldError{{Field: "quantity", Code: "invalid_type", Message: "quantity must be an integer"}}, problem.Errors)
+
+	res, problem = send(http.MethodPost, "/products/", `{"name": "dup", "quantity": 1, "price": 1, "code_value": "S82254D", "expiration": "01/01/2099"}`)
+	assert.Equal(t, 409, res.Code)
+	assert.Equal(t, "code_value_taken", problem.Code)
+
+	res, problem = send(http.MethodGet, "/products/abc", "")
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, "bad_request", problem.Code)
+
+	// sin X-Request-ID se genera uno
+	req, res := createRequestTest(http.MethodGet, "/products/99999", "")
+	r.ServeHTTP(res, req)
+	assert.Len(t, res.Header().Get("X-Request-ID"), 32)
+	assert.Contains(t, res.Body.String(), `"request_id":"`+res.Header().Get("X-Request-ID")+`"`)
 }
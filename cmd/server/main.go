@@ -1,9 +1,17 @@
 package main
 
 import (
+	"context"
 	"github.com/fgiudicatti-meli/web-server/cmd/server/handler"
 	"github.com/fgiudicatti-meli/web-server/cmd/server/middlewares"
+	"github.com/fgiudicatti-meli/web-server/internal/cart"
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/importer"
+	"github.com/fgiudicatti-meli/web-server/internal/inventory"
+	"github.com/fgiudicatti-meli/web-server/internal/order"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
 	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
 	"github.com/fgiudicatti-meli/web-server/pkg/store"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -12,6 +20,7 @@ import (
 	"github.com/swaggo/swag/example/basic/docs"
 	"log"
 	"os"
+	"time"
 )
 
 // @title MELI Bootcamp API
@@ -30,15 +39,56 @@ func main() {
 	if err := godotenv.Load("../../config.env"); err != nil {
 		log.Fatal("Error loading config.env file: ", err)
 	}
+	if format := os.Getenv("EXPIRATION_FORMAT"); format != "" {
+		if err := domain.SetDateFormat(format); err != nil {
+			log.Fatal("Error in EXPIRATION_FORMAT: ", err)
+		}
+	}
+	if currency := os.Getenv("CURRENCY"); currency != "" {
+		if err := money.SetDefaultCurrency(currency); err != nil {
+			log.Fatal("Error in CURRENCY: ", err)
+		}
+	}
 
-	storage := store.NewStore("../../products.json")
+	storage, err := newStorage()
+	if err != nil {
+		log.Fatal("Error opening store: ", err)
+	}
+	if checker, ok := storage.(store.IntegrityChecker); ok {
+		checkStoreIntegrity(context.Background(), checker, os.Getenv("STORE_REPAIR") == "true")
+	}
 
-	repo := product.NewRepository(storage)
+	movementStore := store.NewJournal[domain.Movement](os.Getenv("MOVEMENTS_DSN"))
+	ledger := inventory.NewService(product.NewRepository(storage), movementStore)
+	reconcileStock(ledger)
+	repo := ledger.Products()
 	service := product.NewService(repo)
+	inventoryHandler := handler.NewInventoryHandler(ledger)
 	productHandler := handler.NewProductHandler(service)
+	importHandler := handler.NewImportHandler(importer.NewService(service))
+	rules, err := loadPricingRules()
+	if err != nil {
+		log.Fatal("Error loading pricing rules: ", err)
+	}
+	prices := pricing.NewService(service, rules)
+	pricingHandler := handler.NewPricingHandler(prices)
+	var lockTTL time.Duration
+	if value := os.Getenv("CART_LOCK_TTL"); value != "" {
+		if lockTTL, err = time.ParseDuration(value); err != nil || lockTTL <= 0 {
+			log.Fatal("Error in CART_LOCK_TTL: ", value)
+		}
+	}
+	orderStore := store.NewCollection[domain.Order](os.Getenv("ORDERS_DSN"))
+	orders := order.NewService(order.NewRepository(orderStore), repo, prices)
+	orderHandler := handler.NewOrderHandler(orders)
+	cartStore := store.NewCollection[cart.Cart](os.Getenv("CARTS_DSN"))
+	cartHandler := handler.NewCartHandler(cart.NewService(cartStore, service, prices, order.CartPlacer(orders), lockTTL))
+	startExpirationJob(service)
 
 	r := gin.Default()
+	r.Use(middlewares.RequestID())
 	r.Use(middlewares.CatchPanic())
+	r.Use(middlewares.Actor())
 
 	docs.SwaggerInfo.Host = os.Getenv("HOST")
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -46,19 +96,140 @@ func main() {
 	r.GET("/ping", func(c *gin.Context) { c.String(200, "pong") })
 	products := r.Group("/products")
 	{
-		products.GET("", productHandler.GetAll())
-		products.GET(":id", productHandler.GetByID())
+		products.GET("", middlewares.CacheControl(os.Getenv("CACHE_CONTROL_PRODUCTS")), productHandler.GetAll())
+		products.GET(":id", middlewares.CacheControl(os.Getenv("CACHE_CONTROL_PRODUCT")), productHandler.GetByID())
 		// --- from here and below middleware apply in every route
 		products.Use(middlewares.MiddlewareVerifyToken())
 		products.GET("/search", productHandler.Search())
-		products.GET("/consumer_price", productHandler.GetPriceProducts())
+		products.GET("/expired", productHandler.Expired())
+		products.GET("/expiring", productHandler.Expiring())
+		products.POST("/expire", productHandler.Expire())
+		products.POST("/reconcile", inventoryHandler.Reconcile())
+		products.POST("/bulk", productHandler.BulkCreate())
+		products.PATCH("/bulk", productHandler.BulkUpdate())
+		products.DELETE("/bulk", productHandler.BulkDelete())
+		products.POST("/import", importHandler.Import())
+		products.GET("/export", productHandler.Export())
+		products.GET(":id/movements", inventoryHandler.Movements())
+		products.POST(":id/movements", inventoryHandler.Record())
+		products.GET(":id/stock", inventoryHandler.Stock())
+		products.GET("/consumer_price", pricingHandler.ConsumerPrice())
 		products.POST("", productHandler.AddProduct())
 		products.DELETE(":id", productHandler.Delete())
 		products.PATCH(":id", productHandler.Patch())
 		products.PUT(":id", productHandler.Put())
 	}
+	carts := r.Group("/carts")
+	{
+		carts.Use(middlewares.MiddlewareVerifyToken())
+		carts.POST("", cartHandler.Create())
+		carts.GET(":id", cartHandler.Get())
+		carts.DELETE(":id", cartHandler.Delete())
+		carts.PUT(":id/lines/:product_id", cartHandler.SetLine())
+		carts.DELETE(":id/lines/:product_id", cartHandler.RemoveLine())
+		carts.PUT(":id/promo", cartHandler.SetPromo())
+		carts.POST(":id/lock", cartHandler.Lock())
+		carts.POST(":id/checkout", cartHandler.Checkout())
+	}
+	orderRoutes := r.Group("/orders")
+	{
+		orderRoutes.Use(middlewares.MiddlewareVerifyToken())
+		orderRoutes.GET("", orderHandler.GetAll())
+		orderRoutes.POST("", orderHandler.Place())
+		orderRoutes.GET(":id", orderHandler.GetByID())
+		orderRoutes.POST(":id/confirm", orderHandler.Confirm())
+		orderRoutes.POST(":id/cancel", orderHandler.Cancel())
+	}
 
 	if err := r.Run(); err != nil {
 		panic(err)
 	}
 }
+
+// startExpirationJob lanza en segundo plano el job de vencimientos cada
+// EXPIRATION_JOB_INTERVAL (por ejemplo "1h"); vacio o "0" lo deshabilita.
+// EXPIRATION_ACTION elige si los vencidos se despublican o solo se marcan.
+func startExpirationJob(service product.Service) {
+	value := os.Getenv("EXPIRATION_JOB_INTERVAL")
+	if value == "" || value == "0" {
+		log.Print("expiration job disabled")
+		return
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Fatal("Error in EXPIRATION_JOB_INTERVAL: ", value)
+	}
+	action, err := product.ParseExpireAction(os.Getenv("EXPIRATION_ACTION"))
+	if err != nil {
+		log.Fatal("Error in EXPIRATION_ACTION: ", err)
+	}
+	go product.RunExpirationJob(context.Background(), service, interval, action)
+}
+
+// reconcileStock ajusta al arrancar el stock que no coincide con el libro de inventario
+func reconcileStock(ledger inventory.Service) {
+	ctx := inventory.WithActor(context.Background(), "system")
+	adjustments, err := ledger.Reconcile(ctx)
+	if err != nil {
+		log.Fatal("Error reconciling stock: ", err)
+	}
+	if len(adjustments) > 0 {
+		log.Printf("stock reconciled: %d products adjusted", len(adjustments))
+	}
+}
+
+// newStorage abre el backend registrado bajo STORE_BACKEND ("json",
+// "sqlite", ...) sobre STORE_DSN. STORE_CACHE y STORE_CACHE_POLL se pasan
+// como opciones; los backends que no las usan las ignoran.
+func newStorage() (store.Store, error) {
+	backend := os.Getenv("STORE_BACKEND")
+	if backend == "" {
+		backend = "json"
+	}
+	return store.Open(backend, store.Config{
+		DSN: os.Getenv("STORE_DSN"),
+		Options: map[string]string{
+			"cache": os.Getenv("STORE_CACHE"),
+			"poll":  os.Getenv("STORE_CACHE_POLL"),
+		},
+	})
+}
+
+// checkStoreIntegrity revisa el store al arrancar y, si repair es true,
+// reasigna los ids duplicados en lugar de abortar
+func checkStoreIntegrity(ctx context.Context, checker store.IntegrityChecker, repair bool) {
+	report, err := checker.CheckIntegrity(ctx)
+	if err != nil {
+		log.Fatal("Error checking store integrity: ", err)
+	}
+	if report.Ok() {
+		log.Println("store integrity:", report)
+		return
+	}
+	if !repair {
+		log.Println("WARNING store integrity: duplicated ids found (set STORE_REPAIR=true to fix):", report)
+		return
+	}
+	report, err = checker.Repair(ctx)
+	if err != nil {
+		log.Fatal("Error repairing store: ", err)
+	}
+	log.Println("store repaired:", report)
+}
+
+// loadPricingRules lee las reglas de PRICING_RULES, o usa los recargos
+// historicos si no esta definida; PRICE_ROUNDING vale si el archivo no
+// indica el redondeo
+func loadPricingRules() (pricing.Rules, error) {
+	rules := pricing.DefaultRules()
+	if path := os.Getenv("PRICING_RULES"); path != "" {
+		var err error
+		if rules, err = pricing.LoadRules(path); err != nil {
+			return pricing.Rules{}, err
+		}
+	}
+	if rules.Rounding == "" {
+		rules.Rounding = os.Getenv("PRICE_ROUNDING")
+	}
+	return rules, rules.Validate()
+}
@@ -1,7 +1,10 @@
 package middlewares
 
 import (
+	"errors"
 	"fmt"
+	"github.com/fgiudicatti-meli/web-server/internal/inventory"
+	"github.com/fgiudicatti-meli/web-server/pkg/web"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"os"
@@ -13,9 +16,8 @@ func MiddlewareVerifyToken() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		token := ctx.Request.Header.Get("token")
 		if token != os.Getenv("TOKEN") {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "invalid token",
-			})
+			web.Failure(ctx, http.StatusUnauthorized, errors.New("invalid token"))
+			ctx.Abort()
 			return
 		}
 		ctx.Next()
@@ -31,9 +33,51 @@ func CatchPanic() gin.HandlerFunc {
 				fmt.Printf("Verb http: %s\n", ctx.Request.Method)
 				fmt.Printf("Weight in Bytes: %b\n", ctx.Request.ContentLength)
 				fmt.Printf("Occurs: %s\n", now.Format("2006-01-02 15:04:05"))
+				fmt.Printf("Request id: %s\n", web.RequestID(ctx))
+				web.Failure(ctx, http.StatusInternalServerError, fmt.Errorf("panic: %v", err))
+				ctx.Abort()
 			}
 		}()
 
 		ctx.Next()
 	}
 }
+
+// CacheControl agrega la politica de cache a las respuestas GET de la ruta.
+// Como las respuestas dependen del token, Vary evita que un proxy comparta
+// la copia de un cliente con otro. Con policy vacia no se agrega nada.
+func CacheControl(policy string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if policy != "" && (ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead) {
+			ctx.Header("Cache-Control", policy)
+			ctx.Header("Vary", "token")
+		}
+		ctx.Next()
+	}
+}
+
+// Actor guarda en el contexto del request el autor del header X-Actor
+func Actor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		actor := ctx.Request.Header.Get("X-Actor")
+		if actor == "" {
+			actor = "api"
+		}
+		ctx.Request = ctx.Request.WithContext(inventory.WithActor(ctx.Request.Context(), actor))
+		ctx.Next()
+	}
+}
+
+// RequestID asigna a cada request un id de correlacion: el del header
+// X-Request-ID si el cliente mando uno valido o uno nuevo si no. Se devuelve
+// en el mismo header y en las respuestas de error.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Request.Header.Get(web.RequestIDHeader)
+		if !web.ValidRequestID(id) {
+			id = web.NewRequestID()
+		}
+		web.SetRequestID(ctx, id)
+		ctx.Next()
+	}
+}
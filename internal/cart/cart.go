@@ -0,0 +1,111 @@
+// Package cart mantiene carritos persistidos: lineas con cantidades que se
+// cotizan con las reglas de pricing, se bloquean por un tiempo con el precio
+// congelado y se convierten en una orden.
+package cart
+
+import (
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
+)
+
+var (
+	// ErrNotFound indica que no existe un carrito con el id pedido
+	ErrNotFound = domain.NotFound("cart_not_found", "cart not found")
+	// ErrEmpty indica que el carrito no tiene lineas
+	ErrEmpty = domain.Invalid("cart_empty", "cart is empty")
+	// ErrLocked indica que el carrito tiene una cotizacion bloqueada vigente
+	// y no se puede modificar
+	ErrLocked = domain.Conflict("cart_locked", "cart is locked")
+	// ErrNotLocked indica un checkout de un carrito sin cotizacion bloqueada
+	ErrNotLocked = domain.Conflict("cart_not_locked", "cart must be locked before checkout")
+	// ErrLockExpired indica un checkout despues del vencimiento del bloqueo
+	ErrLockExpired = domain.Conflict("cart_lock_expired", "cart lock expired")
+	// ErrOrdered indica que el carrito ya se convirtio en orden
+	ErrOrdered = domain.Conflict("cart_ordered", "cart was already ordered")
+	// ErrInvalidLine indica una cantidad no positiva o un producto que no se
+	// puede vender: inexistente, no publicado o vencido
+	ErrInvalidLine = domain.Invalid("invalid_cart_line", "invalid cart line")
+	// ErrInsufficientStock indica una linea con mas unidades que el stock
+	ErrInsufficientStock = domain.Conflict("insufficient_stock", "insufficient stock")
+)
+
+// Status es el estado de un carrito
+type Status string
+
+const (
+	// StatusOpen acepta cambios y se recotiza en cada lectura
+	StatusOpen Status = "open"
+	// StatusLocked tiene la cotizacion congelada hasta LockedUntil
+	StatusLocked Status = "locked"
+	// StatusOrdered ya se convirtio en la orden OrderId
+	StatusOrdered Status = "ordered"
+)
+
+// Line es un producto del carrito y sus unidades
+type Line struct {
+	ProductId int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// Cart es un carrito con su ultima cotizacion
+type Cart struct {
+	Id     int    `json:"id"`
+	Status Status `json:"status"`
+	Lines  []Line `json:"lines"`
+	Promo  string `json:"promo,omitempty"`
+	// Quote es nil mientras el carrito esta vacio
+	Quote *pricing.Quote `json:"quote,omitempty"`
+	// QuoteError explica por que no se pudo recotizar un carrito abierto,
+	// por ejemplo porque un producto se despublico
+	QuoteError  string     `json:"quote_error,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	OrderId     int        `json:"order_id,omitempty"`
+	Version     int        `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// items convierte las lineas al formato de pricing
+func (c Cart) items() []pricing.Item {
+	items := make([]pricing.Item, len(c.Lines))
+	for i, l := range c.Lines {
+		items[i] = pricing.Item{ProductId: l.ProductId, Quantity: l.Quantity}
+	}
+	return items
+}
+
+// lockActive indica si el bloqueo sigue vigente en now
+func (c Cart) lockActive(now time.Time) bool {
+	return c.Status == StatusLocked && c.LockedUntil != nil && now.Before(*c.LockedUntil)
+}
+
+// reopen vuelve a abrir un carrito cuyo bloqueo vencio
+func (c *Cart) reopen(now time.Time) {
+	if c.Status == StatusLocked && !c.lockActive(now) {
+		c.Status = StatusOpen
+		c.LockedUntil = nil
+	}
+}
+
+// withLine devuelve una copia de las lineas con productId en quantity
+// unidades; quantity 0 quita la linea
+func withLine(lines []Line, productId, quantity int) []Line {
+	out := make([]Line, 0, len(lines)+1)
+	found := false
+	for _, l := range lines {
+		if l.ProductId == productId {
+			found = true
+			if quantity == 0 {
+				continue
+			}
+			l.Quantity = quantity
+		}
+		out = append(out, l)
+	}
+	if !found && quantity > 0 {
+		out = append(out, Line{ProductId: productId, Quantity: quantity})
+	}
+	return out
+}
@@ -0,0 +1,277 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+// DefaultLockTTL es cuanto dura una cotizacion bloqueada si no se configura
+const DefaultLockTTL = 15 * time.Minute
+
+// Catalog es de donde el servicio obtiene los productos; product.Service lo
+// implementa
+type Catalog interface {
+	GetByID(ctx context.Context, id int) (domain.Product, error)
+}
+
+// OrderPlacer convierte un carrito bloqueado en una orden y devuelve su id.
+// Checkout crea la orden antes de guardar el carrito, asi que PlaceOrder
+// tiene que devolver la orden que ya exista para c.Id en lugar de crear
+// otra: si guardar el carrito fallo, el reintento no reserva el stock dos
+// veces.
+type OrderPlacer interface {
+	PlaceOrder(ctx context.Context, c Cart) (int, error)
+	// OrderOf devuelve la orden no cancelada del carrito cartId, o 0
+	OrderOf(ctx context.Context, cartId int) (int, error)
+}
+
+type Service interface {
+	Create(ctx context.Context, lines []Line, promo string) (Cart, error)
+	Get(ctx context.Context, id int) (Cart, error)
+	SetLine(ctx context.Context, id, productId, quantity int) (Cart, error)
+	SetPromo(ctx context.Context, id int, promo string) (Cart, error)
+	Lock(ctx context.Context, id int) (Cart, error)
+	Checkout(ctx context.Context, id int) (Cart, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type service struct {
+	carts   *store.Collection[Cart]
+	catalog Catalog
+	prices  pricing.Service
+	placer  OrderPlacer
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewService crea un servicio de carritos. ttl es cuanto dura una
+// cotizacion bloqueada; 0 usa DefaultLockTTL.
+func NewService(carts *store.Collection[Cart], catalog Catalog, prices pricing.Service, placer OrderPlacer, ttl time.Duration) Service {
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+	return &service{carts: carts, catalog: catalog, prices: prices, placer: placer, ttl: ttl, now: time.Now}
+}
+
+// Create crea un carrito abierto con lines y el codigo promo
+func (s *service) Create(ctx context.Context, lines []Line, promo string) (Cart, error) {
+	now := s.now()
+	c := Cart{Status: StatusOpen, Promo: promo, Lines: []Line{}, CreatedAt: now, UpdatedAt: now}
+	for _, l := range lines {
+		if err := s.checkLine(ctx, l); err != nil {
+			return Cart{}, err
+		}
+		c.Lines = withLine(c.Lines, l.ProductId, l.Quantity)
+	}
+	if err := s.quote(ctx, &c); err != nil {
+		return Cart{}, err
+	}
+	err := s.carts.Update(ctx, func(tx *store.CollectionTx[Cart]) error {
+		c.Id = tx.NextID()
+		c.Version = 1
+		tx.Put(c.Id, c)
+		return nil
+	})
+	if err != nil {
+		return Cart{}, err
+	}
+	return c, nil
+}
+
+// Get devuelve el carrito id. Un carrito abierto se recotiza con los
+// precios y reglas actuales; si no se puede, QuoteError dice por que.
+func (s *service) Get(ctx context.Context, id int) (Cart, error) {
+	c, ok, err := s.carts.Get(ctx, id)
+	if err != nil {
+		return Cart{}, err
+	}
+	if !ok {
+		return Cart{}, ErrNotFound
+	}
+	c.reopen(s.now())
+	if c.Status == StatusOpen {
+		if err := s.quote(ctx, &c); err != nil {
+			c.Quote, c.QuoteError = nil, err.Error()
+		}
+	}
+	return c, nil
+}
+
+// SetLine deja productId con quantity unidades; quantity 0 quita la linea
+func (s *service) SetLine(ctx context.Context, id, productId, quantity int) (Cart, error) {
+	line := Line{ProductId: productId, Quantity: quantity}
+	if quantity != 0 {
+		if err := s.checkLine(ctx, line); err != nil {
+			return Cart{}, err
+		}
+	}
+	return s.modify(ctx, id, func(c *Cart) error {
+		c.Lines = withLine(c.Lines, productId, quantity)
+		return nil
+	})
+}
+
+// SetPromo cambia el codigo de promocion; "" lo quita
+func (s *service) SetPromo(ctx context.Context, id int, promo string) (Cart, error) {
+	return s.modify(ctx, id, func(c *Cart) error {
+		c.Promo = promo
+		return nil
+	})
+}
+
+// Lock valida todas las lineas, cotiza el carrito y congela ese precio por
+// el ttl del servicio
+func (s *service) Lock(ctx context.Context, id int) (Cart, error) {
+	return s.modify(ctx, id, func(c *Cart) error {
+		if len(c.Lines) == 0 {
+			return ErrEmpty
+		}
+		for _, l := range c.Lines {
+			if err := s.checkLine(ctx, l); err != nil {
+				return err
+			}
+		}
+		until := s.now().Add(s.ttl)
+		c.Status, c.LockedUntil = StatusLocked, &until
+		return nil
+	})
+}
+
+// Checkout convierte un carrito bloqueado en una orden. Las lineas se
+// vuelven a validar porque el stock pudo cambiar desde el bloqueo. Si el
+// carrito no se puede guardar queda bloqueado y el reintento recibe la
+// misma orden de OrderPlacer.
+func (s *service) Checkout(ctx context.Context, id int) (Cart, error) {
+	var c Cart
+	err := s.carts.Update(ctx, func(tx *store.CollectionTx[Cart]) error {
+		var ok bool
+		if c, ok = tx.Get(id); !ok {
+			return ErrNotFound
+		}
+		now := s.now()
+		switch {
+		case c.Status == StatusOrdered:
+			return ErrOrdered
+		case c.Status != StatusLocked:
+			return ErrNotLocked
+		case !c.lockActive(now):
+			return ErrLockExpired
+		}
+		for _, l := range c.Lines {
+			if err := s.checkLine(ctx, l); err != nil {
+				return err
+			}
+		}
+		orderId, err := s.placer.PlaceOrder(ctx, c)
+		if err != nil {
+			return err
+		}
+		c.Status, c.OrderId, c.LockedUntil = StatusOrdered, orderId, nil
+		c.Version++
+		c.UpdatedAt = now
+		tx.Put(c.Id, c)
+		return nil
+	})
+	if err != nil {
+		return Cart{}, err
+	}
+	return c, nil
+}
+
+// Delete elimina un carrito que todavia no se convirtio en orden
+func (s *service) Delete(ctx context.Context, id int) error {
+	return s.carts.Update(ctx, func(tx *store.CollectionTx[Cart]) error {
+		c, ok := tx.Get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		if c.Status == StatusOrdered {
+			return ErrOrdered
+		}
+		orderId, err := s.placer.OrderOf(ctx, id)
+		if err != nil {
+			return err
+		}
+		if orderId != 0 {
+			return fmt.Errorf("%w: order %d was placed for it", ErrOrdered, orderId)
+		}
+		tx.Delete(id)
+		return nil
+	})
+}
+
+// modify aplica change a un carrito abierto (o con el bloqueo vencido), lo
+// recotiza y lo guarda; si algo falla el carrito queda como estaba
+func (s *service) modify(ctx context.Context, id int, change func(c *Cart) error) (Cart, error) {
+	var c Cart
+	err := s.carts.Update(ctx, func(tx *store.CollectionTx[Cart]) error {
+		var ok bool
+		if c, ok = tx.Get(id); !ok {
+			return ErrNotFound
+		}
+		now := s.now()
+		c.reopen(now)
+		switch c.Status {
+		case StatusOrdered:
+			return ErrOrdered
+		case StatusLocked:
+			return ErrLocked
+		}
+		if err := change(&c); err != nil {
+			return err
+		}
+		if err := s.quote(ctx, &c); err != nil {
+			return err
+		}
+		c.Version++
+		c.UpdatedAt = now
+		tx.Put(c.Id, c)
+		return nil
+	})
+	if err != nil {
+		return Cart{}, err
+	}
+	return c, nil
+}
+
+// quote recalcula c.Quote; un carrito vacio no tiene cotizacion
+func (s *service) quote(ctx context.Context, c *Cart) error {
+	c.Quote, c.QuoteError = nil, ""
+	if len(c.Lines) == 0 {
+		return nil
+	}
+	q, err := s.prices.Quote(ctx, c.items(), c.Promo)
+	if err != nil {
+		return err
+	}
+	c.Quote = &q
+	return nil
+}
+
+// checkLine valida que l se pueda vender hoy: cantidad positiva, producto
+// publicado, no vencido y con unidades disponibles (sin contar las
+// reservadas por otras ordenes)
+func (s *service) checkLine(ctx context.Context, l Line) error {
+	if l.Quantity < 1 {
+		return fmt.Errorf("%w: quantity of product %d must be positive", ErrInvalidLine, l.ProductId)
+	}
+	p, err := s.catalog.GetByID(ctx, l.ProductId)
+	if err != nil {
+		return fmt.Errorf("%w: product %d not found", ErrInvalidLine, l.ProductId)
+	}
+	if !p.IsPublished {
+		return fmt.Errorf("%w: product %d is not published", ErrInvalidLine, l.ProductId)
+	}
+	if today := domain.DateOf(s.now()); !p.Expiration.IsZero() && p.Expiration.Before(today) {
+		return fmt.Errorf("%w: product %d expired on %s", ErrInvalidLine, l.ProductId, p.Expiration)
+	}
+	if p.Available() < l.Quantity {
+		return fmt.Errorf("%w: product %d has %d units available", ErrInsufficientStock, l.ProductId, p.Available())
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package cart
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+// reservingPlacer reserva las unidades de cada carrito en el repositorio,
+// como lo hace el servicio de ordenes
+type reservingPlacer struct {
+	repo   product.Repository
+	orders int
+	placed map[int]int
+}
+
+func (r *reservingPlacer) PlaceOrder(ctx context.Context, c Cart) (int, error) {
+	if id := r.placed[c.Id]; id != 0 {
+		return id, nil
+	}
+	for _, l := range c.Lines {
+		p, err := r.repo.GetByID(ctx, l.ProductId)
+		if err != nil {
+			return 0, err
+		}
+		p.Reserved += l.Quantity
+		if _, err := r.repo.Update(ctx, p.Id, p); err != nil {
+			return 0, err
+		}
+	}
+	r.orders++
+	if r.placed == nil {
+		r.placed = map[int]int{}
+	}
+	r.placed[c.Id] = r.orders
+	return r.orders, nil
+}
+
+func (r *reservingPlacer) OrderOf(ctx context.Context, cartId int) (int, error) {
+	return r.placed[cartId], nil
+}
+
+type testEnv struct {
+	service  *service
+	repo     product.Repository
+	products product.Service
+	clock    time.Time
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "a", CodeValue: "A", Quantity: 10, IsPublished: true, Price: money.MustNew(1000, "ARS")},
+		{Id: 2, Name: "b", CodeValue: "B", Quantity: 1, IsPublished: true, Price: money.MustNew(2000, "ARS")},
+		{Id: 3, Name: "hidden", CodeValue: "C", Quantity: 10, IsPublished: false, Price: money.MustNew(1000, "ARS")},
+		{Id: 4, Name: "expired", CodeValue: "D", Quantity: 10, IsPublished: true, Price: money.MustNew(1000, "ARS"), Expiration: domain.NewDate(2024, time.March, 9)},
+	})
+	repo := product.NewRepository(storage)
+	products := product.NewService(repo)
+	prices := pricing.NewService(products, pricing.DefaultRules())
+	env := &testEnv{repo: repo, products: products, clock: now}
+	env.service = NewService(store.NewCollection[Cart](""), products, prices, &reservingPlacer{repo: repo}, time.Minute).(*service)
+	env.service.now = func() time.Time { return env.clock }
+	return env
+}
+
+func TestService_CartLifecycle(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	s := env.service
+
+	c, err := s.Create(ctx, nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, StatusOpen, c.Status)
+	assert.Nil(t, c.Quote)
+
+	c, err = s.SetLine(ctx, c.Id, 1, 3)
+	assert.Nil(t, err)
+	c, err = s.SetLine(ctx, c.Id, 2, 1)
+	assert.Nil(t, err)
+	// 3 x 10 + 20 = 50, con 21% de recargo
+	assert.Equal(t, money.MustNew(6050, "ARS"), c.Quote.Total)
+	assert.Equal(t, 3, c.Version)
+
+	c, err = s.SetLine(ctx, c.Id, 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []Line{{1, 2}, {2, 1}}, c.Lines)
+
+	_, err = s.SetLine(ctx, c.Id, 2, 2)
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	for _, productId := range []int{3, 4, 99} {
+		_, err = s.SetLine(ctx, c.Id, productId, 1)
+		assert.ErrorIs(t, err, ErrInvalidLine, productId)
+	}
+	_, err = s.SetLine(ctx, c.Id, 1, -1)
+	assert.ErrorIs(t, err, ErrInvalidLine)
+	_, err = s.SetPromo(ctx, c.Id, "NOPE")
+	assert.ErrorIs(t, err, pricing.ErrInvalidPromo)
+
+	_, err = s.Checkout(ctx, c.Id)
+	assert.ErrorIs(t, err, ErrNotLocked)
+
+	c, err = s.Lock(ctx, c.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusLocked, c.Status)
+	assert.Equal(t, now.Add(time.Minute), *c.LockedUntil)
+	_, err = s.SetLine(ctx, c.Id, 1, 1)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	c, err = s.Checkout(ctx, c.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusOrdered, c.Status)
+	assert.Equal(t, 1, c.OrderId)
+	p, _ := env.products.GetByID(ctx, 1)
+	assert.Equal(t, 2, p.Reserved)
+	p, _ = env.products.GetByID(ctx, 2)
+	assert.Equal(t, 0, p.Available())
+
+	_, err = s.Checkout(ctx, c.Id)
+	assert.ErrorIs(t, err, ErrOrdered)
+	assert.ErrorIs(t, s.Delete(ctx, c.Id), ErrOrdered)
+}
+
+func TestService_LockExpires(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	s := env.service
+
+	c, err := s.Create(ctx, []Line{{1, 1}}, "")
+	assert.Nil(t, err)
+	_, err = s.Lock(ctx, 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+	c, err = s.Lock(ctx, c.Id)
+	assert.Nil(t, err)
+	locked := c.Quote.Total
+
+	// el precio bloqueado no cambia aunque cambie el producto
+	_, err = env.products.Update(ctx, 1, domain.Product{Price: money.MustNew(5000, "ARS")})
+	assert.Nil(t, err)
+	c, err = s.Get(ctx, c.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, locked, c.Quote.Total)
+
+	env.clock = now.Add(2 * time.Minute)
+	_, err = s.Checkout(ctx, c.Id)
+	assert.ErrorIs(t, err, ErrLockExpired)
+	c, err = s.Get(ctx, c.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusOpen, c.Status)
+	assert.Equal(t, money.MustNew(6050, "ARS"), c.Quote.Total)
+
+	// si el producto se despublica el carrito se lee igual, sin cotizacion
+	p, err := env.repo.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	p.IsPublished = false
+	_, err = env.repo.Update(ctx, 1, p)
+	assert.Nil(t, err)
+	c, err = s.Get(ctx, c.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Quote)
+	assert.Contains(t, c.QuoteError, "not published")
+	_, err = s.Lock(ctx, c.Id)
+	assert.ErrorIs(t, err, ErrInvalidLine)
+}
+
+func TestService_CheckoutRevalidatesStock(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	s := env.service
+
+	// dos carritos bloquean la unica unidad del producto 2
+	first, err := s.Create(ctx, []Line{{1, 1}, {2, 1}}, "")
+	assert.Nil(t, err)
+	second, err := s.Create(ctx, []Line{{2, 1}}, "")
+	assert.Nil(t, err)
+	_, err = s.Lock(ctx, first.Id)
+	assert.Nil(t, err)
+	_, err = s.Lock(ctx, second.Id)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, id := range []int{first.Id, second.Id} {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			_, errs[i] = s.Checkout(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, ErrInsufficientStock)
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+	p, _ := env.products.GetByID(ctx, 2)
+	assert.Equal(t, 1, p.Reserved)
+}
+
+func TestService_DeleteWithPendingOrder(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	s := env.service
+
+	c, err := s.Create(ctx, []Line{{1, 2}}, "")
+	assert.Nil(t, err)
+	c, err = s.Lock(ctx, c.Id)
+	assert.Nil(t, err)
+	// un checkout que creo la orden pero no llego a guardar el carrito
+	orderId, err := s.placer.PlaceOrder(ctx, c)
+	assert.Nil(t, err)
+
+	assert.ErrorIs(t, s.Delete(ctx, c.Id), ErrOrdered)
+	c, err = s.Checkout(ctx, c.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, orderId, c.OrderId)
+
+	other, err := s.Create(ctx, []Line{{1, 1}}, "")
+	assert.Nil(t, err)
+	_, err = s.Lock(ctx, other.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Delete(ctx, other.Id))
+}
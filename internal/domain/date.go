@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// isoLayout es el formato en que se guardan las fechas en las bases sql,
+// porque se ordena igual como texto que como fecha
+const isoLayout = "2006-01-02"
+
+// dateLayouts son los formatos que se aceptan al leer una fecha
+var dateLayouts = []string{"2/1/2006", isoLayout, time.RFC3339}
+
+// DateFormats son los formatos de salida que se pueden configurar, por nombre
+var DateFormats = map[string]string{
+	"dd/mm/yyyy": "02/01/2006",
+	"yyyy-mm-dd": isoLayout,
+	"rfc3339":    time.RFC3339,
+}
+
+// ErrInvalidDate indica una fecha con formato invalido o que no existe
+var ErrInvalidDate = Invalid("invalid_date", "invalid date")
+
+// dateFormat es el layout con el que se serializan las fechas
+var dateFormat atomic.Value
+
+func init() {
+	dateFormat.Store(DateFormats["dd/mm/yyyy"])
+}
+
+// SetDateFormat elige el formato de salida de las fechas por su nombre en DateFormats
+func SetDateFormat(name string) error {
+	layout, ok := DateFormats[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown date format %q", name)
+	}
+	dateFormat.Store(layout)
+	return nil
+}
+
+// Date es una fecha de calendario, sin hora ni zona horaria
+type Date struct {
+	t time.Time
+}
+
+// NewDate crea la fecha year/month/day; los valores fuera de rango se
+// normalizan como en time.Date
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf devuelve la fecha de t en su propia zona horaria
+func DateOf(t time.Time) Date {
+	return NewDate(t.Date())
+}
+
+// Today devuelve la fecha de hoy en la zona horaria local
+func Today() Date {
+	return DateOf(time.Now())
+}
+
+// ParseDate interpreta una fecha dd/mm/yyyy, yyyy-mm-dd (ISO-8601) o RFC3339
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return DateOf(t), nil
+		}
+	}
+	return Date{}, fmt.Errorf("%w %q, must be an existing date as dd/mm/yyyy, yyyy-mm-dd or RFC3339", ErrInvalidDate, s)
+}
+
+// Time devuelve la fecha como la medianoche UTC de ese dia
+func (d Date) Time() time.Time {
+	return d.t
+}
+
+// IsZero indica si la fecha no fue asignada
+func (d Date) IsZero() bool {
+	return d.t.IsZero()
+}
+
+// Compare devuelve -1, 0 o 1 segun d sea anterior, igual o posterior a o
+func (d Date) Compare(o Date) int {
+	return d.t.Compare(o.t)
+}
+
+// Before indica si d es anterior a o
+func (d Date) Before(o Date) bool {
+	return d.t.Before(o.t)
+}
+
+// After indica si d es posterior a o
+func (d Date) After(o Date) bool {
+	return d.t.After(o.t)
+}
+
+// AddDays devuelve la fecha n dias despues de d
+func (d Date) AddDays(n int) Date {
+	return Date{d.t.AddDate(0, 0, n)}
+}
+
+// String devuelve la fecha en el formato configurado, o "" si es cero
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.t.Format(dateFormat.Load().(string))
+}
+
+// MarshalJSON serializa la fecha en el formato configurado
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON acepta cualquiera de los formatos de ParseDate; "" y null
+// dejan la fecha en cero
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: must be a string", ErrInvalidDate)
+	}
+	if s == nil || *s == "" {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(*s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value guarda la fecha como texto yyyy-mm-dd
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return "", nil
+	}
+	return d.t.Format(isoLayout), nil
+}
+
+// Scan lee una fecha guardada como texto en cualquiera de los formatos de
+// ParseDate, para leer tambien las filas previas a la migracion
+func (d *Date) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case time.Time:
+		*d = DateOf(v)
+		return nil
+	case nil:
+		*d = Date{}
+		return nil
+	default:
+		return fmt.Errorf("can't scan %T into a date", src)
+	}
+	if s == "" {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDate(t *testing.T) {
+	want := NewDate(2022, time.March, 5)
+	for _, s := range []string{"05/03/2022", "5/3/2022", "2022-03-05", "2022-03-05T23:30:00-03:00", " 05/03/2022 "} {
+		d, err := ParseDate(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, want, d, s)
+	}
+	for _, s := range []string{"31/02/2022", "99/99/2020", "29/02/2023", "12/2022", "2022/03/05", "mañana", ""} {
+		_, err := ParseDate(s)
+		assert.ErrorIs(t, err, ErrInvalidDate, s)
+	}
+	leap, err := ParseDate("29/02/2024")
+	assert.Nil(t, err)
+	assert.Equal(t, NewDate(2024, time.February, 29), leap)
+}
+
+func TestDate_JSON(t *testing.T) {
+	defer SetDateFormat("dd/mm/yyyy")
+
+	var p Product
+	assert.Nil(t, json.Unmarshal([]byte(`{"expiration": "2021-12-15"}`), &p))
+	assert.Equal(t, NewDate(2021, time.December, 15), p.Expiration)
+
+	bytes, _ := json.Marshal(p.Expiration)
+	assert.Equal(t, `"15/12/2021"`, string(bytes))
+	assert.Nil(t, SetDateFormat("yyyy-mm-dd"))
+	bytes, _ = json.Marshal(p.Expiration)
+	assert.Equal(t, `"2021-12-15"`, string(bytes))
+	assert.Nil(t, SetDateFormat("RFC3339"))
+	bytes, _ = json.Marshal(p.Expiration)
+	assert.Equal(t, `"2021-12-15T00:00:00Z"`, string(bytes))
+	assert.NotNil(t, SetDateFormat("mm/dd/yyyy"))
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"expiration": "31/02/2022"}`), &p), ErrInvalidDate)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"expiration": 20221231}`), &p), ErrInvalidDate)
+	assert.Nil(t, json.Unmarshal([]byte(`{"expiration": null}`), &p))
+	assert.True(t, p.Expiration.IsZero())
+}
+
+func TestDate_Scan(t *testing.T) {
+	var d Date
+	assert.Nil(t, d.Scan("15/12/2021"))
+	value, err := d.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "2021-12-15", value)
+	assert.Nil(t, d.Scan([]byte("2021-12-16")))
+	assert.Equal(t, NewDate(2021, time.December, 16), d)
+	assert.NotNil(t, d.Scan(42))
+}
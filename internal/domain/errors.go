@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind clasifica un Error segun lo que le pasa a quien llama; la capa http
+// lo traduce al status de la respuesta
+type Kind int
+
+const (
+	// KindInternal es una falla que no depende de lo que se pidio
+	KindInternal Kind = iota
+	// KindInvalid indica datos que no cumplen las reglas del dominio
+	KindInvalid
+	// KindNotFound indica que lo pedido no existe
+	KindNotFound
+	// KindConflict indica un cambio que choca con el estado actual
+	KindConflict
+	// KindPrecondition indica que la version que conocia quien pide el
+	// cambio ya no es la actual
+	KindPrecondition
+)
+
+// FieldError es el problema de un campo puntual en un error de validacion
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error es un error de dominio con un codigo estable que pueden leer los
+// clientes. Dos Error con el mismo Code son el mismo error para errors.Is,
+// asi un error de validacion armado con sus campos sigue siendo igual al
+// sentinel del paquete que lo produce.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Message
+	}
+	return e.Message + ": " + strings.Join(messages, ", ")
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithFields devuelve una copia de e con el detalle de los campos invalidos
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	c := *e
+	c.Fields = append([]FieldError(nil), fields...)
+	return &c
+}
+
+// NewError crea un Error de la clase kind
+func NewError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// NotFound crea un Error de algo que no existe
+func NotFound(code, message string) *Error {
+	return NewError(KindNotFound, code, message)
+}
+
+// Conflict crea un Error de un cambio que choca con el estado actual
+func Conflict(code, message string) *Error {
+	return NewError(KindConflict, code, message)
+}
+
+// Invalid crea un Error de validacion
+func Invalid(code, message string) *Error {
+	return NewError(KindInvalid, code, message)
+}
+
+// AsError busca un Error en la cadena de err
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	base := Invalid("invalid_product", "invalid product")
+	err := base.WithFields(
+		FieldError{Field: "name", Code: "required", Message: "name can't be empty"},
+		FieldError{Field: "price", Code: "not_positive", Message: "price must be greater than 0"},
+	)
+	assert.Equal(t, "invalid product: name can't be empty, price must be greater than 0", err.Error())
+	assert.Empty(t, base.Fields)
+
+	// el mismo codigo es el mismo error aunque lo declare otro paquete
+	wrapped := fmt.Errorf("importing row 3: %w", err)
+	assert.ErrorIs(t, wrapped, base)
+	assert.ErrorIs(t, wrapped, Invalid("invalid_product", "another message"))
+	assert.False(t, errors.Is(wrapped, NotFound("product_not_found", "product not found")))
+
+	e, ok := AsError(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, KindInvalid, e.Kind)
+	assert.Len(t, e.Fields, 2)
+	_, ok = AsError(errors.New("plain"))
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, fmt.Errorf("%w: 30/02/2022", ErrInvalidDate), Invalid("invalid_date", ""))
+}
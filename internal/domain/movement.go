@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// MovementKind es el motivo de un cambio de stock
+type MovementKind string
+
+const (
+	// MovementReceipt es mercaderia que ingresa
+	MovementReceipt MovementKind = "receipt"
+	// MovementSale son unidades vendidas
+	MovementSale MovementKind = "sale"
+	// MovementAdjustment corrige el stock, por ejemplo despues de un conteo
+	MovementAdjustment MovementKind = "adjustment"
+	// MovementWriteOff son unidades dadas de baja: rotas, vencidas, perdidas
+	MovementWriteOff MovementKind = "write_off"
+)
+
+// ValidMovementKind indica si k es uno de los tipos de movimiento conocidos
+func ValidMovementKind(k MovementKind) bool {
+	switch k {
+	case MovementReceipt, MovementSale, MovementAdjustment, MovementWriteOff:
+		return true
+	}
+	return false
+}
+
+// Movement es un asiento del libro de inventario
+type Movement struct {
+	Id        int          `json:"id"`
+	ProductId int          `json:"product_id"`
+	Kind      MovementKind `json:"kind"`
+	// Quantity es el cambio de stock, negativo si salen unidades
+	Quantity int `json:"quantity"`
+	// Balance es el stock del producto despues del movimiento
+	Balance int    `json:"balance"`
+	Reason  string `json:"reason,omitempty"`
+	Actor   string `json:"actor,omitempty"`
+	// OrderId es la orden que produjo el movimiento, si lo produjo una
+	OrderId   int       `json:"order_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
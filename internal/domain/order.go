@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+)
+
+// OrderStatus es el estado de una orden
+type OrderStatus string
+
+const (
+	// OrderPending tiene el stock reservado pero no descontado
+	OrderPending OrderStatus = "pending"
+	// OrderConfirmed desconto el stock de Quantity
+	OrderConfirmed OrderStatus = "confirmed"
+	// OrderCancelled libero las unidades que tenia reservadas
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// OrderLine es un producto de la orden con el precio al que se vendio
+type OrderLine struct {
+	ProductId int         `json:"product_id"`
+	Name      string      `json:"name"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price" swaggertype:"number"`
+	// Total es el precio final de la linea con descuentos, recargos e
+	// impuestos
+	Total money.Money `json:"total" swaggertype:"number"`
+}
+
+type Order struct {
+	Id     int         `json:"id"`
+	Status OrderStatus `json:"status"`
+	Lines  []OrderLine `json:"lines"`
+	Promo  string      `json:"promo,omitempty"`
+	Total  money.Money `json:"total" swaggertype:"number"`
+	// CartId es el carrito del que salio la orden, si salio de uno
+	CartId    int       `json:"cart_id,omitempty"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -1,11 +1,26 @@
 package domain
 
+import "github.com/fgiudicatti-meli/web-server/pkg/money"
+
 type Product struct {
-	Id          int     `json:"id"`
-	Name        string  `json:"name" binding:"required"`
-	Quantity    int     `json:"quantity" binding:"required"`
-	CodeValue   string  `json:"code_value" binding:"required"`
-	IsPublished bool    `json:"is_published"`
-	Expiration  string  `json:"expiration" binding:"required"`
-	Price       float64 `json:"price" binding:"required"`
+	Id          int         `json:"id"`
+	Name        string      `json:"name"`
+	Quantity    int         `json:"quantity"`
+	CodeValue   string      `json:"code_value"`
+	IsPublished bool        `json:"is_published"`
+	Expiration  Date        `json:"expiration" swaggertype:"string" example:"15/12/2021"`
+	Price       money.Money `json:"price" swaggertype:"number" example:"555.99"`
+	// Version la incrementa el store en cada actualizacion; se usa como ETag
+	Version int `json:"version"`
+	// LifecycleReason la completa el job de vencimientos con el motivo y la
+	// accion tomada; se borra al cambiar la fecha de expiracion
+	LifecycleReason string `json:"lifecycle_reason,omitempty"`
+	// Reserved son las unidades apartadas por ordenes pendientes; las
+	// disponibles para vender son Quantity - Reserved
+	Reserved int `json:"reserved"`
+}
+
+// Available devuelve las unidades que se pueden vender o reservar
+func (p Product) Available() int {
+	return p.Quantity - p.Reserved
 }
@@ -0,0 +1,290 @@
+// Package importer da de alta productos desde planillas csv o xlsx: ubica
+// cada campo segun un mapeo de columnas, valida cada fila con las mismas
+// reglas que POST /products, detecta codigos repetidos contra el store y
+// dentro del archivo y agrega las filas validas en una sola escritura.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+// ErrInvalidFile indica un archivo que no se puede importar: vacio, sin
+// las columnas obligatorias o con un mapeo invalido
+var ErrInvalidFile = domain.Invalid("invalid_import_file", "invalid import file")
+
+// RowStatus es el resultado de una fila
+type RowStatus string
+
+const (
+	// RowValid paso las validaciones; en un dry run no se agrega
+	RowValid RowStatus = "valid"
+	// RowCreated se agrego al store
+	RowCreated RowStatus = "created"
+	// RowInvalid tiene campos que no pasan las validaciones
+	RowInvalid RowStatus = "invalid"
+	// RowConflict tiene un code_value que ya existe en el store o en una
+	// fila anterior del archivo
+	RowConflict RowStatus = "conflict"
+	// RowAborted era valida pero no se agrego porque la importacion es
+	// todo-o-nada y otra fila fallo
+	RowAborted RowStatus = "aborted"
+	// RowFailed era valida pero el store no la pudo agregar
+	RowFailed RowStatus = "failed"
+)
+
+// Options configura una importacion
+type Options struct {
+	Mapping Mapping
+	// DryRun valida todas las filas sin agregar ninguna
+	DryRun bool
+	// Atomic agrega todas las filas o ninguna
+	Atomic bool
+}
+
+// FieldError es un problema de una fila; Field y Column estan vacios si no
+// es de un campo en particular
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// RowResult es el resultado de una fila. Row es su numero en la planilla,
+// contando la de encabezados como 1.
+type RowResult struct {
+	Row       int          `json:"row"`
+	Status    RowStatus    `json:"status"`
+	CodeValue string       `json:"code_value,omitempty"`
+	Id        int          `json:"id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Report es el reporte fila por fila de una importacion; las filas vacias
+// no se cuentan
+type Report struct {
+	DryRun bool `json:"dry_run"`
+	Atomic bool `json:"atomic"`
+	Rows   int  `json:"rows"`
+	// Valid son las filas que pasaron las validaciones, se hayan agregado o
+	// no
+	Valid     int         `json:"valid"`
+	Invalid   int         `json:"invalid"`
+	Conflicts int         `json:"conflicts"`
+	Created   int         `json:"created"`
+	Results   []RowResult `json:"results"`
+}
+
+type Service interface {
+	Import(ctx context.Context, rows [][]string, opts Options) (Report, error)
+}
+
+type service struct {
+	products product.Service
+}
+
+// NewService crea un servicio de importacion que agrega los productos con
+// products
+func NewService(products product.Service) Service {
+	return &service{products: products}
+}
+
+// Import valida las filas de una planilla (la primera son los encabezados)
+// y, salvo en un dry run, agrega las validas con product.Service.CreateMany
+// en una sola escritura del store
+func (s *service) Import(ctx context.Context, rows [][]string, opts Options) (Report, error) {
+	if len(rows) == 0 {
+		return Report{}, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	l, err := opts.Mapping.layout(rows[0])
+	if err != nil {
+		return Report{}, err
+	}
+	stored, err := s.products.GetAll(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	existing := make(map[string]bool, len(stored))
+	for _, p := range stored {
+		existing[p.CodeValue] = true
+	}
+
+	report := Report{DryRun: opts.DryRun, Atomic: opts.Atomic, Results: []RowResult{}}
+	seen := map[string]int{}
+	var batch []domain.Product
+	var index []int
+	for i, row := range rows[1:] {
+		if blank(row) {
+			continue
+		}
+		p, errs := l.product(row)
+		r := RowResult{Row: i + 2, Status: RowValid, CodeValue: p.CodeValue, Errors: errs}
+		switch {
+		case len(errs) > 0:
+			r.Status = RowInvalid
+		case existing[p.CodeValue]:
+			r.Status = RowConflict
+			r.Errors = []FieldError{{Field: "code_value", Message: "code_value already exists"}}
+		case seen[p.CodeValue] > 0:
+			r.Status = RowConflict
+			r.Errors = []FieldError{{Field: "code_value", Message: fmt.Sprintf("code_value repeats row %d", seen[p.CodeValue])}}
+		default:
+			seen[p.CodeValue] = r.Row
+			batch = append(batch, p)
+			index = append(index, len(report.Results))
+		}
+		report.Results = append(report.Results, r)
+	}
+	if len(report.Results) == 0 {
+		return Report{}, fmt.Errorf("%w: the file has no rows", ErrInvalidFile)
+	}
+
+	switch {
+	case opts.DryRun || len(batch) == 0:
+	case opts.Atomic && len(batch) < len(report.Results):
+		for _, i := range index {
+			report.Results[i].Status = RowAborted
+		}
+	default:
+		results, err := s.products.CreateMany(ctx, batch, opts.Atomic)
+		if err != nil {
+			return Report{}, err
+		}
+		for j, res := range results {
+			r := &report.Results[index[j]]
+			switch {
+			case res.Err == nil:
+				r.Status, r.Id = RowCreated, res.Product.Id
+			case errors.Is(res.Err, product.ErrAborted):
+				r.Status = RowAborted
+			case errors.Is(res.Err, store.ErrDuplicate):
+				r.Status = RowConflict
+				r.Errors = []FieldError{{Field: "code_value", Message: res.Err.Error()}}
+			default:
+				r.Status = RowFailed
+				r.Errors = []FieldError{{Message: res.Err.Error()}}
+			}
+		}
+	}
+
+	report.Rows = len(report.Results)
+	for _, r := range report.Results {
+		switch r.Status {
+		case RowInvalid:
+			report.Invalid++
+		case RowConflict:
+			report.Conflicts++
+		case RowCreated:
+			report.Valid++
+			report.Created++
+		default:
+			report.Valid++
+		}
+	}
+	return report, nil
+}
+
+// product arma el producto de una fila. Si todos los campos se pueden leer
+// se valida como en POST /products.
+func (l layout) product(row []string) (domain.Product, []FieldError) {
+	var p domain.Product
+	var errs []FieldError
+	fail := func(field, column, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	p.Name, _ = l.cell(row, "name")
+	p.CodeValue, _ = l.cell(row, "code_value")
+	if value, column := l.cell(row, "quantity"); value != "" {
+		n, err := parseInt(value)
+		if err != nil {
+			fail("quantity", column, "quantity must be an integer, got %q", value)
+		}
+		p.Quantity = n
+	}
+	if value, column := l.cell(row, "is_published"); value != "" {
+		b, err := parseBool(value)
+		if err != nil {
+			fail("is_published", column, "is_published must be true or false, got %q", value)
+		}
+		p.IsPublished = b
+	}
+	if value, column := l.cell(row, "expiration"); value != "" {
+		d, err := domain.ParseDate(value)
+		if err != nil {
+			fail("expiration", column, "invalid expiration: %v", err)
+		}
+		p.Expiration = d
+	}
+	if value, column := l.cell(row, "price"); value != "" {
+		m, err := parsePrice(value)
+		if err != nil {
+			fail("price", column, "invalid price: %v", err)
+		}
+		p.Price = m
+	}
+	if len(errs) == 0 {
+		err := product.Validate(p)
+		if e, ok := domain.AsError(err); ok && len(e.Fields) > 0 {
+			for _, f := range e.Fields {
+				_, column := l.cell(row, f.Field)
+				fail(f.Field, column, "%s", f.Message)
+			}
+		} else if err != nil {
+			fail("", "", "%v", err)
+		}
+	}
+	return p, errs
+}
+
+// parseInt acepta enteros escritos como decimales ("10.0"), como los
+// guardan algunas planillas
+func parseInt(s string) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+		return 0, strconv.ErrSyntax
+	}
+	return int(f), nil
+}
+
+// parseBool acepta ademas de los valores de strconv.ParseBool si/no y yes/no
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "si", "sí", "yes", "y", "s":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// parsePrice lee un importe en la moneda por defecto; una coma sin punto se
+// toma como separador decimal ("555,99")
+func parsePrice(s string) (money.Money, error) {
+	if strings.Contains(s, ",") && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return money.Parse(s, money.DefaultCurrency(), money.HalfEven)
+}
+
+// blank dice si todas las celdas de la fila estan vacias
+func blank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
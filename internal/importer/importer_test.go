@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService() (Service, product.Service) {
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "Yerba", CodeValue: "YER", Quantity: 5, Price: money.MustNew(1000, "ARS"), Expiration: domain.NewDate(2099, 1, 1)},
+	})
+	products := product.NewService(product.NewRepository(storage))
+	return NewService(products), products
+}
+
+// sheet es una planilla con encabezados propios, mapeados con Mapping
+var sheet = [][]string{
+	{"Nombre", "Cantidad", "SKU", "Publicado", "Vence", "Precio", "Notas"},
+	{"Queso", "10", "QUE", "si", "31/12/2099", "555,99"},
+	{"Leche", "diez", "LEC", "", "2099-13-01", "gratis"},
+	{"", "", "", "", "", "", ""},
+	{"Yerba nueva", "3", "YER", "no", "2099-01-01", "10"},
+	{"Queso otra vez", "1", "QUE", "true", "2099-01-01", "10"},
+	{"Pan", "0", "PAN", "false", "2099-01-01", "10"},
+	{"Arroz", "2.0", "ARR", "", "2099-01-01", "20.5"},
+}
+
+var sheetMapping = Mapping{
+	"name": "nombre", "quantity": "Cantidad", "code_value": "SKU",
+	"is_published": "Publicado", "expiration": "Vence", "price": "Precio",
+}
+
+func TestParseMapping(t *testing.T) {
+	m, err := ParseMapping(" name=Nombre, code_value = SKU ,")
+	assert.Nil(t, err)
+	assert.Equal(t, Mapping{"name": "Nombre", "code_value": "SKU"}, m)
+
+	for _, s := range []string{"name", "name=", "id=Id"} {
+		_, err = ParseMapping(s)
+		assert.ErrorIs(t, err, ErrInvalidFile, s)
+	}
+}
+
+func TestImport_DryRun(t *testing.T) {
+	ctx := context.Background()
+	s, products := newTestService()
+
+	report, err := s.Import(ctx, sheet, Options{Mapping: sheetMapping, DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, report.Rows)
+	assert.Equal(t, 2, report.Valid)
+	assert.Equal(t, 2, report.Invalid)
+	assert.Equal(t, 2, report.Conflicts)
+	assert.Equal(t, 0, report.Created)
+
+	statuses := map[int]RowStatus{}
+	for _, r := range report.Results {
+		statuses[r.Row] = r.Status
+	}
+	assert.Equal(t, map[int]RowStatus{2: RowValid, 3: RowInvalid, 5: RowConflict, 6: RowConflict, 7: RowInvalid, 8: RowValid}, statuses)
+
+	// cada campo que no se puede leer tiene su error, con la columna
+	invalid := report.Results[1]
+	assert.Len(t, invalid.Errors, 3)
+	assert.Equal(t, FieldError{Field: "quantity", Column: "Cantidad", Message: `quantity must be an integer, got "diez"`}, invalid.Errors[0])
+	assert.Equal(t, "expiration", invalid.Errors[1].Field)
+	assert.Equal(t, "price", invalid.Errors[2].Field)
+	assert.Equal(t, "code_value already exists", report.Results[2].Errors[0].Message)
+	assert.Equal(t, "code_value repeats row 2", report.Results[3].Errors[0].Message)
+	assert.Equal(t, "quantity must be greater than 0", report.Results[4].Errors[0].Message)
+
+	all, _ := products.GetAll(ctx)
+	assert.Len(t, all, 1)
+}
+
+func TestImport_Commit(t *testing.T) {
+	ctx := context.Background()
+	s, products := newTestService()
+
+	// todo-o-nada con filas invalidas no agrega nada
+	report, err := s.Import(ctx, sheet, Options{Mapping: sheetMapping, Atomic: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, report.Created)
+	assert.Equal(t, RowAborted, report.Results[0].Status)
+	all, _ := products.GetAll(ctx)
+	assert.Len(t, all, 1)
+
+	report, err = s.Import(ctx, sheet, Options{Mapping: sheetMapping})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, RowCreated, report.Results[0].Status)
+	assert.NotZero(t, report.Results[0].Id)
+
+	p, err := products.GetByID(ctx, report.Results[0].Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "Queso", p.Name)
+	assert.True(t, p.IsPublished)
+	assert.Equal(t, domain.NewDate(2099, 12, 31), p.Expiration)
+	assert.Equal(t, money.MustNew(55599, "ARS"), p.Price)
+	p, err = products.GetByID(ctx, report.Results[5].Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, p.Quantity)
+
+	// lo ya importado ahora choca contra el store
+	report, err = s.Import(ctx, sheet[:2], Options{Mapping: sheetMapping})
+	assert.Nil(t, err)
+	assert.Equal(t, RowConflict, report.Results[0].Status)
+}
+
+func TestImport_InvalidFile(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestService()
+
+	_, err := s.Import(ctx, nil, Options{})
+	assert.ErrorIs(t, err, ErrInvalidFile)
+	_, err = s.Import(ctx, sheet, Options{})
+	assert.ErrorIs(t, err, ErrInvalidFile)
+	assert.Contains(t, err.Error(), `"code_value" (code_value)`)
+	_, err = s.Import(ctx, sheet[:1], Options{Mapping: sheetMapping})
+	assert.ErrorIs(t, err, ErrInvalidFile)
+
+	// sin mapeo se usan los nombres de los campos
+	report, err := s.Import(ctx, [][]string{
+		{"Name", "Quantity", "Code Value", "Expiration", "Price"},
+		{"Harina", "1", "HAR", "01/01/2099", "100"},
+	}, Options{DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Valid)
+}
+
+// unreadableStore no puede leer los productos guardados
+type unreadableStore struct {
+	store.Store
+}
+
+var errUnreadable = errors.New("products.json: permission denied")
+
+func (unreadableStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	return nil, errUnreadable
+}
+
+func TestService_ImportStoreError(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(product.NewService(product.NewRepository(unreadableStore{store.NewMemoryStore(nil)})))
+
+	// sin los productos guardados no se puede saber que filas chocan
+	_, err := s.Import(ctx, sheet, Options{Mapping: sheetMapping, DryRun: true})
+	assert.ErrorIs(t, err, errUnreadable)
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fields son los campos de domain.Product que se pueden importar, por su
+// nombre json; todos son obligatorios salvo is_published
+var fields = map[string]bool{
+	"name":         true,
+	"quantity":     true,
+	"code_value":   true,
+	"is_published": false,
+	"expiration":   true,
+	"price":        true,
+}
+
+// FormulaPrefixes son los caracteres con los que Excel y otras planillas
+// interpretan una celda como formula; la exportacion csv los escapa con un '
+const FormulaPrefixes = "=+-@\t\r"
+
+// Mapping asocia un campo de domain.Product (por su nombre json) con el
+// encabezado de la columna que lo trae. Los campos que no estan se buscan en
+// la columna con su mismo nombre.
+type Mapping map[string]string
+
+// ParseMapping lee un mapeo como "name=Nombre,code_value=SKU"
+func ParseMapping(s string) (Mapping, error) {
+	m := Mapping{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("%w: mapping %q must be field=column", ErrInvalidFile, pair)
+		}
+		m[field] = column
+	}
+	return m, m.validate()
+}
+
+// validate controla que el mapeo solo use campos importables
+func (m Mapping) validate() error {
+	for field := range m {
+		if _, ok := fields[field]; !ok {
+			return fmt.Errorf("%w: unknown field %q in mapping", ErrInvalidFile, field)
+		}
+	}
+	return nil
+}
+
+// layout es donde esta cada campo en las filas de una planilla
+type layout struct {
+	header []string
+	index  map[string]int
+}
+
+// layout ubica cada campo en header. Los encabezados se comparan sin
+// distinguir mayusculas y con espacios o guiones equivalentes a "_"; falta
+// una columna obligatoria es un error.
+func (m Mapping) layout(header []string) (layout, error) {
+	if err := m.validate(); err != nil {
+		return layout{}, err
+	}
+	l := layout{header: header, index: map[string]int{}}
+	var missing []string
+	for field, required := range fields {
+		column := field
+		if name, ok := m[field]; ok {
+			column = name
+		}
+		found := false
+		for i, h := range header {
+			if normalize(h) == normalize(column) {
+				l.index[field], found = i, true
+				break
+			}
+		}
+		if !found && required {
+			missing = append(missing, fmt.Sprintf("%q (%s)", column, field))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return layout{}, fmt.Errorf("%w: missing columns %s", ErrInvalidFile, strings.Join(missing, ", "))
+	}
+	return l, nil
+}
+
+// cell devuelve el valor del campo en row, sin el ' con que se escapan las
+// formulas, y el encabezado de su columna
+func (l layout) cell(row []string, field string) (string, string) {
+	i, ok := l.index[field]
+	if !ok {
+		return "", ""
+	}
+	if i >= len(row) {
+		return "", l.header[i]
+	}
+	value := strings.TrimSpace(row[i])
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(FormulaPrefixes, rune(value[1])) {
+		value = strings.TrimSpace(value[1:])
+	}
+	return value, l.header[i]
+}
+
+func normalize(header string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(header)))
+}
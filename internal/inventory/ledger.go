@@ -0,0 +1,86 @@
+// Package inventory lleva el libro de movimientos de stock de los productos
+package inventory
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+var (
+	// ErrNotFound indica un producto que no existe ni tiene movimientos
+	ErrNotFound = domain.NotFound("product_not_found", "product not found")
+	// ErrInvalidMovement indica un tipo o un signo de cantidad invalido
+	ErrInvalidMovement = domain.Invalid("invalid_movement", "invalid stock movement")
+	// ErrNotRecorded indica un cambio de stock que quedo sin su movimiento
+	ErrNotRecorded = domain.NewError(domain.KindInternal, "movement_not_recorded", "stock changed but the movement was not recorded")
+)
+
+type movementKey struct{}
+
+type actorKey struct{}
+
+// WithMovement devuelve un contexto en el que los cambios de stock se registran como m
+func WithMovement(ctx context.Context, m domain.Movement) context.Context {
+	return context.WithValue(ctx, movementKey{}, m)
+}
+
+// WithActor devuelve un contexto en el que los movimientos se registran a nombre de actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor devuelve el autor guardado con WithActor, o "" si no hay
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// note arma el movimiento a registrar: el de WithMovement o uno con kind y reason
+func note(ctx context.Context, kind domain.MovementKind, reason string) domain.Movement {
+	m, ok := ctx.Value(movementKey{}).(domain.Movement)
+	if !ok {
+		m = domain.Movement{Kind: kind, Reason: reason}
+	}
+	if m.Actor == "" {
+		m.Actor = Actor(ctx)
+	}
+	return m
+}
+
+// ledger aplica los cambios de stock con el libro de movimientos bloqueado
+type ledger struct {
+	products  product.Repository
+	movements *store.Journal[domain.Movement]
+	now       func() time.Time
+}
+
+// write ejecuta fn con el libro bloqueado; fn devuelve si ya escribio el producto
+func (l *ledger) write(ctx context.Context, fn func(tx *store.JournalTx[domain.Movement]) (bool, error)) error {
+	written := false
+	err := l.movements.Update(ctx, func(tx *store.JournalTx[domain.Movement]) error {
+		var err error
+		written, err = fn(tx)
+		return err
+	})
+	if err != nil && written {
+		log.Printf("inventory: stock changed but the movement was not recorded: %v", err)
+		return ErrNotRecorded
+	}
+	return err
+}
+
+// record agrega m como movimiento de p, que ya tiene el stock resultante
+func (l *ledger) record(tx *store.JournalTx[domain.Movement], p domain.Product, quantity int, m domain.Movement) domain.Movement {
+	m.Id = tx.NextID()
+	m.ProductId = p.Id
+	m.Quantity = quantity
+	m.Balance = p.Quantity
+	m.CreatedAt = l.now()
+	tx.Add(m)
+	return m
+}
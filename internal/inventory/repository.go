@@ -0,0 +1,124 @@
+package inventory
+
+import (
+	"context"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+// repository decora un product.Repository registrando los cambios de Quantity
+type repository struct {
+	product.Repository
+	*ledger
+}
+
+// Create registra el stock inicial como un ingreso
+func (r *repository) Create(ctx context.Context, p domain.Product) (domain.Product, error) {
+	var created domain.Product
+	err := r.write(ctx, func(tx *store.JournalTx[domain.Movement]) (bool, error) {
+		var err error
+		if created, err = r.products.Create(ctx, p); err != nil {
+			return false, err
+		}
+		if created.Quantity != 0 {
+			r.record(tx, created, created.Quantity, note(ctx, domain.MovementReceipt, "initial stock"))
+		}
+		return true, nil
+	})
+	if err != nil {
+		return domain.Product{}, err
+	}
+	return created, nil
+}
+
+// Update registra la diferencia de Quantity como un ajuste
+func (r *repository) Update(ctx context.Context, id int, p domain.Product) (domain.Product, error) {
+	var updated domain.Product
+	err := r.write(ctx, func(tx *store.JournalTx[domain.Movement]) (bool, error) {
+		old, err := r.products.GetByID(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		if updated, err = r.products.Update(ctx, id, p); err != nil {
+			return false, err
+		}
+		if quantity := updated.Quantity - old.Quantity; quantity != 0 {
+			r.record(tx, updated, quantity, note(ctx, domain.MovementAdjustment, "product update"))
+		}
+		return true, nil
+	})
+	if err != nil {
+		return domain.Product{}, err
+	}
+	return updated, nil
+}
+
+// Delete da de baja el stock que le quedaba al producto
+func (r *repository) Delete(ctx context.Context, id, version int) error {
+	return r.write(ctx, func(tx *store.JournalTx[domain.Movement]) (bool, error) {
+		old, err := r.products.GetByID(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		// con la misma version, old es el producto que se borra
+		if err := r.products.Delete(ctx, id, version); err != nil {
+			return false, err
+		}
+		if old.Quantity != 0 {
+			quantity := -old.Quantity
+			old.Quantity = 0
+			r.record(tx, old, quantity, note(ctx, domain.MovementWriteOff, "product deleted"))
+		}
+		return true, nil
+	})
+}
+
+// Apply registra un movimiento por cada cambio del lote que modifico Quantity
+func (r *repository) Apply(ctx context.Context, changes []store.Change, atomic bool) ([]store.ChangeResult, error) {
+	var results []store.ChangeResult
+	err := r.write(ctx, func(tx *store.JournalTx[domain.Movement]) (bool, error) {
+		quantities := map[int]int{}
+		for _, c := range changes {
+			if c.Op != store.OpUpdate {
+				continue
+			}
+			if p, err := r.products.GetByID(ctx, c.Product.Id); err == nil {
+				quantities[p.Id] = p.Quantity
+			}
+		}
+		var err error
+		if results, err = r.products.Apply(ctx, changes, atomic); err != nil {
+			return false, err
+		}
+		for i, res := range results {
+			if res.Err != nil {
+				continue
+			}
+			p := res.Product
+			switch changes[i].Op {
+			case store.OpCreate:
+				if p.Quantity != 0 {
+					r.record(tx, p, p.Quantity, note(ctx, domain.MovementReceipt, "initial stock"))
+				}
+			case store.OpUpdate:
+				quantity := p.Quantity - quantities[p.Id]
+				quantities[p.Id] = p.Quantity
+				if quantity != 0 {
+					r.record(tx, p, quantity, note(ctx, domain.MovementAdjustment, "product update"))
+				}
+			case store.OpDelete:
+				if quantity := -p.Quantity; quantity != 0 {
+					p.Quantity = 0
+					r.record(tx, p, quantity, note(ctx, domain.MovementWriteOff, "product deleted"))
+				}
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
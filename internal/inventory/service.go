@@ -0,0 +1,155 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+type Service interface {
+	// Products devuelve el repositorio de productos que registra los
+	// cambios de stock; todo el que modifique productos debe usar este
+	Products() product.Repository
+	Record(ctx context.Context, productId int, m domain.Movement) (domain.Movement, error)
+	Movements(ctx context.Context, productId int) ([]domain.Movement, error)
+	StockAt(ctx context.Context, productId int, date domain.Date) (int, error)
+	Reconcile(ctx context.Context) ([]domain.Movement, error)
+}
+
+type service struct {
+	*ledger
+}
+
+// NewService crea el libro de inventario sobre el repositorio sin decorar products
+func NewService(products product.Repository, movements *store.Journal[domain.Movement]) Service {
+	return &service{&ledger{products: products, movements: movements, now: time.Now}}
+}
+
+// Products devuelve products decorado para registrar los movimientos
+func (s *service) Products() product.Repository {
+	return &repository{Repository: s.products, ledger: s.ledger}
+}
+
+// Record aplica al producto el movimiento m y lo registra
+func (s *service) Record(ctx context.Context, productId int, m domain.Movement) (domain.Movement, error) {
+	if err := validate(m); err != nil {
+		return domain.Movement{}, err
+	}
+	if m.Actor == "" {
+		m.Actor = Actor(ctx)
+	}
+	m.OrderId = 0
+	err := s.write(ctx, func(tx *store.JournalTx[domain.Movement]) (bool, error) {
+		p, err := s.products.GetByID(ctx, productId)
+		if err != nil {
+			return false, ErrNotFound
+		}
+		p.Quantity += m.Quantity
+		if p.Quantity < p.Reserved {
+			return false, fmt.Errorf("%w: product %d has %d units reserved", product.ErrBelowReserved, p.Id, p.Reserved)
+		}
+		if p, err = s.products.Update(ctx, productId, p); err != nil {
+			return false, err
+		}
+		m = s.record(tx, p, m.Quantity, m)
+		return true, nil
+	})
+	if err != nil {
+		return domain.Movement{}, err
+	}
+	return m, nil
+}
+
+// validate revisa el tipo y el signo de un movimiento manual
+func validate(m domain.Movement) error {
+	switch {
+	case !domain.ValidMovementKind(m.Kind):
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidMovement, m.Kind)
+	case m.Quantity == 0:
+		return fmt.Errorf("%w: quantity can't be 0", ErrInvalidMovement)
+	case m.Kind == domain.MovementReceipt && m.Quantity < 0:
+		return fmt.Errorf("%w: a receipt must have a positive quantity", ErrInvalidMovement)
+	case (m.Kind == domain.MovementSale || m.Kind == domain.MovementWriteOff) && m.Quantity > 0:
+		return fmt.Errorf("%w: a %s must have a negative quantity", ErrInvalidMovement, m.Kind)
+	case m.Kind == domain.MovementAdjustment && m.Reason == "":
+		return fmt.Errorf("%w: an adjustment needs a reason", ErrInvalidMovement)
+	}
+	return nil
+}
+
+// Movements devuelve los movimientos del producto en orden cronologico
+func (s *service) Movements(ctx context.Context, productId int) ([]domain.Movement, error) {
+	var movements []domain.Movement
+	err := s.movements.View(ctx, func(tx *store.JournalTx[domain.Movement]) error {
+		movements = []domain.Movement{}
+		for _, m := range tx.All() {
+			if m.ProductId == productId {
+				movements = append(movements, m)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(movements) == 0 {
+		if _, err := s.products.GetByID(ctx, productId); err != nil {
+			return nil, ErrNotFound
+		}
+	}
+	return movements, nil
+}
+
+// StockAt calcula el stock del producto al final del dia date
+func (s *service) StockAt(ctx context.Context, productId int, date domain.Date) (int, error) {
+	movements, err := s.Movements(ctx, productId)
+	if err != nil {
+		return 0, err
+	}
+	stock := 0
+	for _, m := range movements {
+		if domain.DateOf(m.CreatedAt).After(date) {
+			break
+		}
+		stock += m.Quantity
+	}
+	return stock, nil
+}
+
+// Reconcile registra un ajuste por la diferencia entre el stock y los movimientos
+func (s *service) Reconcile(ctx context.Context) ([]domain.Movement, error) {
+	adjustments := []domain.Movement{}
+	err := s.write(ctx, func(tx *store.JournalTx[domain.Movement]) (bool, error) {
+		balances := map[int]int{}
+		tracked := map[int]bool{}
+		for _, m := range tx.All() {
+			balances[m.ProductId] += m.Quantity
+			tracked[m.ProductId] = true
+		}
+		products, err := s.products.GetAll(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, p := range products {
+			quantity := p.Quantity - balances[p.Id]
+			if quantity == 0 {
+				continue
+			}
+			reason := "reconciliation"
+			if !tracked[p.Id] {
+				reason = "opening balance"
+			}
+			m := domain.Movement{Kind: domain.MovementAdjustment, Reason: reason, Actor: Actor(ctx)}
+			adjustments = append(adjustments, s.record(tx, p, quantity, m))
+		}
+		return false, ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return adjustments, nil
+}
@@ -0,0 +1,135 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var day = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T) (*service, *time.Time) {
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "a", CodeValue: "A", Quantity: 10, IsPublished: true, Price: money.MustNew(1000, "ARS")},
+	})
+	s := NewService(product.NewRepository(storage), store.NewJournal[domain.Movement]("")).(*service)
+	clock := day
+	s.now = func() time.Time { return clock }
+	return s, &clock
+}
+
+func TestService_RecordsEveryChange(t *testing.T) {
+	ctx := WithActor(context.Background(), "ana")
+	s, clock := newTestService(t)
+	products := product.NewService(s.Products())
+
+	// el stock anterior al libro entra como saldo inicial
+	adjustments, err := s.Reconcile(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, adjustments, 1)
+	assert.Equal(t, "opening balance", adjustments[0].Reason)
+	adjustments, err = s.Reconcile(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, adjustments)
+
+	*clock = day.AddDate(0, 0, 1)
+	m, err := s.Record(ctx, 1, domain.Movement{Kind: domain.MovementReceipt, Quantity: 5, Reason: "supplier"})
+	assert.Nil(t, err)
+	assert.Equal(t, 15, m.Balance)
+	assert.Equal(t, "ana", m.Actor)
+
+	*clock = day.AddDate(0, 0, 2)
+	_, err = products.Update(ctx, 1, domain.Product{Quantity: 12})
+	assert.Nil(t, err)
+	// cambiar otro campo no deja movimiento
+	_, err = products.Update(ctx, 1, domain.Product{Name: "renamed"})
+	assert.Nil(t, err)
+
+	created, err := products.Create(ctx, domain.Product{Name: "b", CodeValue: "B", Quantity: 4, Price: money.MustNew(1000, "ARS")})
+	assert.Nil(t, err)
+	assert.Nil(t, products.Delete(ctx, created.Id, created.Version))
+
+	movements, err := s.Movements(ctx, 1)
+	assert.Nil(t, err)
+	kinds := []domain.MovementKind{}
+	quantities := []int{}
+	for _, m := range movements {
+		kinds = append(kinds, m.Kind)
+		quantities = append(quantities, m.Quantity)
+	}
+	assert.Equal(t, []domain.MovementKind{domain.MovementAdjustment, domain.MovementReceipt, domain.MovementAdjustment}, kinds)
+	assert.Equal(t, []int{10, 5, -3}, quantities)
+	assert.Equal(t, "product update", movements[2].Reason)
+
+	movements, err = s.Movements(ctx, created.Id)
+	assert.Nil(t, err)
+	assert.Len(t, movements, 2)
+	assert.Equal(t, domain.MovementWriteOff, movements[1].Kind)
+	assert.Equal(t, 0, movements[1].Balance)
+
+	for date, want := range map[domain.Date]int{
+		domain.NewDate(2024, time.March, 9):  0,
+		domain.NewDate(2024, time.March, 10): 10,
+		domain.NewDate(2024, time.March, 11): 15,
+		domain.NewDate(2024, time.March, 20): 12,
+	} {
+		stock, err := s.StockAt(ctx, 1, date)
+		assert.Nil(t, err)
+		assert.Equal(t, want, stock, date.String())
+	}
+	_, err = s.StockAt(ctx, 99, domain.Today())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_RecordValidates(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestService(t)
+	_, err := s.Reconcile(ctx)
+	assert.Nil(t, err)
+
+	for _, m := range []domain.Movement{
+		{Kind: "gift", Quantity: 1},
+		{Kind: domain.MovementReceipt, Quantity: 0},
+		{Kind: domain.MovementReceipt, Quantity: -1},
+		{Kind: domain.MovementSale, Quantity: 1},
+		{Kind: domain.MovementWriteOff, Quantity: 1},
+		{Kind: domain.MovementAdjustment, Quantity: 1},
+	} {
+		_, err := s.Record(ctx, 1, m)
+		assert.ErrorIs(t, err, ErrInvalidMovement, m)
+	}
+	_, err = s.Record(ctx, 99, domain.Movement{Kind: domain.MovementReceipt, Quantity: 1})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// no se puede bajar de las unidades reservadas
+	p, err := s.products.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	p.Reserved = 8
+	_, err = s.products.Update(ctx, 1, p)
+	assert.Nil(t, err)
+	_, err = s.Record(ctx, 1, domain.Movement{Kind: domain.MovementWriteOff, Quantity: -3, Reason: "broken"})
+	assert.ErrorIs(t, err, product.ErrBelowReserved)
+	_, err = s.Record(ctx, 1, domain.Movement{Kind: domain.MovementWriteOff, Quantity: -2, Reason: "broken"})
+	assert.Nil(t, err)
+
+	// un cambio hecho por fuera del libro se corrige al reconciliar
+	p, err = s.products.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	p.Quantity = 9
+	_, err = s.products.Update(ctx, 1, p)
+	assert.Nil(t, err)
+	adjustments, err := s.Reconcile(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, adjustments, 1)
+	assert.Equal(t, "reconciliation", adjustments[0].Reason)
+	assert.Equal(t, 1, adjustments[0].Quantity)
+	stock, err := s.StockAt(ctx, 1, domain.Today())
+	assert.Nil(t, err)
+	assert.Equal(t, 9, stock)
+}
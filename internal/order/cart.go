@@ -0,0 +1,53 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fgiudicatti-meli/web-server/internal/cart"
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+type cartPlacer struct {
+	s Service
+}
+
+// CartPlacer crea la orden pendiente del checkout de un carrito
+func CartPlacer(s Service) cart.OrderPlacer {
+	return cartPlacer{s}
+}
+
+// PlaceOrder crea la orden del carrito c, o devuelve la que no se cancelo
+func (p cartPlacer) PlaceOrder(ctx context.Context, c cart.Cart) (int, error) {
+	if c.Quote == nil {
+		return 0, cart.ErrEmpty
+	}
+	if id, err := p.OrderOf(ctx, c.Id); err != nil || id != 0 {
+		return id, err
+	}
+	o, err := p.s.PlaceQuote(ctx, *c.Quote, c.Id)
+	switch {
+	case errors.Is(err, ErrInsufficientStock):
+		return 0, fmt.Errorf("%w: %v", cart.ErrInsufficientStock, err)
+	case errors.Is(err, ErrUnavailable):
+		return 0, fmt.Errorf("%w: %v", cart.ErrInvalidLine, err)
+	case err != nil:
+		return 0, err
+	}
+	return o.Id, nil
+}
+
+// OrderOf devuelve la orden del carrito cartId que no se cancelo, o 0
+func (p cartPlacer) OrderOf(ctx context.Context, cartId int) (int, error) {
+	orders, err := p.s.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, o := range orders {
+		if o.CartId == cartId && o.Status != domain.OrderCancelled {
+			return o.Id, nil
+		}
+	}
+	return 0, nil
+}
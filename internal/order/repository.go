@@ -0,0 +1,87 @@
+package order
+
+import (
+	"context"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]domain.Order, error)
+	GetByID(ctx context.Context, id int) (domain.Order, error)
+	Create(ctx context.Context, o domain.Order) (domain.Order, error)
+	// Update aplica change a la orden id sin que otra actualizacion se
+	// intercale; si change devuelve error la orden no cambia
+	Update(ctx context.Context, id int, change func(o *domain.Order) error) (domain.Order, error)
+}
+
+type repository struct {
+	orders *store.Collection[domain.Order]
+}
+
+// NewRepository crea un repositorio de ordenes sobre una coleccion
+func NewRepository(orders *store.Collection[domain.Order]) Repository {
+	return &repository{orders}
+}
+
+// GetAll devuelve todas las ordenes ordenadas por id
+func (r *repository) GetAll(ctx context.Context) ([]domain.Order, error) {
+	var orders []domain.Order
+	err := r.orders.View(ctx, func(tx *store.CollectionTx[domain.Order]) error {
+		orders = []domain.Order{}
+		for _, id := range tx.All() {
+			o, _ := tx.Get(id)
+			orders = append(orders, o)
+		}
+		return nil
+	})
+	return orders, err
+}
+
+// GetByID busca una orden por su id
+func (r *repository) GetByID(ctx context.Context, id int) (domain.Order, error) {
+	o, ok, err := r.orders.Get(ctx, id)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	if !ok {
+		return domain.Order{}, ErrNotFound
+	}
+	return o, nil
+}
+
+// Create asigna id y version inicial y guarda la orden
+func (r *repository) Create(ctx context.Context, o domain.Order) (domain.Order, error) {
+	err := r.orders.Update(ctx, func(tx *store.CollectionTx[domain.Order]) error {
+		o.Id = tx.NextID()
+		o.Version = 1
+		tx.Put(o.Id, o)
+		return nil
+	})
+	if err != nil {
+		return domain.Order{}, err
+	}
+	return o, nil
+}
+
+// Update aplica change e incrementa la version
+func (r *repository) Update(ctx context.Context, id int, change func(o *domain.Order) error) (domain.Order, error) {
+	var o domain.Order
+	err := r.orders.Update(ctx, func(tx *store.CollectionTx[domain.Order]) error {
+		var ok bool
+		if o, ok = tx.Get(id); !ok {
+			return ErrNotFound
+		}
+		if err := change(&o); err != nil {
+			return err
+		}
+		o.Version++
+		tx.Put(id, o)
+		return nil
+	})
+	if err != nil {
+		return domain.Order{}, err
+	}
+	return o, nil
+}
@@ -0,0 +1,127 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/inventory"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+)
+
+var (
+	// ErrNotFound indica que no existe una orden con el id pedido
+	ErrNotFound = domain.NotFound("order_not_found", "order not found")
+	// ErrInvalidTransition indica un cambio de estado no permitido, como
+	// confirmar una orden cancelada
+	ErrInvalidTransition = domain.Conflict("invalid_transition", "invalid order status transition")
+	// ErrInsufficientStock indica que no hay unidades disponibles para
+	// reservar
+	ErrInsufficientStock = domain.Conflict("insufficient_stock", "insufficient stock")
+	// ErrUnavailable indica un producto inexistente, no publicado o vencido
+	ErrUnavailable = domain.Invalid("product_unavailable", "product unavailable")
+)
+
+type Service interface {
+	GetAll(ctx context.Context) ([]domain.Order, error)
+	GetByID(ctx context.Context, id int) (domain.Order, error)
+	Place(ctx context.Context, items []pricing.Item, promo string) (domain.Order, error)
+	PlaceQuote(ctx context.Context, q pricing.Quote, cartId int) (domain.Order, error)
+	Confirm(ctx context.Context, id int) (domain.Order, error)
+	Cancel(ctx context.Context, id int) (domain.Order, error)
+}
+
+type service struct {
+	r      Repository
+	stock  stock
+	prices pricing.Service
+	now    func() time.Time
+}
+
+// NewService crea un servicio de ordenes sobre el repositorio de productos
+func NewService(r Repository, products product.Repository, prices pricing.Service) Service {
+	s := &service{r: r, prices: prices, now: time.Now}
+	s.stock = stock{products: products, today: func() domain.Date { return domain.DateOf(s.now()) }}
+	return s
+}
+
+// GetAll devuelve todas las ordenes
+func (s *service) GetAll(ctx context.Context) ([]domain.Order, error) {
+	return s.r.GetAll(ctx)
+}
+
+// GetByID busca una orden por su id
+func (s *service) GetByID(ctx context.Context, id int) (domain.Order, error) {
+	return s.r.GetByID(ctx, id)
+}
+
+// Place cotiza items y crea una orden pendiente con el stock reservado
+func (s *service) Place(ctx context.Context, items []pricing.Item, promo string) (domain.Order, error) {
+	q, err := s.prices.Quote(ctx, items, promo)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	return s.PlaceQuote(ctx, q, 0)
+}
+
+// PlaceQuote crea una orden pendiente con los precios de q
+func (s *service) PlaceQuote(ctx context.Context, q pricing.Quote, cartId int) (domain.Order, error) {
+	if len(q.Lines) == 0 {
+		return domain.Order{}, fmt.Errorf("%w: the order has no lines", pricing.ErrInvalidItems)
+	}
+	now := s.now()
+	o := domain.Order{
+		Status:    domain.OrderPending,
+		Lines:     make([]domain.OrderLine, len(q.Lines)),
+		Promo:     q.Promo,
+		Total:     q.Total,
+		CartId:    cartId,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for i, l := range q.Lines {
+		o.Lines[i] = domain.OrderLine{ProductId: l.ProductId, Name: l.Name, Quantity: l.Quantity, UnitPrice: l.UnitPrice, Total: l.Total}
+	}
+	if err := s.stock.applyAll(ctx, o.Lines, s.stock.reserve, release); err != nil {
+		return domain.Order{}, err
+	}
+	created, err := s.r.Create(ctx, o)
+	if err != nil {
+		_ = s.stock.applyAll(context.Background(), o.Lines, release, unrelease)
+		return domain.Order{}, err
+	}
+	return created, nil
+}
+
+// Confirm descuenta del stock las unidades reservadas por una orden pendiente
+func (s *service) Confirm(ctx context.Context, id int) (domain.Order, error) {
+	sale := domain.Movement{Kind: domain.MovementSale, Reason: fmt.Sprintf("order %d", id), OrderId: id}
+	return s.transition(ctx, inventory.WithMovement(ctx, sale), id, domain.OrderConfirmed, commit, uncommit)
+}
+
+// Cancel libera las unidades reservadas por una orden pendiente
+func (s *service) Cancel(ctx context.Context, id int) (domain.Order, error) {
+	return s.transition(ctx, ctx, id, domain.OrderCancelled, release, unrelease)
+}
+
+// transition pasa una orden pendiente a status aplicando op al stock de sus lineas
+func (s *service) transition(ctx, stockCtx context.Context, id int, status domain.OrderStatus, op, undo stockOp) (domain.Order, error) {
+	var applied []domain.OrderLine
+	o, err := s.r.Update(ctx, id, func(o *domain.Order) error {
+		if o.Status != domain.OrderPending {
+			return fmt.Errorf("%w: order %d is %s", ErrInvalidTransition, o.Id, o.Status)
+		}
+		if err := s.stock.applyAll(stockCtx, o.Lines, op, undo); err != nil {
+			return err
+		}
+		applied = o.Lines
+		o.Status, o.UpdatedAt = status, s.now()
+		return nil
+	})
+	if err != nil && applied != nil {
+		s.stock.undoAll(ctx, applied, undo)
+	}
+	return o, err
+}
@@ -0,0 +1,231 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/cart"
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/pricing"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T) (*service, product.Service) {
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "a", CodeValue: "A", Quantity: 10, IsPublished: true, Price: money.MustNew(1000, "ARS")},
+		{Id: 2, Name: "b", CodeValue: "B", Quantity: 2, IsPublished: true, Price: money.MustNew(2000, "ARS")},
+		{Id: 3, Name: "hidden", CodeValue: "C", Quantity: 10, IsPublished: false, Price: money.MustNew(1000, "ARS")},
+	})
+	repo := product.NewRepository(storage)
+	products := product.NewService(repo)
+	prices := pricing.NewService(products, pricing.DefaultRules())
+	s := NewService(NewRepository(store.NewCollection[domain.Order]("")), repo, prices).(*service)
+	s.now = func() time.Time { return now }
+	return s, products
+}
+
+func stockOf(t *testing.T, products product.Service, id int) (quantity, reserved int) {
+	p, err := products.GetByID(context.Background(), id)
+	assert.Nil(t, err)
+	return p.Quantity, p.Reserved
+}
+
+func TestService_OrderLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s, products := newTestService(t)
+
+	o, err := s.Place(ctx, []pricing.Item{{ProductId: 1, Quantity: 3}, {ProductId: 2, Quantity: 1}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, domain.OrderPending, o.Status)
+	assert.Equal(t, 1, o.Version)
+	// 3 x 10 + 20 = 50, con 21% de recargo
+	assert.Equal(t, money.MustNew(6050, "ARS"), o.Total)
+	quantity, reserved := stockOf(t, products, 1)
+	assert.Equal(t, 10, quantity)
+	assert.Equal(t, 3, reserved)
+
+	// las unidades reservadas no se pueden vender ni quitar del stock
+	_, err = s.Place(ctx, []pricing.Item{{ProductId: 1, Quantity: 8}}, "")
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	_, err = products.Update(ctx, 1, domain.Product{Quantity: 2})
+	assert.ErrorIs(t, err, product.ErrBelowReserved)
+	reservedProduct, err := products.GetByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.ErrorIs(t, products.Delete(ctx, 1, reservedProduct.Version), product.ErrReserved)
+
+	o, err = s.Confirm(ctx, o.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.OrderConfirmed, o.Status)
+	assert.Equal(t, 2, o.Version)
+	quantity, reserved = stockOf(t, products, 1)
+	assert.Equal(t, 7, quantity)
+	assert.Equal(t, 0, reserved)
+	quantity, reserved = stockOf(t, products, 2)
+	assert.Equal(t, 1, quantity)
+	assert.Equal(t, 0, reserved)
+
+	_, err = s.Cancel(ctx, o.Id)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	_, err = s.Confirm(ctx, o.Id)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	_, err = s.Confirm(ctx, 99)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	o, err = s.Place(ctx, []pricing.Item{{ProductId: 1, Quantity: 2}}, "")
+	assert.Nil(t, err)
+	o, err = s.Cancel(ctx, o.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.OrderCancelled, o.Status)
+	quantity, reserved = stockOf(t, products, 1)
+	assert.Equal(t, 7, quantity)
+	assert.Equal(t, 0, reserved)
+
+	orders, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, orders, 2)
+}
+
+func TestService_PlaceIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	s, products := newTestService(t)
+
+	// la primera linea se reserva y se libera cuando falla la segunda
+	_, err := s.Place(ctx, []pricing.Item{{ProductId: 1, Quantity: 3}, {ProductId: 2, Quantity: 3}}, "")
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	_, reserved := stockOf(t, products, 1)
+	assert.Equal(t, 0, reserved)
+
+	// un producto que se despublica despues de cotizar tampoco reserva nada
+	q, err := s.prices.Quote(ctx, []pricing.Item{{ProductId: 1, Quantity: 1}, {ProductId: 2, Quantity: 1}}, "")
+	assert.Nil(t, err)
+	p, err := s.stock.products.GetByID(ctx, 2)
+	assert.Nil(t, err)
+	p.IsPublished = false
+	_, err = s.stock.products.Update(ctx, 2, p)
+	assert.Nil(t, err)
+	_, err = s.PlaceQuote(ctx, q, 0)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	_, reserved = stockOf(t, products, 1)
+	assert.Equal(t, 0, reserved)
+
+	_, err = s.Place(ctx, []pricing.Item{{ProductId: 3, Quantity: 1}}, "")
+	assert.ErrorIs(t, err, pricing.ErrNotPublished)
+	orders, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, orders)
+}
+
+func TestService_ConcurrentPlaceDoesNotOversell(t *testing.T) {
+	ctx := context.Background()
+	s, products := newTestService(t)
+
+	const buyers = 25
+	var wg sync.WaitGroup
+	errs := make([]error, buyers)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.Place(ctx, []pricing.Item{{ProductId: 1, Quantity: 1}}, "")
+		}(i)
+	}
+	wg.Wait()
+
+	placed := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			placed++
+		case !errors.Is(err, ErrInsufficientStock):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 10, placed)
+	quantity, reserved := stockOf(t, products, 1)
+	assert.Equal(t, 10, quantity)
+	assert.Equal(t, 10, reserved)
+	orders, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, orders, 10)
+}
+
+func TestCartPlacer_PlaceOrderOncePerCart(t *testing.T) {
+	ctx := context.Background()
+	s, products := newTestService(t)
+	q, err := s.prices.Quote(ctx, []pricing.Item{{ProductId: 1, Quantity: 2}}, "")
+	assert.Nil(t, err)
+	c := cart.Cart{Id: 7, Quote: &q}
+	placer := CartPlacer(s)
+
+	// un checkout que se reintenta porque no se pudo guardar el carrito
+	first, err := placer.PlaceOrder(ctx, c)
+	assert.Nil(t, err)
+	again, err := placer.PlaceOrder(ctx, c)
+	assert.Nil(t, err)
+	assert.Equal(t, first, again)
+	_, reserved := stockOf(t, products, 1)
+	assert.Equal(t, 2, reserved)
+
+	// con la orden cancelada el carrito puede generar otra
+	_, err = s.Cancel(ctx, first)
+	assert.Nil(t, err)
+	pending, err := placer.OrderOf(ctx, c.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pending)
+	again, err = placer.PlaceOrder(ctx, c)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, again)
+	_, reserved = stockOf(t, products, 1)
+	assert.Equal(t, 2, reserved)
+}
+
+// failingRepository aplica los cambios a las ordenes pero no los guarda,
+// como una falla al escribir la coleccion
+type failingRepository struct {
+	Repository
+}
+
+var errSave = errors.New("disk full")
+
+func (r failingRepository) Update(ctx context.Context, id int, change func(o *domain.Order) error) (domain.Order, error) {
+	return r.Repository.Update(ctx, id, func(o *domain.Order) error {
+		if err := change(o); err != nil {
+			return err
+		}
+		return errSave
+	})
+}
+
+func TestService_TransitionUndoesStockWhenSaveFails(t *testing.T) {
+	ctx := context.Background()
+	s, products := newTestService(t)
+	o, err := s.Place(ctx, []pricing.Item{{ProductId: 1, Quantity: 3}}, "")
+	assert.Nil(t, err)
+
+	orders := s.r
+	s.r = failingRepository{orders}
+	_, err = s.Confirm(ctx, o.Id)
+	assert.ErrorIs(t, err, errSave)
+	_, err = s.Cancel(ctx, o.Id)
+	assert.ErrorIs(t, err, errSave)
+	quantity, reserved := stockOf(t, products, 1)
+	assert.Equal(t, 10, quantity)
+	assert.Equal(t, 3, reserved)
+
+	// el reintento descuenta las unidades una sola vez
+	s.r = orders
+	o, err = s.Confirm(ctx, o.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.OrderConfirmed, o.Status)
+	quantity, reserved = stockOf(t, products, 1)
+	assert.Equal(t, 7, quantity)
+	assert.Equal(t, 0, reserved)
+}
@@ -0,0 +1,111 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/inventory"
+	"github.com/fgiudicatti-meli/web-server/internal/product"
+)
+
+// maxStockRetries acota los reintentos cuando otro request modifica el producto
+const maxStockRetries = 100
+
+// stock cambia Quantity y Reserved de los productos con la version como precondicion
+type stock struct {
+	products product.Repository
+	today    func() domain.Date
+}
+
+// stockOp cambia un producto para una linea; devuelve error si no se puede
+type stockOp func(p *domain.Product, quantity int) error
+
+// change aplica op al producto id hasta que la escritura no tenga conflicto
+func (s stock) change(ctx context.Context, id, quantity int, op stockOp) error {
+	for attempt := 0; attempt < maxStockRetries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p, err := s.products.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("%w: product %d not found", ErrUnavailable, id)
+		}
+		if err := op(&p, quantity); err != nil {
+			return err
+		}
+		_, err = s.products.Update(ctx, id, p)
+		if !errors.Is(err, product.ErrVersionMismatch) {
+			return err
+		}
+	}
+	return fmt.Errorf("product %d: %w", id, product.ErrVersionMismatch)
+}
+
+// applyAll aplica op a todas las lineas o, si una falla, a ninguna
+func (s stock) applyAll(ctx context.Context, lines []domain.OrderLine, op, undo stockOp) error {
+	for i, l := range lines {
+		if err := s.change(ctx, l.ProductId, l.Quantity, op); err != nil {
+			s.undoAll(ctx, lines[:i], undo)
+			return err
+		}
+	}
+	return nil
+}
+
+// undoAll deshace con undo un applyAll que ya se aplico a lines
+func (s stock) undoAll(ctx context.Context, lines []domain.OrderLine, undo stockOp) {
+	// con contexto propio: deshacer no se puede cortar a medias
+	rollback := inventory.WithMovement(context.Background(), domain.Movement{
+		Kind: domain.MovementAdjustment, Reason: "rollback", Actor: inventory.Actor(ctx),
+	})
+	for _, l := range lines {
+		_ = s.change(rollback, l.ProductId, l.Quantity, undo)
+	}
+}
+
+// reserve aparta unidades de un producto publicado y no vencido
+func (s stock) reserve(p *domain.Product, quantity int) error {
+	switch {
+	case !p.IsPublished:
+		return fmt.Errorf("%w: product %d is not published", ErrUnavailable, p.Id)
+	case !p.Expiration.IsZero() && p.Expiration.Before(s.today()):
+		return fmt.Errorf("%w: product %d expired on %s", ErrUnavailable, p.Id, p.Expiration)
+	case p.Available() < quantity:
+		return fmt.Errorf("%w: product %d has %d units available", ErrInsufficientStock, p.Id, p.Available())
+	}
+	p.Reserved += quantity
+	return nil
+}
+
+// release devuelve unidades reservadas
+func release(p *domain.Product, quantity int) error {
+	p.Reserved -= quantity
+	if p.Reserved < 0 {
+		p.Reserved = 0
+	}
+	return nil
+}
+
+// unrelease deshace un release sin volver a validar el producto
+func unrelease(p *domain.Product, quantity int) error {
+	p.Reserved += quantity
+	return nil
+}
+
+// commit descuenta del stock unidades que estaban reservadas
+func commit(p *domain.Product, quantity int) error {
+	if p.Quantity < quantity {
+		return fmt.Errorf("%w: product %d has %d units", ErrInsufficientStock, p.Id, p.Quantity)
+	}
+	p.Quantity -= quantity
+	return release(p, quantity)
+}
+
+// uncommit deshace un commit
+func uncommit(p *domain.Product, quantity int) error {
+	p.Quantity += quantity
+	p.Reserved += quantity
+	return nil
+}
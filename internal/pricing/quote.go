@@ -0,0 +1,189 @@
+package pricing
+
+import (
+	"fmt"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+)
+
+// ErrInvalidPromo indica un codigo de promocion inexistente, vencido o que
+// no aplica al pedido
+var ErrInvalidPromo = domain.Invalid("invalid_promo", "invalid promo code")
+
+// Item es un producto del pedido y sus unidades
+type Item struct {
+	ProductId int
+	Quantity  int
+}
+
+// Line es el detalle del precio de un producto del pedido. Total es
+// Subtotal - Discount + Markup + Tax.
+type Line struct {
+	Product   domain.Product `json:"-"`
+	ProductId int            `json:"product_id"`
+	Name      string         `json:"name"`
+	Category  string         `json:"category,omitempty"`
+	Quantity  int            `json:"quantity"`
+	UnitPrice money.Money    `json:"unit_price" swaggertype:"number"`
+	Subtotal  money.Money    `json:"subtotal" swaggertype:"number"`
+	Discount  money.Money    `json:"discount" swaggertype:"number"`
+	Markup    money.Money    `json:"markup" swaggertype:"number"`
+	Tax       money.Money    `json:"tax" swaggertype:"number"`
+	Total     money.Money    `json:"total" swaggertype:"number"`
+	// Applied describe las reglas aplicadas, por ejemplo "markup 21%"
+	Applied []string `json:"applied,omitempty"`
+}
+
+// Quote es el precio de un pedido. Los totales son la suma de las lineas;
+// Adjustment es lo que se sumo o resto para respetar los topes del total.
+type Quote struct {
+	Lines      []Line      `json:"lines"`
+	Promo      string      `json:"promo,omitempty"`
+	Subtotal   money.Money `json:"subtotal" swaggertype:"number"`
+	Discount   money.Money `json:"discount" swaggertype:"number"`
+	Markup     money.Money `json:"markup" swaggertype:"number"`
+	Tax        money.Money `json:"tax" swaggertype:"number"`
+	Adjustment money.Money `json:"adjustment" swaggertype:"number"`
+	Total      money.Money `json:"total_price" swaggertype:"number"`
+}
+
+// linePrice es un producto ya resuelto con sus unidades
+type linePrice struct {
+	product  domain.Product
+	quantity int
+}
+
+// price calcula el precio de las lineas a la fecha today, con el codigo de
+// promocion promoCode si no es vacio. Por linea se aplica primero el descuento
+// (por cantidad mas promocion, con el tope de Caps.MaxDiscount), despues el
+// recargo del tramo y por ultimo el impuesto sobre el resultado.
+func (r Rules) price(lines []linePrice, promoCode string, today domain.Date) (Quote, error) {
+	mode, err := money.ParseRounding(r.Rounding)
+	if err != nil {
+		return Quote{}, err
+	}
+	var q Quote
+	subtotals := make([]money.Money, len(lines))
+	for i, l := range lines {
+		if subtotals[i], err = l.product.Price.Mul(int64(l.quantity)); err != nil {
+			return Quote{}, err
+		}
+		if q.Subtotal, err = q.Subtotal.Add(subtotals[i]); err != nil {
+			return Quote{}, err
+		}
+	}
+
+	var promo Promo
+	if promoCode != "" {
+		var ok bool
+		if promo, ok = r.promo(promoCode); !ok || !promo.validOn(today) {
+			return Quote{}, fmt.Errorf("%w: %s", ErrInvalidPromo, promoCode)
+		}
+		if q.Subtotal.Compare(promo.MinSubtotal) < 0 {
+			return Quote{}, fmt.Errorf("%w: %s needs a subtotal of at least %s", ErrInvalidPromo, promo.Code, promo.MinSubtotal)
+		}
+		q.Promo = promo.Code
+	}
+	markup, hasMarkup := r.markupFor(len(lines))
+
+	q.Lines = make([]Line, 0, len(lines))
+	for i, l := range lines {
+		line := Line{
+			Product:   l.product,
+			ProductId: l.product.Id,
+			Name:      l.product.Name,
+			Category:  r.categoryOf(l.product),
+			Quantity:  l.quantity,
+			UnitPrice: l.product.Price,
+			Subtotal:  subtotals[i],
+		}
+
+		discount := r.quantityDiscountFor(line.Category, l.quantity)
+		if discount > 0 {
+			line.Applied = append(line.Applied, "quantity discount "+discount.String())
+		}
+		if q.Promo != "" && promo.appliesTo(line.Category) && promo.Percent > 0 {
+			discount += promo.Percent
+			line.Applied = append(line.Applied, "promo "+promo.Code+" "+promo.Percent.String())
+		}
+		if r.Caps.MaxDiscount > 0 && discount > r.Caps.MaxDiscount {
+			discount = r.Caps.MaxDiscount
+			line.Applied = append(line.Applied, "discount capped at "+discount.String())
+		}
+		if line.Discount, err = discount.apply(line.Subtotal, mode); err != nil {
+			return Quote{}, err
+		}
+		net, err := line.Subtotal.Sub(line.Discount)
+		if err != nil {
+			return Quote{}, err
+		}
+
+		if hasMarkup {
+			if line.Markup, err = markup.apply(net, mode); err != nil {
+				return Quote{}, err
+			}
+			line.Applied = append(line.Applied, "markup "+markup.String())
+		}
+		taxed, err := net.Add(line.Markup)
+		if err != nil {
+			return Quote{}, err
+		}
+
+		tax, source := r.taxFor(l.product, line.Category)
+		if tax > 0 {
+			if line.Tax, err = tax.apply(taxed, mode); err != nil {
+				return Quote{}, err
+			}
+			line.Applied = append(line.Applied, "tax "+tax.String()+" ("+source+")")
+		}
+		if line.Total, err = taxed.Add(line.Tax); err != nil {
+			return Quote{}, err
+		}
+
+		if err := q.add(line); err != nil {
+			return Quote{}, err
+		}
+	}
+	if err := r.applyCaps(&q); err != nil {
+		return Quote{}, err
+	}
+	return q, nil
+}
+
+// add suma line a los totales del pedido
+func (q *Quote) add(line Line) error {
+	var err error
+	if q.Discount, err = q.Discount.Add(line.Discount); err != nil {
+		return err
+	}
+	if q.Markup, err = q.Markup.Add(line.Markup); err != nil {
+		return err
+	}
+	if q.Tax, err = q.Tax.Add(line.Tax); err != nil {
+		return err
+	}
+	if q.Total, err = q.Total.Add(line.Total); err != nil {
+		return err
+	}
+	q.Lines = append(q.Lines, line)
+	return nil
+}
+
+// applyCaps lleva el total a [MinTotal, MaxTotal] y registra la diferencia
+// en Adjustment
+func (r Rules) applyCaps(q *Quote) error {
+	target := q.Total
+	switch {
+	case !r.Caps.MinTotal.IsZero() && q.Total.Compare(r.Caps.MinTotal) < 0:
+		target = r.Caps.MinTotal
+	case !r.Caps.MaxTotal.IsZero() && q.Total.Compare(r.Caps.MaxTotal) > 0:
+		target = r.Caps.MaxTotal
+	}
+	var err error
+	if q.Adjustment, err = target.Sub(q.Total); err != nil {
+		return err
+	}
+	q.Total = target
+	return nil
+}
@@ -0,0 +1,347 @@
+// Package pricing calcula el precio al consumidor de una lista de productos
+// a partir de reglas configurables: recargos por cantidad de items,
+// impuestos por producto o categoria, descuentos por cantidad, codigos de
+// promocion y topes.
+package pricing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+)
+
+// ErrInvalidRules indica un archivo de reglas mal formado o inconsistente
+var ErrInvalidRules = domain.Invalid("invalid_pricing_rules", "invalid pricing rules")
+
+// Percent es un porcentaje en centesimos: 2100 es 21%, 1050 es 10.5%
+type Percent int64
+
+// percentScale es el denominador para aplicar un Percent a un importe
+const percentScale = 10000
+
+// ParsePercent interpreta un porcentaje como "21" o "10.5", con hasta dos
+// decimales
+func ParsePercent(s string) (Percent, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("%w: %q is not a percentage", ErrInvalidRules, s)
+	}
+	r.Mul(r, big.NewRat(100, 1))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, fmt.Errorf("%w: %q has more than two decimals", ErrInvalidRules, s)
+	}
+	return Percent(r.Num().Int64()), nil
+}
+
+// String devuelve el porcentaje sin ceros de mas, por ejemplo "10.5%"
+func (p Percent) String() string {
+	s := strconv.FormatFloat(float64(p)/100, 'f', -1, 64)
+	return s + "%"
+}
+
+// MarshalJSON escribe el porcentaje como numero
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return []byte(strings.TrimSuffix(p.String(), "%")), nil
+}
+
+// UnmarshalJSON acepta un numero o un string
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	text := string(bytes.TrimSpace(data))
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParsePercent(text)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// apply devuelve el p% de m
+func (p Percent) apply(m money.Money, mode money.Rounding) (money.Money, error) {
+	return m.MulRatio(int64(p), percentScale, mode)
+}
+
+// Markup es un recargo segun la cantidad de productos distintos del pedido.
+// MaxItems 0 no tiene limite.
+type Markup struct {
+	MinItems int     `json:"min_items"`
+	MaxItems int     `json:"max_items,omitempty"`
+	Percent  Percent `json:"percent" swaggertype:"number"`
+}
+
+// contains indica si el tramo aplica a un pedido de n productos
+func (m Markup) contains(n int) bool {
+	return n >= m.MinItems && (m.MaxItems == 0 || n <= m.MaxItems)
+}
+
+// Category agrupa productos por id o por prefijo de code_value
+type Category struct {
+	Name         string   `json:"name"`
+	ProductIds   []int    `json:"product_ids,omitempty"`
+	CodePrefixes []string `json:"code_prefixes,omitempty"`
+}
+
+// contains indica si p pertenece a la categoria
+func (c Category) contains(p domain.Product) bool {
+	for _, id := range c.ProductIds {
+		if id == p.Id {
+			return true
+		}
+	}
+	for _, prefix := range c.CodePrefixes {
+		if strings.HasPrefix(p.CodeValue, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Taxes son las alicuotas: la del producto tiene prioridad sobre la de su
+// categoria y esta sobre Default
+type Taxes struct {
+	Default    Percent            `json:"default" swaggertype:"number"`
+	Categories map[string]Percent `json:"categories,omitempty"`
+	Products   map[int]Percent    `json:"products,omitempty"`
+}
+
+// QuantityDiscount descuenta Percent de cada linea con al menos MinQuantity
+// unidades; con Category solo aplica a esa categoria
+type QuantityDiscount struct {
+	MinQuantity int     `json:"min_quantity"`
+	Percent     Percent `json:"percent" swaggertype:"number"`
+	Category    string  `json:"category,omitempty"`
+}
+
+// Promo es un codigo de descuento. Sin Categories aplica a todos los
+// productos; MinSubtotal es el minimo del pedido antes de descuentos.
+type Promo struct {
+	Code        string      `json:"code"`
+	Percent     Percent     `json:"percent" swaggertype:"number"`
+	Categories  []string    `json:"categories,omitempty"`
+	MinSubtotal money.Money `json:"min_subtotal,omitempty" swaggertype:"number"`
+	ValidFrom   domain.Date `json:"valid_from,omitempty" swaggertype:"string"`
+	ValidUntil  domain.Date `json:"valid_until,omitempty" swaggertype:"string"`
+}
+
+// appliesTo indica si la promo descuenta productos de category
+func (p Promo) appliesTo(category string) bool {
+	if len(p.Categories) == 0 {
+		return true
+	}
+	for _, c := range p.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// validOn indica si la promo esta vigente el dia today
+func (p Promo) validOn(today domain.Date) bool {
+	if !p.ValidFrom.IsZero() && today.Before(p.ValidFrom) {
+		return false
+	}
+	return p.ValidUntil.IsZero() || !today.After(p.ValidUntil)
+}
+
+// Caps son los topes. MaxDiscount limita la suma de descuentos de una
+// linea; MinTotal y MaxTotal acotan el total del pedido. Cero no pone tope.
+type Caps struct {
+	MaxDiscount Percent     `json:"max_discount,omitempty" swaggertype:"number"`
+	MinTotal    money.Money `json:"min_total,omitempty" swaggertype:"number"`
+	MaxTotal    money.Money `json:"max_total,omitempty" swaggertype:"number"`
+}
+
+// Rules es la configuracion completa de precios
+type Rules struct {
+	// Rounding es half-even o half-up; cada importe se redondea por linea
+	Rounding          string             `json:"rounding,omitempty"`
+	Markups           []Markup           `json:"markups"`
+	Categories        []Category         `json:"categories,omitempty"`
+	Taxes             Taxes              `json:"taxes"`
+	QuantityDiscounts []QuantityDiscount `json:"quantity_discounts,omitempty"`
+	Promos            []Promo            `json:"promos,omitempty"`
+	Caps              Caps               `json:"caps"`
+}
+
+// DefaultRules son los recargos historicos de consumer_price: 21% hasta 9
+// productos, 17% de 10 a 19 y 15% desde 20
+func DefaultRules() Rules {
+	return Rules{
+		Markups: []Markup{
+			{MinItems: 1, MaxItems: 9, Percent: 2100},
+			{MinItems: 10, MaxItems: 19, Percent: 1700},
+			{MinItems: 20, Percent: 1500},
+		},
+	}
+}
+
+// LoadRules lee y valida las reglas del archivo json en path
+func LoadRules(path string) (Rules, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(file))
+	decoder.DisallowUnknownFields()
+	var rules Rules
+	if err := decoder.Decode(&rules); err != nil {
+		return Rules{}, fmt.Errorf("%w: %s: %v", ErrInvalidRules, path, err)
+	}
+	if err := rules.Validate(); err != nil {
+		return Rules{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Validate revisa que las reglas sean consistentes: porcentajes no
+// negativos, tramos de recargo sin superponerse, categorias y promos sin
+// repetir y referencias a categorias existentes
+func (r Rules) Validate() error {
+	if _, err := money.ParseRounding(r.Rounding); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	for i, m := range r.Markups {
+		switch {
+		case m.Percent < 0:
+			return fmt.Errorf("%w: markup %d has a negative percent", ErrInvalidRules, i)
+		case m.MinItems < 0 || (m.MaxItems != 0 && m.MaxItems < m.MinItems):
+			return fmt.Errorf("%w: markup %d has an invalid item range", ErrInvalidRules, i)
+		}
+		for j, other := range r.Markups[:i] {
+			if m.contains(other.MinItems) || other.contains(m.MinItems) {
+				return fmt.Errorf("%w: markups %d and %d overlap", ErrInvalidRules, j, i)
+			}
+		}
+	}
+	categories := map[string]bool{}
+	for _, c := range r.Categories {
+		if c.Name == "" || categories[c.Name] {
+			return fmt.Errorf("%w: category names must be unique and not empty, got %q", ErrInvalidRules, c.Name)
+		}
+		categories[c.Name] = true
+	}
+	known := func(category string) error {
+		if !categories[category] {
+			return fmt.Errorf("%w: unknown category %q", ErrInvalidRules, category)
+		}
+		return nil
+	}
+	if r.Taxes.Default < 0 {
+		return fmt.Errorf("%w: negative default tax", ErrInvalidRules)
+	}
+	for category, tax := range r.Taxes.Categories {
+		if err := known(category); err != nil {
+			return err
+		}
+		if tax < 0 {
+			return fmt.Errorf("%w: negative tax for category %q", ErrInvalidRules, category)
+		}
+	}
+	for id, tax := range r.Taxes.Products {
+		if tax < 0 {
+			return fmt.Errorf("%w: negative tax for product %d", ErrInvalidRules, id)
+		}
+	}
+	for i, d := range r.QuantityDiscounts {
+		if d.MinQuantity < 1 || d.Percent < 0 || d.Percent > percentScale {
+			return fmt.Errorf("%w: quantity discount %d needs min_quantity >= 1 and a percent between 0 and 100", ErrInvalidRules, i)
+		}
+		if d.Category != "" {
+			if err := known(d.Category); err != nil {
+				return err
+			}
+		}
+	}
+	codes := map[string]bool{}
+	for _, p := range r.Promos {
+		code := strings.ToUpper(p.Code)
+		if code == "" || codes[code] {
+			return fmt.Errorf("%w: promo codes must be unique and not empty, got %q", ErrInvalidRules, p.Code)
+		}
+		codes[code] = true
+		if p.Percent < 0 || p.Percent > percentScale {
+			return fmt.Errorf("%w: promo %s percent must be between 0 and 100", ErrInvalidRules, p.Code)
+		}
+		if !p.ValidFrom.IsZero() && !p.ValidUntil.IsZero() && p.ValidUntil.Before(p.ValidFrom) {
+			return fmt.Errorf("%w: promo %s ends before it starts", ErrInvalidRules, p.Code)
+		}
+		for _, category := range p.Categories {
+			if err := known(category); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Caps.MaxDiscount < 0 || r.Caps.MaxDiscount > percentScale {
+		return fmt.Errorf("%w: max_discount must be between 0 and 100", ErrInvalidRules)
+	}
+	if r.Caps.MinTotal.Amount() < 0 || r.Caps.MaxTotal.Amount() < 0 ||
+		(!r.Caps.MaxTotal.IsZero() && r.Caps.MaxTotal.Compare(r.Caps.MinTotal) < 0) {
+		return fmt.Errorf("%w: min_total and max_total must be positive and min_total <= max_total", ErrInvalidRules)
+	}
+	return nil
+}
+
+// categoryOf devuelve la primera categoria que contiene a p, o ""
+func (r Rules) categoryOf(p domain.Product) string {
+	for _, c := range r.Categories {
+		if c.contains(p) {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// markupFor devuelve el tramo de recargo de un pedido de n productos
+func (r Rules) markupFor(n int) (Percent, bool) {
+	for _, m := range r.Markups {
+		if m.contains(n) {
+			return m.Percent, true
+		}
+	}
+	return 0, false
+}
+
+// taxFor devuelve la alicuota de p y de donde sale
+func (r Rules) taxFor(p domain.Product, category string) (Percent, string) {
+	if tax, ok := r.Taxes.Products[p.Id]; ok {
+		return tax, "product"
+	}
+	if tax, ok := r.Taxes.Categories[category]; ok {
+		return tax, "category " + category
+	}
+	return r.Taxes.Default, "default"
+}
+
+// quantityDiscountFor devuelve el mayor descuento por cantidad que aplica a
+// quantity unidades de category
+func (r Rules) quantityDiscountFor(category string, quantity int) Percent {
+	var best Percent
+	for _, d := range r.QuantityDiscounts {
+		if quantity >= d.MinQuantity && (d.Category == "" || d.Category == category) && d.Percent > best {
+			best = d.Percent
+		}
+	}
+	return best
+}
+
+// promo busca un codigo sin distinguir mayusculas
+func (r Rules) promo(code string) (Promo, bool) {
+	for _, p := range r.Promos {
+		if strings.EqualFold(p.Code, code) {
+			return p, true
+		}
+	}
+	return Promo{}, false
+}
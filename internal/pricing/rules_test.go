@@ -0,0 +1,64 @@
+package pricing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePercent(t *testing.T) {
+	for in, want := range map[string]Percent{"21": 2100, "10.5": 1050, "0.25": 25, "0": 0, "1e1": 1000} {
+		got, err := ParsePercent(in)
+		assert.Nil(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"", "abc", "10.125", "21%"} {
+		_, err := ParsePercent(in)
+		assert.ErrorIs(t, err, ErrInvalidRules, in)
+	}
+	assert.Equal(t, "10.5%", Percent(1050).String())
+	assert.Equal(t, "21%", Percent(2100).String())
+}
+
+func TestDefaultRules_Markups(t *testing.T) {
+	rules := DefaultRules()
+	assert.Nil(t, rules.Validate())
+	for n, want := range map[int]Percent{1: 2100, 9: 2100, 10: 1700, 19: 1700, 20: 1500, 500: 1500} {
+		got, ok := rules.markupFor(n)
+		assert.True(t, ok, n)
+		assert.Equal(t, want, got, n)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules("../../pricing.json")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultRules().Markups, rules.Markups)
+
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "pricing.json")
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	invalid := []string{
+		`{"markups": [{"min_items": 1, "max_items": 10, "percent": 21}, {"min_items": 10, "percent": 17}]}`,
+		`{"markups": [{"min_items": 5, "max_items": 2, "percent": 21}]}`,
+		`{"taxes": {"default": -1}}`,
+		`{"taxes": {"categories": {"food": 10.5}}}`,
+		`{"categories": [{"name": "food"}, {"name": "food"}]}`,
+		`{"quantity_discounts": [{"min_quantity": 0, "percent": 5}]}`,
+		`{"promos": [{"code": "A", "percent": 10}, {"code": "a", "percent": 5}]}`,
+		`{"promos": [{"code": "A", "percent": 110}]}`,
+		`{"promos": [{"code": "A", "percent": 10, "valid_from": "2023-02-01", "valid_until": "2023-01-01"}]}`,
+		`{"caps": {"min_total": 100, "max_total": 10}}`,
+		`{"rounding": "down"}`,
+		`{"markup": []}`,
+		`{"taxes": {"default": "10.125"}}`,
+	}
+	for _, content := range invalid {
+		_, err := LoadRules(write(content))
+		assert.ErrorIs(t, err, ErrInvalidRules, content)
+	}
+}
@@ -0,0 +1,67 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+var (
+	// ErrInvalidItems indica un pedido vacio, con productos repetidos o con
+	// cantidades no positivas
+	ErrInvalidItems = domain.Invalid("invalid_items", "invalid items")
+	// ErrProductNotFound indica un producto del pedido que no existe
+	ErrProductNotFound = domain.Invalid("unknown_product", "product not found")
+	// ErrNotPublished indica un producto del pedido que no esta publicado
+	ErrNotPublished = domain.Invalid("product_not_published", "product is not published")
+)
+
+// Catalog es de donde el servicio obtiene los productos; product.Service lo
+// implementa
+type Catalog interface {
+	GetByID(ctx context.Context, id int) (domain.Product, error)
+}
+
+type Service interface {
+	Quote(ctx context.Context, items []Item, promo string) (Quote, error)
+}
+
+type service struct {
+	catalog Catalog
+	rules   Rules
+	today   func() domain.Date
+}
+
+// NewService crea un servicio de precios con reglas ya validadas
+func NewService(catalog Catalog, rules Rules) Service {
+	return &service{catalog: catalog, rules: rules, today: domain.Today}
+}
+
+// Quote calcula el precio al consumidor de items. Todos los productos deben
+// existir, estar publicados y aparecer una sola vez.
+func (s *service) Quote(ctx context.Context, items []Item, promo string) (Quote, error) {
+	if len(items) == 0 {
+		return Quote{}, fmt.Errorf("%w: the list is empty", ErrInvalidItems)
+	}
+	lines := make([]linePrice, 0, len(items))
+	seen := map[int]bool{}
+	for _, item := range items {
+		if item.Quantity < 1 {
+			return Quote{}, fmt.Errorf("%w: quantity of product %d must be positive", ErrInvalidItems, item.ProductId)
+		}
+		if seen[item.ProductId] {
+			return Quote{}, fmt.Errorf("%w: product %d is repeated", ErrInvalidItems, item.ProductId)
+		}
+		seen[item.ProductId] = true
+		p, err := s.catalog.GetByID(ctx, item.ProductId)
+		if err != nil {
+			return Quote{}, fmt.Errorf("%w: %d", ErrProductNotFound, item.ProductId)
+		}
+		if !p.IsPublished {
+			return Quote{}, fmt.Errorf("%w: %d", ErrNotPublished, item.ProductId)
+		}
+		lines = append(lines, linePrice{product: p, quantity: item.Quantity})
+	}
+	return s.rules.price(lines, promo, s.today())
+}
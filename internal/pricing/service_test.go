@@ -0,0 +1,165 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// catalog es un Catalog en memoria para los tests
+type catalog map[int]domain.Product
+
+func (c catalog) GetByID(ctx context.Context, id int) (domain.Product, error) {
+	p, ok := c[id]
+	if !ok {
+		return domain.Product{}, errors.New("product not found")
+	}
+	return p, nil
+}
+
+func ars(minor int64) money.Money {
+	return money.MustNew(minor, "ARS")
+}
+
+func newTestService(rules Rules, products ...domain.Product) *service {
+	c := catalog{}
+	for _, p := range products {
+		c[p.Id] = p
+	}
+	s := NewService(c, rules).(*service)
+	s.today = func() domain.Date { return domain.NewDate(2023, time.March, 15) }
+	return s
+}
+
+func TestService_QuoteDefaultRules(t *testing.T) {
+	products := []domain.Product{
+		{Id: 1, Name: "a", Price: ars(7142), IsPublished: true},
+		{Id: 2, Name: "b", Price: ars(35279), IsPublished: true},
+		{Id: 3, Name: "c", Price: ars(83902), IsPublished: true},
+	}
+	s := newTestService(DefaultRules(), products...)
+
+	q, err := s.Quote(context.Background(), []Item{{1, 1}, {2, 1}, {3, 1}}, "")
+	assert.Nil(t, err)
+	// cada linea se redondea por separado: 86.42 + 426.88 + 1015.21
+	assert.Equal(t, ars(152851), q.Total)
+	assert.Equal(t, ars(126323), q.Subtotal)
+	assert.Equal(t, ars(26528), q.Markup)
+	assert.Len(t, q.Lines, 3)
+	assert.Equal(t, []string{"markup 21%"}, q.Lines[0].Applied)
+
+	// 10 productos distintos caen en el tramo de 17%
+	for i := 4; i <= 10; i++ {
+		products = append(products, domain.Product{Id: i, Price: ars(10000), IsPublished: true})
+	}
+	s = newTestService(DefaultRules(), products...)
+	items := make([]Item, len(products))
+	for i, p := range products {
+		items[i] = Item{p.Id, 1}
+	}
+	q, err = s.Quote(context.Background(), items, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"markup 17%"}, q.Lines[9].Applied)
+	assert.Equal(t, ars(11700), q.Lines[9].Total)
+}
+
+func TestService_QuoteRules(t *testing.T) {
+	rules := Rules{
+		Markups:    []Markup{{MinItems: 1, Percent: 1000}},
+		Categories: []Category{{Name: "food", CodePrefixes: []string{"F"}}, {Name: "books", ProductIds: []int{3}}},
+		Taxes: Taxes{
+			Default:    2100,
+			Categories: map[string]Percent{"food": 1050},
+			Products:   map[int]Percent{2: 0},
+		},
+		QuantityDiscounts: []QuantityDiscount{{MinQuantity: 10, Percent: 500}, {MinQuantity: 10, Percent: 1000, Category: "books"}},
+		Promos: []Promo{
+			{Code: "BOOKS", Percent: 1500, Categories: []string{"books"}},
+			{Code: "OLD", Percent: 1000, ValidUntil: domain.NewDate(2023, time.January, 1)},
+			{Code: "BIG", Percent: 1000, MinSubtotal: ars(1000000)},
+		},
+		Caps: Caps{MaxDiscount: 2000},
+	}
+	assert.Nil(t, rules.Validate())
+	s := newTestService(rules,
+		domain.Product{Id: 1, CodeValue: "F1", Price: ars(1000), IsPublished: true},
+		domain.Product{Id: 2, CodeValue: "F2", Price: ars(1000), IsPublished: true},
+		domain.Product{Id: 3, CodeValue: "B1", Price: ars(1000), IsPublished: true},
+		domain.Product{Id: 4, CodeValue: "X1", Price: ars(1000), IsPublished: true},
+	)
+
+	q, err := s.Quote(context.Background(), []Item{{1, 10}, {2, 1}, {3, 10}, {4, 1}}, "books")
+	assert.Nil(t, err)
+	assert.Equal(t, "BOOKS", q.Promo)
+
+	food := q.Lines[0]
+	assert.Equal(t, "food", food.Category)
+	// 100 - 5% = 95, +10% = 104.50, +10.5% = 115.47
+	assert.Equal(t, ars(10000), food.Subtotal)
+	assert.Equal(t, ars(500), food.Discount)
+	assert.Equal(t, ars(950), food.Markup)
+	assert.Equal(t, ars(1097), food.Tax)
+	assert.Equal(t, ars(11547), food.Total)
+	assert.Equal(t, []string{"quantity discount 5%", "markup 10%", "tax 10.5% (category food)"}, food.Applied)
+
+	// el impuesto del producto gana sobre el de la categoria
+	assert.Equal(t, ars(0), q.Lines[1].Tax)
+
+	// 10% por cantidad + 15% de promo, con tope de 20%
+	books := q.Lines[2]
+	assert.Equal(t, ars(2000), books.Discount)
+	assert.Equal(t, []string{"quantity discount 10%", "promo BOOKS 15%", "discount capped at 20%", "markup 10%", "tax 21% (default)"}, books.Applied)
+
+	var total money.Money
+	for _, line := range q.Lines {
+		total, _ = total.Add(line.Total)
+	}
+	assert.Equal(t, total, q.Total)
+	assert.True(t, q.Adjustment.IsZero())
+
+	for _, code := range []string{"OLD", "BIG", "NOPE"} {
+		_, err = s.Quote(context.Background(), []Item{{1, 1}}, code)
+		assert.ErrorIs(t, err, ErrInvalidPromo, code)
+	}
+}
+
+func TestService_QuoteCaps(t *testing.T) {
+	rules := Rules{Caps: Caps{MinTotal: ars(5000), MaxTotal: ars(20000)}}
+	s := newTestService(rules, domain.Product{Id: 1, Price: ars(1000), IsPublished: true})
+
+	q, err := s.Quote(context.Background(), []Item{{1, 2}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, ars(5000), q.Total)
+	assert.Equal(t, ars(3000), q.Adjustment)
+
+	q, err = s.Quote(context.Background(), []Item{{1, 30}}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, ars(20000), q.Total)
+	assert.Equal(t, ars(-10000), q.Adjustment)
+}
+
+func TestService_QuoteInvalidItems(t *testing.T) {
+	s := newTestService(DefaultRules(),
+		domain.Product{Id: 1, Price: ars(1000), IsPublished: true},
+		domain.Product{Id: 2, Price: ars(1000)},
+		domain.Product{Id: 3, Price: money.MustNew(1000, "USD"), IsPublished: true},
+	)
+	ctx := context.Background()
+	_, err := s.Quote(ctx, nil, "")
+	assert.ErrorIs(t, err, ErrInvalidItems)
+	_, err = s.Quote(ctx, []Item{{1, 1}, {1, 2}}, "")
+	assert.ErrorIs(t, err, ErrInvalidItems)
+	_, err = s.Quote(ctx, []Item{{1, 0}}, "")
+	assert.ErrorIs(t, err, ErrInvalidItems)
+	_, err = s.Quote(ctx, []Item{{9, 1}}, "")
+	assert.ErrorIs(t, err, ErrProductNotFound)
+	_, err = s.Quote(ctx, []Item{{2, 1}}, "")
+	assert.ErrorIs(t, err, ErrNotPublished)
+	_, err = s.Quote(ctx, []Item{{1, 1}, {3, 1}}, "")
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
@@ -0,0 +1,118 @@
+package product
+
+import (
+	"context"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/search"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+// ErrAborted indica un item valido de un lote todo-o-nada que no se aplico
+// porque otro item fallo
+var ErrAborted = store.ErrAborted
+
+// BulkResult es el resultado de un item de una operacion en lote: el
+// producto como quedo guardado (o como estaba, si se borro) o el motivo por
+// el que no se aplico
+type BulkResult struct {
+	Product domain.Product
+	Err     error
+}
+
+// CreateMany agrega products en una sola escritura. Un code_value repetido,
+// contra los productos guardados o dentro del lote, falla solo ese item
+// salvo con atomic, donde no se agrega ninguno.
+func (s *service) CreateMany(ctx context.Context, products []domain.Product, atomic bool) ([]BulkResult, error) {
+	results := make([]BulkResult, len(products))
+	changes := make([]store.Change, len(products))
+	for i, p := range products {
+		p.LifecycleReason, p.Reserved = "", 0
+		changes[i] = store.Change{Op: store.OpCreate, Product: p}
+	}
+	return s.applyBulk(ctx, results, changes, atomic)
+}
+
+// UpdateMany aplica cada update sobre el producto u.Id con las mismas reglas
+// que Update, en una sola escritura
+func (s *service) UpdateMany(ctx context.Context, updates []domain.Product, atomic bool) ([]BulkResult, error) {
+	results := make([]BulkResult, len(updates))
+	changes := make([]store.Change, len(updates))
+	for i, u := range updates {
+		p, err := s.r.GetByID(ctx, u.Id)
+		if err != nil {
+			results[i].Err = store.ErrNotFound
+			continue
+		}
+		if p, err = merge(p, u); err != nil {
+			results[i].Err = err
+			continue
+		}
+		changes[i] = store.Change{Op: store.OpUpdate, Product: p}
+	}
+	return s.applyBulk(ctx, results, changes, atomic)
+}
+
+// DeleteMany elimina los productos ids en una sola escritura; los que tienen
+// unidades reservadas fallan con ErrReserved. Cada borrado lleva la version
+// en la que se reviso Reserved, asi una reserva posterior lo hace fallar con
+// ErrVersionMismatch.
+func (s *service) DeleteMany(ctx context.Context, ids []int, atomic bool) ([]BulkResult, error) {
+	results := make([]BulkResult, len(ids))
+	changes := make([]store.Change, len(ids))
+	for i, id := range ids {
+		p, err := s.r.GetByID(ctx, id)
+		switch {
+		case err != nil:
+			results[i].Err = store.ErrNotFound
+		case p.Reserved > 0:
+			results[i].Err = ErrReserved
+		default:
+			changes[i] = store.Change{Op: store.OpDelete, Id: id, Version: p.Version}
+		}
+	}
+	return s.applyBulk(ctx, results, changes, atomic)
+}
+
+// applyBulk manda al repositorio los cambios de los items que no fallaron
+// antes (results[i].Err nil) y completa results. Con atomic, si alguno ya
+// fallo no se manda nada.
+func (s *service) applyBulk(ctx context.Context, results []BulkResult, changes []store.Change, atomic bool) ([]BulkResult, error) {
+	var pending []store.Change
+	var index []int
+	for i, r := range results {
+		if r.Err == nil {
+			pending = append(pending, changes[i])
+			index = append(index, i)
+		}
+	}
+	if atomic && len(pending) < len(results) {
+		for _, i := range index {
+			results[i].Err = ErrAborted
+		}
+		return results, nil
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+	before := s.revisionTag(ctx)
+	applied, err := s.r.Apply(ctx, pending, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for j, r := range applied {
+		results[index[j]] = BulkResult{Product: r.Product, Err: r.Err}
+	}
+	s.updateIndex(ctx, before, func(ix *search.Index) {
+		for j, r := range applied {
+			switch {
+			case r.Err != nil:
+			case pending[j].Op == store.OpDelete:
+				ix.Remove(pending[j].Id)
+			default:
+				ix.Add(r.Product)
+			}
+		}
+	})
+	return results, nil
+}
@@ -0,0 +1,142 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+// ExpireAction es lo que hace ExpireProducts con un producto vencido
+type ExpireAction string
+
+const (
+	// ExpireUnpublish despublica el producto y registra el motivo
+	ExpireUnpublish ExpireAction = "unpublish"
+	// ExpireFlag solo registra el motivo, sin despublicarlo
+	ExpireFlag ExpireAction = "flag"
+)
+
+// ParseExpireAction valida el nombre de una ExpireAction; "" es unpublish
+func ParseExpireAction(s string) (ExpireAction, error) {
+	switch ExpireAction(s) {
+	case "", ExpireUnpublish:
+		return ExpireUnpublish, nil
+	case ExpireFlag:
+		return ExpireFlag, nil
+	}
+	return "", fmt.Errorf("unknown expire action %q, must be unpublish or flag", s)
+}
+
+// ExpireReport resume una ejecucion de ExpireProducts
+type ExpireReport struct {
+	Action ExpireAction `json:"action"`
+	Date   domain.Date  `json:"date" swaggertype:"string"`
+	// Expired son los productos actualizados en esta ejecucion
+	Expired []int `json:"expired"`
+	// Skipped son los productos que cambiaron o se borraron mientras se
+	// procesaban; se vuelven a evaluar en la proxima ejecucion
+	Skipped []int `json:"skipped"`
+	// Failed son los productos que el store no pudo actualizar
+	Failed []ExpireFailure `json:"failed"`
+}
+
+// ExpireFailure es un producto que ExpireProducts no pudo actualizar
+type ExpireFailure struct {
+	Id    int    `json:"id"`
+	Error string `json:"error"`
+}
+
+// expiredFilter selecciona los productos vencidos a la fecha today: un
+// producto vale hasta el dia de su expiracion inclusive
+func expiredFilter(today domain.Date) query.Expr {
+	return query.Cmp{Field: "expiration", Op: query.OpLt, Value: today}
+}
+
+// expiringFilter selecciona los productos que vencen entre today y los
+// proximos days dias
+func expiringFilter(today domain.Date, days int) query.Expr {
+	return query.And{
+		Left:  query.Cmp{Field: "expiration", Op: query.OpGe, Value: today},
+		Right: query.Cmp{Field: "expiration", Op: query.OpLe, Value: today.AddDays(days)},
+	}
+}
+
+// andFilter agrega extra al filtro de q
+func andFilter(q query.Query, extra query.Expr) query.Query {
+	if q.Filter == nil {
+		q.Filter = extra
+	} else {
+		q.Filter = query.And{Left: q.Filter, Right: extra}
+	}
+	return q
+}
+
+// ListExpired devuelve los productos vencidos a la fecha today
+func (s *service) ListExpired(ctx context.Context, today domain.Date, q query.Query) (query.Page, error) {
+	return s.r.List(ctx, andFilter(q, expiredFilter(today)))
+}
+
+// ListExpiring devuelve los productos que todavia no vencieron pero vencen
+// dentro de los proximos days dias
+func (s *service) ListExpiring(ctx context.Context, today domain.Date, days int, q query.Query) (query.Page, error) {
+	return s.r.List(ctx, andFilter(q, expiringFilter(today, days)))
+}
+
+// ExpireProducts aplica action a los productos vencidos a la fecha today
+// que todavia no fueron procesados, registrando el motivo en
+// LifecycleReason. Dos ejecuciones nunca se superponen.
+func (s *service) ExpireProducts(ctx context.Context, today domain.Date, action ExpireAction) (ExpireReport, error) {
+	s.expireMu.Lock()
+	defer s.expireMu.Unlock()
+
+	report := ExpireReport{Action: action, Date: today, Expired: []int{}, Skipped: []int{}, Failed: []ExpireFailure{}}
+	pending := query.And{
+		Left:  expiredFilter(today),
+		Right: query.Cmp{Field: "lifecycle_reason", Op: query.OpEq, Value: ""},
+	}
+	var filter query.Expr = pending
+	if action == ExpireUnpublish {
+		filter = query.And{Left: pending, Right: query.Cmp{Field: "is_published", Op: query.OpEq, Value: true}}
+	}
+	page, err := s.r.List(ctx, query.Query{Filter: filter})
+	if err != nil {
+		return report, err
+	}
+
+	if len(page.Items) == 0 {
+		return report, nil
+	}
+	changes := make([]store.Change, len(page.Items))
+	for i, p := range page.Items {
+		switch action {
+		case ExpireUnpublish:
+			p.IsPublished = false
+			p.LifecycleReason = fmt.Sprintf("expired on %s: unpublished automatically", p.Expiration)
+		case ExpireFlag:
+			p.LifecycleReason = fmt.Sprintf("expired on %s", p.Expiration)
+		}
+		changes[i] = store.Change{Op: store.OpUpdate, Product: p}
+	}
+	// todos los cambios en una sola escritura, que en el backend json es
+	// reescribir el archivo una vez y no una por producto
+	results, err := s.applyBulk(ctx, make([]BulkResult, len(changes)), changes, false)
+	if err != nil {
+		return report, err
+	}
+	for i, r := range results {
+		id := page.Items[i].Id
+		switch {
+		case r.Err == nil:
+			report.Expired = append(report.Expired, id)
+		case errors.Is(r.Err, ErrVersionMismatch), errors.Is(r.Err, store.ErrNotFound):
+			report.Skipped = append(report.Skipped, id)
+		default:
+			report.Failed = append(report.Failed, ExpireFailure{Id: id, Error: r.Err.Error()})
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,159 @@
+package product
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var today = domain.NewDate(2024, time.March, 10)
+
+func newExpirationService() (Service, store.Store) {
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "old", CodeValue: "A", IsPublished: true, Expiration: domain.NewDate(2021, time.December, 15)},
+		{Id: 2, Name: "yesterday", CodeValue: "B", IsPublished: true, Expiration: today.AddDays(-1)},
+		{Id: 3, Name: "today", CodeValue: "C", IsPublished: true, Expiration: today},
+		{Id: 4, Name: "next week", CodeValue: "D", IsPublished: true, Expiration: today.AddDays(7)},
+		{Id: 5, Name: "unpublished", CodeValue: "E", IsPublished: false, Expiration: today.AddDays(-30)},
+	})
+	return NewService(NewRepository(storage)), storage
+}
+
+func pageIDs(page query.Page) []int {
+	ids := []int{}
+	for _, p := range page.Items {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+func TestService_ListExpiredAndExpiring(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newExpirationService()
+
+	page, err := s.ListExpired(ctx, today, query.Query{})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2, 5}, pageIDs(page))
+
+	page, err = s.ListExpired(ctx, today, query.Query{Filter: query.Cmp{Field: "is_published", Op: query.OpEq, Value: true}})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, pageIDs(page))
+
+	page, err = s.ListExpiring(ctx, today, 7, query.Query{})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 4}, pageIDs(page))
+	page, _ = s.ListExpiring(ctx, today, 6, query.Query{})
+	assert.Equal(t, []int{3}, pageIDs(page))
+}
+
+func TestService_ExpireProducts(t *testing.T) {
+	ctx := context.Background()
+	s, storage := newExpirationService()
+
+	report, err := s.ExpireProducts(ctx, today, ExpireUnpublish)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, report.Expired)
+	p, _ := storage.GetOne(ctx, 1)
+	assert.False(t, p.IsPublished)
+	assert.Equal(t, "expired on 15/12/2021: unpublished automatically", p.LifecycleReason)
+	p, _ = storage.GetOne(ctx, 3)
+	assert.True(t, p.IsPublished)
+
+	// lo ya procesado no se vuelve a tocar
+	report, err = s.ExpireProducts(ctx, today, ExpireUnpublish)
+	assert.Nil(t, err)
+	assert.Empty(t, report.Expired)
+
+	// flag registra el motivo de los que quedaban, sin despublicar
+	report, err = s.ExpireProducts(ctx, today.AddDays(1), ExpireFlag)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 5}, report.Expired)
+	p, _ = storage.GetOne(ctx, 3)
+	assert.True(t, p.IsPublished)
+	assert.Equal(t, "expired on 10/03/2024", p.LifecycleReason)
+
+	// una fecha nueva borra el motivo
+	p, err = s.Update(ctx, 1, domain.Product{Expiration: today.AddDays(365)})
+	assert.Nil(t, err)
+	assert.Empty(t, p.LifecycleReason)
+}
+
+// writeCounter cuenta las escrituras que llegan al repositorio
+type writeCounter struct {
+	Repository
+	updates, batches int
+}
+
+func (r *writeCounter) Update(ctx context.Context, id int, p domain.Product) (domain.Product, error) {
+	r.updates++
+	return r.Repository.Update(ctx, id, p)
+}
+
+func (r *writeCounter) Apply(ctx context.Context, changes []store.Change, atomic bool) ([]store.ChangeResult, error) {
+	r.batches++
+	return r.Repository.Apply(ctx, changes, atomic)
+}
+
+func TestService_ExpireProductsInOneWrite(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "old", CodeValue: "A", IsPublished: true, Expiration: today.AddDays(-3)},
+		{Id: 2, Name: "yesterday", CodeValue: "B", IsPublished: true, Expiration: today.AddDays(-1)},
+		{Id: 3, Name: "changing", CodeValue: "C", IsPublished: true, Expiration: today.AddDays(-1)},
+		// un code_value repetido en los datos hace fallar solo ese producto
+		{Id: 4, Name: "duplicated", CodeValue: "D", IsPublished: true, Expiration: today.AddDays(-1)},
+		{Id: 5, Name: "fresh", CodeValue: "D", IsPublished: true, Expiration: today.AddDays(10)},
+	})
+	repo := NewRepository(storage)
+	counter := &writeCounter{Repository: racingRepository{Repository: repo, beforeWrite: func() {
+		p, err := repo.GetByID(ctx, 3)
+		assert.Nil(t, err)
+		p.Name = "changed"
+		_, err = repo.Update(ctx, 3, p)
+		assert.Nil(t, err)
+	}}}
+	s := NewService(counter)
+
+	report, err := s.ExpireProducts(ctx, today, ExpireUnpublish)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, counter.batches)
+	assert.Equal(t, 0, counter.updates)
+	assert.Equal(t, []int{1, 2}, report.Expired)
+	assert.Equal(t, []int{3}, report.Skipped)
+	assert.Equal(t, []ExpireFailure{{Id: 4, Error: "code value already exists"}}, report.Failed)
+	p, _ := storage.GetOne(ctx, 3)
+	assert.True(t, p.IsPublished)
+}
+
+func TestRunExpirationJob(t *testing.T) {
+	s, storage := newExpirationService()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunExpirationJob(ctx, s, 10*time.Millisecond, ExpireUnpublish)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		p, _ := storage.GetOne(context.Background(), 1)
+		return !p.IsPublished
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestParseExpireAction(t *testing.T) {
+	action, err := ParseExpireAction("")
+	assert.Nil(t, err)
+	assert.Equal(t, ExpireUnpublish, action)
+	action, err = ParseExpireAction("flag")
+	assert.Nil(t, err)
+	assert.Equal(t, ExpireFlag, action)
+	_, err = ParseExpireAction("delete")
+	assert.NotNil(t, err)
+}
@@ -1,19 +1,40 @@
 package product
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
 	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
 	"github.com/fgiudicatti-meli/web-server/pkg/store"
 )
 
+// ErrVersionMismatch indica que el producto fue modificado desde la version
+// que conocia quien pide el cambio
+var ErrVersionMismatch = domain.NewError(domain.KindPrecondition, "version_mismatch", "product was modified by another request")
+
+// ErrAtomicUnsupported indica un lote atomico sobre un store que no sabe
+// aplicar lotes
+var ErrAtomicUnsupported = domain.Invalid("atomic_unsupported", "store doesn't support atomic batches")
+
+// eachPageSize es cuantos productos lee Each por vez de un store que sabe
+// paginar
+const eachPageSize = 500
+
 type Repository interface {
-	GetAll() []domain.Product
-	GetByID(id int) (domain.Product, error)
-	SearchPriceGt(price float64) []domain.Product
-	Create(p domain.Product) (domain.Product, error)
-	Update(id int, p domain.Product) (domain.Product, error)
-	Delete(id int) error
+	GetAll(ctx context.Context) ([]domain.Product, error)
+	List(ctx context.Context, q query.Query) (query.Page, error)
+	Each(ctx context.Context, q query.Query, fn func(p domain.Product) error) error
+	GetByID(ctx context.Context, id int) (domain.Product, error)
+	Create(ctx context.Context, p domain.Product) (domain.Product, error)
+	Update(ctx context.Context, id int, p domain.Product) (domain.Product, error)
+	// Delete elimina el producto id solo si sigue en version; si no,
+	// devuelve ErrVersionMismatch
+	Delete(ctx context.Context, id, version int) error
+	// Apply aplica un lote de cambios; ver store.Batcher
+	Apply(ctx context.Context, changes []store.Change, atomic bool) ([]store.ChangeResult, error)
+	Revision(ctx context.Context) (store.Revision, error)
 }
 
 type repository struct {
@@ -26,86 +47,187 @@ func NewRepository(storage store.Store) Repository {
 }
 
 // GetAll devuelve todos los productos
-func (r *repository) GetAll() []domain.Product {
-	products, err := r.storage.GetAll()
+func (r *repository) GetAll(ctx context.Context) ([]domain.Product, error) {
+	products, err := r.storage.GetAll(ctx)
 	if err != nil {
-		return []domain.Product{}
+		return nil, err
 	}
-	return products
+	return products, nil
 }
 
-// GetByID busca un producto por su id
-func (r *repository) GetByID(id int) (domain.Product, error) {
-	product, err := r.storage.GetOne(id)
+// List devuelve una pagina de los productos que cumplen el filtro de q; si
+// el store sabe ejecutar la query se la delega, si no se resuelve en memoria
+func (r *repository) List(ctx context.Context, q query.Query) (query.Page, error) {
+	if lister, ok := r.storage.(store.Lister); ok {
+		return lister.List(ctx, q)
+	}
+	products, err := r.storage.GetAll(ctx)
 	if err != nil {
-		return domain.Product{}, errors.New("product not found")
+		return query.Page{}, err
 	}
-	return product, nil
-
+	return query.Apply(products, q)
 }
 
-// SearchPriceGt busca productos por precio mayor o igual que el precio dado
-func (r *repository) SearchPriceGt(price float64) []domain.Product {
-	var products []domain.Product
-	list, err := r.storage.GetAll()
-	if err != nil {
-		return products
+// Each llama a fn con cada producto que cumple el filtro de q, en el orden
+// de q; limit, offset y cursor no se usan. Si el store sabe paginar se lee
+// de a una pagina por vez, sin tener todo en memoria ni retener la conexion
+// entre paginas; si no, se ordena en memoria lo que devuelve GetAll. Un
+// error de fn corta el recorrido y se devuelve.
+func (r *repository) Each(ctx context.Context, q query.Query, fn func(p domain.Product) error) error {
+	q.Limit, q.Offset, q.Cursor = 0, 0, ""
+	lister, ok := r.storage.(store.Lister)
+	if !ok {
+		products, err := r.storage.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		page, err := query.Apply(products, q)
+		if err != nil {
+			return err
+		}
+		return each(page.Items, fn)
 	}
-	for _, product := range list {
-		if product.Price > price {
-			products = append(products, product)
+	q.Limit = eachPageSize
+	for {
+		page, err := lister.List(ctx, q)
+		if err != nil {
+			return err
+		}
+		if err := each(page.Items, fn); err != nil {
+			return err
+		}
+		if page.NextCursor == "" {
+			return nil
 		}
+		q.Cursor = page.NextCursor
 	}
-	return products
 }
 
-// Create agrega un nuevo producto
-func (r *repository) Create(p domain.Product) (domain.Product, error) {
-	if !r.validateCodeValue(0, p.CodeValue) {
-		return domain.Product{}, errors.New("code value already exists")
+func each(products []domain.Product, fn func(p domain.Product) error) error {
+	for _, p := range products {
+		if err := fn(p); err != nil {
+			return err
+		}
 	}
-	err := r.storage.AddOne(p)
+	return nil
+}
+
+// GetByID busca un producto por su id
+func (r *repository) GetByID(ctx context.Context, id int) (domain.Product, error) {
+	product, err := r.storage.GetOne(ctx, id)
 	if err != nil {
-		return domain.Product{}, errors.New("error creating product")
+		return domain.Product{}, err
 	}
-	list, err := r.storage.GetAll()
-	if err != nil {
+	return product, nil
+
+}
+
+// Create agrega un nuevo producto
+func (r *repository) Create(ctx context.Context, p domain.Product) (domain.Product, error) {
+	if err := r.validateCodeValue(ctx, 0, p.CodeValue); err != nil {
 		return domain.Product{}, err
 	}
-	return list[len(list)-1], nil
+	p, err := r.storage.AddOne(ctx, p)
+	if err != nil {
+		return domain.Product{}, fmt.Errorf("error creating product: %w", err)
+	}
+	return p, nil
 }
 
-// validateCodeValue valida que el codigo no exista en la lista de productos
-func (r *repository) validateCodeValue(id int, codeValue string) bool {
-	list, err := r.storage.GetAll()
+// validateCodeValue valida que el codigo no exista en la lista de productos;
+// si ya existe devuelve store.ErrDuplicate con el detalle del campo
+func (r *repository) validateCodeValue(ctx context.Context, id int, codeValue string) error {
+	list, err := r.storage.GetAll(ctx)
 	if err != nil {
-		return false
+		return err
 	}
 	for _, product := range list {
 		if product.CodeValue == codeValue && product.Id != id {
-			return false
+			return store.ErrDuplicate.WithFields(domain.FieldError{Field: "code_value", Code: "taken", Message: "code_value already exists"})
 		}
 	}
-	return true
+	return nil
 }
 
-// Delete elimina un producto
-func (r *repository) Delete(id int) error {
-	err := r.storage.DeleteOne(id)
+// Delete elimina un producto si sigue en version, con un lote de un solo
+// cambio para que el store compare la version en la misma escritura
+func (r *repository) Delete(ctx context.Context, id, version int) error {
+	results, err := r.Apply(ctx, []store.Change{{Op: store.OpDelete, Id: id, Version: version}}, false)
 	if err != nil {
 		return err
 	}
-	return nil
+	return results[0].Err
 }
 
 // Update actualiza un producto
-func (r *repository) Update(id int, p domain.Product) (domain.Product, error) {
-	if !r.validateCodeValue(id, p.CodeValue) {
-		return domain.Product{}, errors.New("code value already exists")
+func (r *repository) Update(ctx context.Context, id int, p domain.Product) (domain.Product, error) {
+	if err := r.validateCodeValue(ctx, id, p.CodeValue); err != nil {
+		return domain.Product{}, err
+	}
+	p, err := r.storage.UpdateOne(ctx, p)
+	if errors.Is(err, store.ErrVersionConflict) {
+		return domain.Product{}, ErrVersionMismatch
 	}
-	err := r.storage.UpdateOne(p)
 	if err != nil {
-		return domain.Product{}, errors.New("error updating product")
+		return domain.Product{}, fmt.Errorf("error updating product: %w", err)
 	}
 	return p, nil
 }
+
+// Apply aplica el lote en una sola escritura si el store lo soporta; si no,
+// cambio por cambio, lo que no permite el modo atomico
+func (r *repository) Apply(ctx context.Context, changes []store.Change, atomic bool) ([]store.ChangeResult, error) {
+	var results []store.ChangeResult
+	if batcher, ok := r.storage.(store.Batcher); ok {
+		var err error
+		if results, err = batcher.Apply(ctx, changes, atomic); err != nil {
+			return nil, err
+		}
+	} else {
+		if atomic {
+			return nil, ErrAtomicUnsupported
+		}
+		results = make([]store.ChangeResult, len(changes))
+		for i, c := range changes {
+			results[i] = r.applyOne(ctx, c)
+		}
+	}
+	for i := range results {
+		if errors.Is(results[i].Err, store.ErrVersionConflict) {
+			results[i].Err = ErrVersionMismatch
+		}
+	}
+	return results, nil
+}
+
+// applyOne aplica un cambio con las operaciones de a uno del store
+func (r *repository) applyOne(ctx context.Context, c store.Change) store.ChangeResult {
+	var p domain.Product
+	var err error
+	switch c.Op {
+	case store.OpCreate:
+		p, err = r.storage.AddOne(ctx, c.Product)
+	case store.OpUpdate:
+		p, err = r.storage.UpdateOne(ctx, c.Product)
+	case store.OpDelete:
+		if p, err = r.storage.GetOne(ctx, c.Id); err != nil {
+			break
+		}
+		if p.Version != c.Version {
+			err = store.ErrVersionConflict
+			break
+		}
+		err = r.storage.DeleteOne(ctx, c.Id)
+	}
+	return store.ChangeResult{Product: p, Err: err}
+}
+
+// Revision devuelve la revision del store, o una Revision vacia si el
+// backend no sabe calcularla
+func (r *repository) Revision(ctx context.Context) (store.Revision, error) {
+	revisioner, ok := r.storage.(store.Revisioner)
+	if !ok {
+		return store.Revision{}, nil
+	}
+	return revisioner.Revision(ctx)
+}
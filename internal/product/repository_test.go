@@ -0,0 +1,100 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStore es un store.Store minimo escrito fuera de pkg/store
+type fakeStore struct {
+	added []domain.Product
+}
+
+func (f *fakeStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	return f.added, nil
+}
+
+func (f *fakeStore) GetOne(ctx context.Context, id int) (domain.Product, error) {
+	for _, p := range f.added {
+		if p.Id == id {
+			return p, nil
+		}
+	}
+	return domain.Product{}, store.ErrNotFound
+}
+
+func (f *fakeStore) AddOne(ctx context.Context, p domain.Product) (domain.Product, error) {
+	p.Id = 100 + len(f.added)
+	f.added = append(f.added, p)
+	return p, nil
+}
+
+func (f *fakeStore) UpdateOne(ctx context.Context, p domain.Product) (domain.Product, error) {
+	return domain.Product{}, store.ErrNotFound
+}
+
+func (f *fakeStore) DeleteOne(ctx context.Context, id int) error {
+	return store.ErrNotFound
+}
+
+func TestRepository_CreateWithFakeStore(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(&fakeStore{})
+
+	p, err := repo.Create(ctx, domain.Product{Name: "a", CodeValue: "A"})
+	assert.Nil(t, err)
+	assert.Equal(t, 100, p.Id)
+
+	_, err = repo.Create(ctx, domain.Product{Name: "b", CodeValue: "A"})
+	assert.NotNil(t, err)
+
+	assert.ErrorIs(t, repo.Delete(ctx, 5, 0), store.ErrNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, p.Id, p.Version+1), ErrVersionMismatch)
+}
+
+func TestRepository_Each(t *testing.T) {
+	ctx := context.Background()
+	products := make([]domain.Product, 1200)
+	for i := range products {
+		products[i] = domain.Product{Id: i + 1, Name: "p", CodeValue: strconv.Itoa(i + 1), Quantity: i % 7, Price: money.MustNew(int64(i), "ARS")}
+	}
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "products.db"))
+	assert.Nil(t, err)
+	assert.Nil(t, db.(store.Importer).Import(ctx, products))
+
+	filter, err := query.ParseFilter("quantity >= 3")
+	assert.Nil(t, err)
+	q := query.Query{Filter: filter, Sort: []query.SortKey{{Field: "price", Desc: true}}, Limit: 10, Offset: 5}
+	// sqlite se recorre por paginas y el store en memoria de una vez; el
+	// resultado es el mismo y no depende de limit ni offset
+	for _, s := range []store.Store{db, store.NewMemoryStore(products)} {
+		var ids []int
+		err := NewRepository(s).Each(ctx, q, func(p domain.Product) error {
+			ids = append(ids, p.Id)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Len(t, ids, 684)
+		assert.Equal(t, []int{1197, 1196, 1195}, ids[:3])
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = NewRepository(db).Each(ctx, query.Query{}, func(p domain.Product) error {
+		if n++; n == 600 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 600, n)
+}
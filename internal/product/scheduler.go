@@ -0,0 +1,30 @@
+package product
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+// RunExpirationJob ejecuta ExpireProducts al arrancar y luego cada interval,
+// hasta que se cancele ctx
+func RunExpirationJob(ctx context.Context, s Service, interval time.Duration, action ExpireAction) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := s.ExpireProducts(ctx, domain.Today(), action)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("expiration job: %v", err)
+		case len(report.Expired) > 0 || len(report.Skipped) > 0 || len(report.Failed) > 0:
+			log.Printf("expiration job: %s %d products %v, skipped %v, failed %v", action, len(report.Expired), report.Expired, report.Skipped, report.Failed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package product
+
+import (
+	"context"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/search"
+)
+
+// Match es un producto encontrado por SearchText y su relevancia
+type Match struct {
+	domain.Product
+	Score float64 `json:"score"`
+}
+
+// SearchText busca productos por las palabras de su nombre, tolerando
+// acentos, prefijos y errores de tipeo. Los resultados vienen ordenados del
+// mas relevante al menos relevante.
+func (s *service) SearchText(ctx context.Context, text string) ([]Match, error) {
+	if err := s.syncIndex(ctx); err != nil {
+		return nil, err
+	}
+	results := s.index.Search(text)
+	matches := make([]Match, 0, len(results))
+	if len(results) == 0 {
+		return matches, nil
+	}
+	// una sola lectura del store: en el backend json cada GetByID lee y
+	// parsea el archivo entero
+	products, err := s.r.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]domain.Product, len(products))
+	for _, p := range products {
+		byID[p.Id] = p
+	}
+	for _, r := range results {
+		p, ok := byID[r.Id]
+		if !ok {
+			// se borro despues de consultar el indice
+			continue
+		}
+		matches = append(matches, Match{Product: p, Score: r.Score})
+	}
+	return matches, nil
+}
+
+// syncIndex arma el indice la primera vez y lo vuelve a armar si el store
+// cambio por fuera de este servicio (otro proceso sobre el mismo archivo)
+func (s *service) syncIndex(ctx context.Context) error {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	rev, err := s.r.Revision(ctx)
+	if err != nil {
+		return err
+	}
+	if s.indexBuilt && (rev.Tag == "" || rev.Tag == s.indexedTag) {
+		return nil
+	}
+	products, err := s.r.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	s.index.Build(products)
+	s.indexBuilt = true
+	s.indexedTag = rev.Tag
+	return nil
+}
+
+// revisionTag devuelve la revision actual del store, vacia si no se conoce
+func (s *service) revisionTag(ctx context.Context) string {
+	rev, _ := s.r.Revision(ctx)
+	return rev.Tag
+}
+
+// updateIndex aplica al indice una escritura hecha por este servicio. before
+// es la revision previa a la escritura: si coincide con la indexada, el
+// indice queda al dia con la revision nueva sin tener que rearmarlo.
+func (s *service) updateIndex(ctx context.Context, before string, apply func(ix *search.Index)) {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	if !s.indexBuilt {
+		return
+	}
+	apply(s.index)
+	if before == s.indexedTag {
+		s.indexedTag = s.revisionTag(ctx)
+	}
+}
@@ -0,0 +1,88 @@
+package product
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func matchIDs(matches []Match) []int {
+	ids := []int{}
+	for _, m := range matches {
+		ids = append(ids, m.Id)
+	}
+	return ids
+}
+
+func TestService_SearchTextFollowsWrites(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "Cake - Lemon Chiffon", CodeValue: "A"},
+		{Id: 2, Name: "Tea - Vanilla Chai", CodeValue: "B"},
+	})
+	s := NewService(NewRepository(storage))
+
+	matches, err := s.SearchText(ctx, "vanila")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2}, matchIDs(matches))
+
+	p, err := s.Create(ctx, domain.Product{Name: "Vanilla Sponge Cake", CodeValue: "C"})
+	assert.Nil(t, err)
+	matches, _ = s.SearchText(ctx, "vanilla")
+	assert.ElementsMatch(t, []int{2, p.Id}, matchIDs(matches))
+
+	_, err = s.Update(ctx, 2, domain.Product{Name: "Tea - Green"})
+	assert.Nil(t, err)
+	matches, _ = s.SearchText(ctx, "vanilla")
+	assert.Equal(t, []int{p.Id}, matchIDs(matches))
+	assert.Equal(t, "Vanilla Sponge Cake", matches[0].Name)
+
+	assert.Nil(t, s.Delete(ctx, p.Id, p.Version))
+	matches, _ = s.SearchText(ctx, "vanilla")
+	assert.Empty(t, matches)
+
+	// una escritura que no paso por el servicio se ve al rearmar el indice
+	_, err = storage.AddOne(ctx, domain.Product{Name: "Vanilla Ice Cream", CodeValue: "D"})
+	assert.Nil(t, err)
+	matches, _ = s.SearchText(ctx, "vanilla")
+	assert.Len(t, matches, 1)
+}
+
+// countingStore cuenta las lecturas que llegan al store
+type countingStore struct {
+	store.Store
+	getAll, getOne int
+}
+
+func (s *countingStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	s.getAll++
+	return s.Store.GetAll(ctx)
+}
+
+func (s *countingStore) GetOne(ctx context.Context, id int) (domain.Product, error) {
+	s.getOne++
+	return s.Store.GetOne(ctx, id)
+}
+
+func TestService_SearchTextReadsStoreOnce(t *testing.T) {
+	ctx := context.Background()
+	products := make([]domain.Product, 50)
+	for i := range products {
+		products[i] = domain.Product{Id: i + 1, Name: "Tea - Green", CodeValue: strconv.Itoa(i + 1)}
+	}
+	storage := &countingStore{Store: store.NewMemoryStore(products)}
+	s := NewService(NewRepository(storage))
+	_, err := s.SearchText(ctx, "tea")
+	assert.Nil(t, err)
+
+	storage.getAll, storage.getOne = 0, 0
+	matches, err := s.SearchText(ctx, "tea")
+	assert.Nil(t, err)
+	assert.Len(t, matches, 50)
+	assert.Equal(t, 1, storage.getAll)
+	assert.Equal(t, 0, storage.getOne)
+}
@@ -1,95 +1,193 @@
 package product
 
 import (
-	"errors"
+	"context"
+	"sync"
 
 	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/internal/search"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
 )
 
+// ErrBelowReserved indica un cambio de stock que deja menos unidades que las
+// reservadas por ordenes pendientes
+var ErrBelowReserved = domain.Conflict("below_reserved", "quantity can't be lower than the reserved units")
+
+// ErrReserved indica que el producto tiene unidades reservadas por ordenes
+// pendientes y no se puede eliminar
+var ErrReserved = domain.Conflict("product_reserved", "product has reserved units")
+
 type Service interface {
-	GetAll() ([]domain.Product, error)
-	GetByID(id int) (domain.Product, error)
-	SearchPriceGt(price float64) ([]domain.Product, error)
-	Create(p domain.Product) (domain.Product, error)
-	Update(id int, p domain.Product) (domain.Product, error)
-	Delete(id int) error
+	GetAll(ctx context.Context) ([]domain.Product, error)
+	List(ctx context.Context, q query.Query) (query.Page, error)
+	Each(ctx context.Context, q query.Query, fn func(p domain.Product) error) error
+	GetByID(ctx context.Context, id int) (domain.Product, error)
+	Create(ctx context.Context, p domain.Product) (domain.Product, error)
+	Update(ctx context.Context, id int, p domain.Product) (domain.Product, error)
+	Replace(ctx context.Context, id int, p domain.Product) (domain.Product, error)
+	Delete(ctx context.Context, id, version int) error
+	Revision(ctx context.Context) (store.Revision, error)
+	SearchText(ctx context.Context, text string) ([]Match, error)
+	ListExpired(ctx context.Context, today domain.Date, q query.Query) (query.Page, error)
+	ListExpiring(ctx context.Context, today domain.Date, days int, q query.Query) (query.Page, error)
+	ExpireProducts(ctx context.Context, today domain.Date, action ExpireAction) (ExpireReport, error)
+	CreateMany(ctx context.Context, products []domain.Product, atomic bool) ([]BulkResult, error)
+	UpdateMany(ctx context.Context, updates []domain.Product, atomic bool) ([]BulkResult, error)
+	DeleteMany(ctx context.Context, ids []int, atomic bool) ([]BulkResult, error)
 }
 
 type service struct {
 	r Repository
+
+	// index se arma en la primera busqueda por texto y se mantiene con cada
+	// alta, modificacion o baja; indexedTag es la revision que refleja
+	indexMu    sync.Mutex
+	index      *search.Index
+	indexBuilt bool
+	indexedTag string
+
+	// expireMu evita que el job de vencimientos y un disparo manual se pisen
+	expireMu sync.Mutex
 }
 
 // NewService crea un nuevo servicio
 func NewService(r Repository) Service {
-	return &service{r}
+	return &service{r: r, index: search.NewIndex()}
 }
 
 // GetAll devuelve todos los productos
-func (s *service) GetAll() ([]domain.Product, error) {
-	l := s.r.GetAll()
-	return l, nil
+func (s *service) GetAll(ctx context.Context) ([]domain.Product, error) {
+	return s.r.GetAll(ctx)
+}
+
+// List devuelve una pagina de productos filtrada y ordenada segun q
+func (s *service) List(ctx context.Context, q query.Query) (query.Page, error) {
+	return s.r.List(ctx, q)
+}
+
+// Each recorre los productos que cumplen el filtro de q sin armar una
+// pagina; ver Repository.Each
+func (s *service) Each(ctx context.Context, q query.Query, fn func(p domain.Product) error) error {
+	return s.r.Each(ctx, q, fn)
 }
 
 // GetByID busca un producto por su id
-func (s *service) GetByID(id int) (domain.Product, error) {
-	p, err := s.r.GetByID(id)
+func (s *service) GetByID(ctx context.Context, id int) (domain.Product, error) {
+	p, err := s.r.GetByID(ctx, id)
 	if err != nil {
 		return domain.Product{}, err
 	}
 	return p, nil
 }
 
-// SearchPriceGt busca productos por precio mayor que el precio dado
-func (s *service) SearchPriceGt(price float64) ([]domain.Product, error) {
-	l := s.r.SearchPriceGt(price)
-	if len(l) == 0 {
-		return []domain.Product{}, errors.New("no products found")
-	}
-	return l, nil
-}
-
 // Create agrega un nuevo producto
-func (s *service) Create(p domain.Product) (domain.Product, error) {
-	p, err := s.r.Create(p)
+func (s *service) Create(ctx context.Context, p domain.Product) (domain.Product, error) {
+	// solo el job de vencimientos completa el motivo y solo las ordenes
+	// reservan stock
+	p.LifecycleReason = ""
+	p.Reserved = 0
+	before := s.revisionTag(ctx)
+	p, err := s.r.Create(ctx, p)
 	if err != nil {
 		return domain.Product{}, err
 	}
+	s.updateIndex(ctx, before, func(ix *search.Index) { ix.Add(p) })
 	return p, nil
 }
 
-// Delete elimina un producto
-func (s *service) Delete(id int) error {
-	err := s.r.Delete(id)
-	if err != nil {
+// Delete elimina un producto solo si sigue en version y no tiene reservas
+func (s *service) Delete(ctx context.Context, id, version int) error {
+	p, err := s.r.GetByID(ctx, id)
+	switch {
+	case err != nil:
+		return err
+	case p.Version != version:
+		return ErrVersionMismatch
+	case p.Reserved > 0:
+		return ErrReserved
+	}
+	before := s.revisionTag(ctx)
+	if err := s.r.Delete(ctx, id, version); err != nil {
 		return err
 	}
+	s.updateIndex(ctx, before, func(ix *search.Index) { ix.Remove(id) })
 	return nil
 }
 
-// Update actualiza un producto
-func (s *service) Update(id int, u domain.Product) (domain.Product, error) {
-	p, err := s.r.GetByID(id)
+// Update actualiza un producto; un u.Version distinto de 0 es precondicion
+func (s *service) Update(ctx context.Context, id int, u domain.Product) (domain.Product, error) {
+	p, err := s.r.GetByID(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if p, err = merge(p, u); err != nil {
+		return domain.Product{}, err
+	}
+	before := s.revisionTag(ctx)
+	p, err = s.r.Update(ctx, id, p)
 	if err != nil {
 		return domain.Product{}, err
 	}
+	s.updateIndex(ctx, before, func(ix *search.Index) { ix.Add(p) })
+	return p, nil
+}
+
+// Replace guarda p tal cual como el nuevo estado del producto id
+func (s *service) Replace(ctx context.Context, id int, p domain.Product) (domain.Product, error) {
+	current, err := s.r.GetByID(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if p.Version != current.Version {
+		return domain.Product{}, ErrVersionMismatch
+	}
+	p.Id, p.Reserved, p.LifecycleReason = id, current.Reserved, current.LifecycleReason
+	if p.Expiration.Compare(current.Expiration) != 0 {
+		p.LifecycleReason = ""
+	}
+	if err := ValidateExisting(p); err != nil {
+		return domain.Product{}, err
+	}
+	before := s.revisionTag(ctx)
+	p, err = s.r.Update(ctx, id, p)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	s.updateIndex(ctx, before, func(ix *search.Index) { ix.Add(p) })
+	return p, nil
+}
+
+// merge aplica sobre p los campos no vacios de u
+func merge(p, u domain.Product) (domain.Product, error) {
+	if u.Version != 0 && u.Version != p.Version {
+		return domain.Product{}, ErrVersionMismatch
+	}
 	if u.Name != "" {
 		p.Name = u.Name
 	}
 	if u.CodeValue != "" {
 		p.CodeValue = u.CodeValue
 	}
-	if u.Expiration != "" {
+	if !u.Expiration.IsZero() && u.Expiration.Compare(p.Expiration) != 0 {
+		// con otra fecha el job de vencimientos lo vuelve a evaluar
 		p.Expiration = u.Expiration
+		p.LifecycleReason = ""
 	}
 	if u.Quantity > 0 {
+		if u.Quantity < p.Reserved {
+			return domain.Product{}, ErrBelowReserved
+		}
 		p.Quantity = u.Quantity
 	}
-	if u.Price > 0 {
+	if u.Price.IsPositive() {
 		p.Price = u.Price
 	}
-	p, err = s.r.Update(id, p)
-	if err != nil {
-		return domain.Product{}, err
-	}
 	return p, nil
 }
+
+// Revision devuelve la revision actual de los productos; Tag vacio si no se
+// conoce
+func (s *service) Revision(ctx context.Context) (store.Revision, error) {
+	return s.r.Revision(ctx)
+}
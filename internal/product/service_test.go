@@ -0,0 +1,119 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// racingRepository ejecuta beforeWrite justo antes de cada borrado o lote,
+// como un request que escribe entre la lectura del service y la escritura
+type racingRepository struct {
+	Repository
+	beforeWrite func()
+}
+
+func (r racingRepository) Delete(ctx context.Context, id, version int) error {
+	r.beforeWrite()
+	return r.Repository.Delete(ctx, id, version)
+}
+
+func (r racingRepository) Apply(ctx context.Context, changes []store.Change, atomic bool) ([]store.ChangeResult, error) {
+	r.beforeWrite()
+	return r.Repository.Apply(ctx, changes, atomic)
+}
+
+func TestService_DeleteVersion(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "a", CodeValue: "A", Quantity: 5},
+		{Id: 2, Name: "b", CodeValue: "B", Quantity: 5},
+	})
+	repo := NewRepository(storage)
+	s := NewService(racingRepository{Repository: repo, beforeWrite: func() {
+		_, err := repo.Update(ctx, 1, domain.Product{Id: 1, Name: "renamed", CodeValue: "A", Quantity: 5})
+		assert.Nil(t, err)
+	}})
+
+	assert.ErrorIs(t, s.Delete(ctx, 2, 3), ErrVersionMismatch)
+
+	// el cambio que entra despues de comparar la version tampoco se borra
+	assert.ErrorIs(t, s.Delete(ctx, 1, 0), ErrVersionMismatch)
+	p, err := storage.GetOne(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "renamed", p.Name)
+}
+
+func TestService_DeleteReserved(t *testing.T) {
+	ctx := context.Background()
+	storage := store.NewMemoryStore([]domain.Product{
+		{Id: 1, Name: "a", CodeValue: "A", Quantity: 5},
+		{Id: 2, Name: "b", CodeValue: "B", Quantity: 5, Reserved: 1},
+	})
+	repo := NewRepository(storage)
+	// una orden reserva unidades del producto 1 despues de que el service
+	// vio que no tenia reservas
+	reserve := func() {
+		p, err := repo.GetByID(ctx, 1)
+		assert.Nil(t, err)
+		if p.Reserved == 0 {
+			p.Reserved = 2
+			_, err = repo.Update(ctx, 1, p)
+			assert.Nil(t, err)
+		}
+	}
+	s := NewService(racingRepository{Repository: repo, beforeWrite: reserve})
+
+	assert.ErrorIs(t, s.Delete(ctx, 2, 0), ErrReserved)
+	assert.ErrorIs(t, s.Delete(ctx, 1, 0), ErrVersionMismatch)
+	p, err := storage.GetOne(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, p.Reserved)
+
+	p.Reserved = 0
+	_, err = storage.UpdateOne(ctx, p)
+	assert.Nil(t, err)
+	results, err := s.DeleteMany(ctx, []int{1, 2}, false)
+	assert.Nil(t, err)
+	assert.ErrorIs(t, results[0].Err, ErrVersionMismatch)
+	assert.ErrorIs(t, results[1].Err, ErrReserved)
+	_, err = storage.GetOne(ctx, 1)
+	assert.Nil(t, err)
+}
+
+// brokenStore falla las lecturas completas mientras broken es true, como un
+// products.json que no se puede leer
+type brokenStore struct {
+	store.Store
+	broken bool
+}
+
+var errBroken = errors.New("unexpected end of JSON input")
+
+func (s *brokenStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	if s.broken {
+		return nil, errBroken
+	}
+	return s.Store.GetAll(ctx)
+}
+
+func TestService_ReadErrors(t *testing.T) {
+	ctx := context.Background()
+	storage := &brokenStore{Store: store.NewMemoryStore([]domain.Product{{Id: 1, Name: "Tea - Vanilla Chai", CodeValue: "A"}}), broken: true}
+	s := NewService(NewRepository(storage))
+
+	_, err := s.GetAll(ctx)
+	assert.ErrorIs(t, err, errBroken)
+	// el indice no queda armado con una lectura fallida
+	_, err = s.SearchText(ctx, "vanilla")
+	assert.ErrorIs(t, err, errBroken)
+
+	storage.broken = false
+	matches, err := s.SearchText(ctx, "vanilla")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1}, matchIDs(matches))
+}
@@ -0,0 +1,123 @@
+package product
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/validate"
+)
+
+// ErrInvalidProduct indica un producto que no cumple las reglas de Validate
+// o ValidateExisting; el error trae el detalle de cada campo
+var ErrInvalidProduct = domain.Invalid("invalid_product", "invalid product")
+
+const (
+	maxNameLength      = 100
+	maxCodeValueLength = 32
+	// maxQuantity es una cota para detectar errores de carga, como un codigo
+	// de barras en la columna de cantidad
+	maxQuantity = 1_000_000
+	// maxExpirationYears limita cuan lejos en el futuro puede vencer un
+	// producto
+	maxExpirationYears = 100
+)
+
+// codeValuePattern son los code_value validos: letras, numeros y guiones,
+// sin espacios ni comillas que rompan los filtros y los csv
+var codeValuePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+// minExpiration es la fecha de vencimiento mas antigua que se acepta
+var minExpiration = domain.NewDate(1900, 1, 1)
+
+func name(p domain.Product) string            { return p.Name }
+func codeValue(p domain.Product) string       { return p.CodeValue }
+func quantity(p domain.Product) int           { return p.Quantity }
+func price(p domain.Product) money.Money      { return p.Price }
+func expiration(p domain.Product) domain.Date { return p.Expiration }
+func whole(p domain.Product) domain.Product   { return p }
+
+var (
+	nameRule = validate.Field("name", name, validate.Required[string](), validate.MaxLen(maxNameLength))
+
+	codeValueRule = validate.Field("code_value", codeValue, validate.Required[string](), validate.MaxLen(maxCodeValueLength),
+		validate.Matches(codeValuePattern, "letters, digits and dashes"))
+
+	expirationRule = validate.Field("expiration", expiration,
+		validate.That("required", "is required", func(d domain.Date) bool { return !d.IsZero() }),
+		validate.That("too_early", "can't be before 1900", func(d domain.Date) bool { return !d.Before(minExpiration) }),
+		validate.That("too_late", fmt.Sprintf("can't be more than %d years ahead", maxExpirationYears), func(d domain.Date) bool {
+			return !d.After(domain.DateOf(domain.Today().Time().AddDate(maxExpirationYears, 0, 0)))
+		}))
+
+	priceRule = validate.Field("price", price, validate.That("not_positive", "must be greater than 0", money.Money.IsPositive))
+)
+
+// NewRules son las reglas de un producto nuevo (POST, PUT, altas en lote e
+// importacion): todos los campos obligatorios y al menos una unidad
+var NewRules = validate.Rules[domain.Product]{
+	nameRule,
+	validate.Field("quantity", quantity,
+		validate.That("not_positive", "must be greater than 0", func(n int) bool { return n > 0 }),
+		validate.Max(maxQuantity)),
+	codeValueRule,
+	expirationRule,
+	priceRule,
+}
+
+// ExistingRules son las reglas de un producto ya guardado (PATCH y cambios
+// en lote): la cantidad puede ser 0 porque las ventas agotan el stock, pero
+// no menor a lo reservado
+var ExistingRules = validate.Rules[domain.Product]{
+	nameRule,
+	validate.Field("quantity", quantity,
+		validate.That("negative", "can't be negative", func(n int) bool { return n >= 0 }),
+		validate.Max(maxQuantity)),
+	validate.Field("quantity", whole,
+		validate.That("below_reserved", "can't be lower than the reserved units", func(p domain.Product) bool { return p.Quantity >= p.Reserved })),
+	codeValueRule,
+	expirationRule,
+	priceRule,
+}
+
+// Validate aplica NewRules y devuelve ErrInvalidProduct con todos los campos
+// invalidos, o nil
+func Validate(p domain.Product) error {
+	return check(NewRules.Validate(p))
+}
+
+// ValidateExisting aplica ExistingRules al estado completo de un producto
+// guardado
+func ValidateExisting(p domain.Product) error {
+	return check(ExistingRules.Validate(p))
+}
+
+// ValidateChanges aplica ExistingRules solo a los campos no vacios de u,
+// que son los que cambia Update
+func ValidateChanges(u domain.Product) error {
+	var fields []string
+	if u.Name != "" {
+		fields = append(fields, "name")
+	}
+	if u.CodeValue != "" {
+		fields = append(fields, "code_value")
+	}
+	if u.Quantity != 0 {
+		fields = append(fields, "quantity")
+	}
+	if !u.Expiration.IsZero() {
+		fields = append(fields, "expiration")
+	}
+	if !u.Price.IsZero() {
+		fields = append(fields, "price")
+	}
+	return check(ExistingRules.Only(fields...).Validate(u))
+}
+
+func check(fields []domain.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return ErrInvalidProduct.WithFields(fields...)
+}
@@ -0,0 +1,62 @@
+package product
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	assert.ErrorIs(t, err, ErrInvalidProduct)
+	e, ok := domain.AsError(err)
+	assert.True(t, ok)
+	codes := map[string]string{}
+	for _, f := range e.Fields {
+		codes[f.Field] = f.Code
+	}
+	return codes
+}
+
+func TestValidate(t *testing.T) {
+	valid := domain.Product{Name: "Yerba", Quantity: 5, CodeValue: "YER-1", Expiration: domain.NewDate(2030, 1, 1), Price: money.MustNew(1000, "ARS")}
+	assert.Nil(t, Validate(valid))
+
+	// todas las violaciones juntas, una por campo
+	assert.Equal(t, map[string]string{"name": "required", "quantity": "not_positive", "code_value": "required", "expiration": "required", "price": "not_positive"},
+		fieldErrors(t, Validate(domain.Product{})))
+
+	p := valid
+	p.Name, p.CodeValue, p.Quantity = strings.Repeat("a", maxNameLength+1), "YER 1", maxQuantity+1
+	p.Expiration = domain.NewDate(1899, 12, 31)
+	assert.Equal(t, map[string]string{"name": "too_long", "quantity": "too_large", "code_value": "invalid_format", "expiration": "too_early"},
+		fieldErrors(t, Validate(p)))
+
+	p = valid
+	p.Expiration = domain.Today().AddDays(365 * (maxExpirationYears + 1))
+	assert.Equal(t, map[string]string{"expiration": "too_late"}, fieldErrors(t, Validate(p)))
+}
+
+func TestValidateExisting(t *testing.T) {
+	p := domain.Product{Name: "Yerba", CodeValue: "YER", Expiration: domain.NewDate(2030, 1, 1), Price: money.MustNew(1000, "ARS")}
+	assert.Nil(t, ValidateExisting(p))
+
+	p.Quantity = -1
+	assert.Equal(t, map[string]string{"quantity": "negative"}, fieldErrors(t, ValidateExisting(p)))
+	p.Quantity, p.Reserved = 2, 3
+	assert.Equal(t, map[string]string{"quantity": "below_reserved"}, fieldErrors(t, ValidateExisting(p)))
+}
+
+func TestValidateChanges(t *testing.T) {
+	// solo se validan los campos que cambian
+	assert.Nil(t, ValidateChanges(domain.Product{Name: "nuevo nombre"}))
+	assert.Equal(t, map[string]string{"quantity": "negative", "price": "not_positive"},
+		fieldErrors(t, ValidateChanges(domain.Product{Quantity: -1, Price: money.MustNew(-1, "ARS")})))
+	assert.Equal(t, map[string]string{"code_value": "invalid_format"}, fieldErrors(t, ValidateChanges(domain.Product{CodeValue: "a,b"})))
+}
@@ -0,0 +1,224 @@
+// Package search mantiene un indice invertido de los nombres de los
+// productos para buscarlos por palabras, prefijos o con errores de tipeo.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+const (
+	// pesos de cada forma de coincidir con una palabra del nombre; se
+	// multiplican por lo poco frecuente que es esa palabra
+	exactWeight  = 1.0
+	prefixWeight = 0.5
+	fuzzyWeight  = 0.6
+	// minPrefix es el largo minimo para buscar por prefijo
+	minPrefix = 2
+)
+
+// Result es un producto encontrado y su relevancia
+type Result struct {
+	Id    int
+	Score float64
+}
+
+// Index es un indice invertido de nombres de productos, seguro para uso
+// concurrente
+type Index struct {
+	mu sync.RWMutex
+	// postings guarda por cada palabra los productos que la tienen
+	postings map[string]map[int]struct{}
+	// docs guarda las palabras de cada producto para poder sacarlo
+	docs map[int][]string
+	// terms es el vocabulario ordenado; nil si hay que rearmarlo
+	terms []string
+}
+
+// NewIndex crea un indice vacio
+func NewIndex() *Index {
+	return &Index{postings: map[string]map[int]struct{}{}, docs: map[int][]string{}}
+}
+
+// Build reemplaza el contenido del indice por products
+func (ix *Index) Build(products []domain.Product) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.postings = map[string]map[int]struct{}{}
+	ix.docs = map[int][]string{}
+	ix.terms = nil
+	for _, p := range products {
+		ix.addLocked(p)
+	}
+}
+
+// Add indexa p, reemplazando lo que hubiera con su id
+func (ix *Index) Add(p domain.Product) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.removeLocked(p.Id)
+	ix.addLocked(p)
+}
+
+// Remove saca del indice el producto id
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.removeLocked(id)
+}
+
+// Len devuelve la cantidad de productos indexados
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+func (ix *Index) addLocked(p domain.Product) {
+	terms := unique(tokenize(p.Name))
+	ix.docs[p.Id] = terms
+	for _, t := range terms {
+		ids, ok := ix.postings[t]
+		if !ok {
+			ids = map[int]struct{}{}
+			ix.postings[t] = ids
+			ix.terms = nil
+		}
+		ids[p.Id] = struct{}{}
+	}
+}
+
+func (ix *Index) removeLocked(id int) {
+	for _, t := range ix.docs[id] {
+		delete(ix.postings[t], id)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+			ix.terms = nil
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// vocabulary devuelve el vocabulario ordenado, rearmandolo si hace falta
+func (ix *Index) vocabulary() []string {
+	ix.mu.RLock()
+	terms := ix.terms
+	ix.mu.RUnlock()
+	if terms != nil {
+		return terms
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.terms == nil {
+		ix.terms = make([]string, 0, len(ix.postings))
+		for t := range ix.postings {
+			ix.terms = append(ix.terms, t)
+		}
+		sort.Strings(ix.terms)
+	}
+	return ix.terms
+}
+
+// Search busca los productos cuyo nombre contiene todas las palabras de
+// text, ya sea completas, como prefijo o con pocos errores de tipeo. Los
+// resultados vienen ordenados por relevancia y luego por id.
+func (ix *Index) Search(text string) []Result {
+	words := unique(tokenize(text))
+	if len(words) == 0 {
+		return []Result{}
+	}
+	terms := ix.vocabulary()
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	total := float64(len(ix.docs))
+	var scores map[int]float64
+	for _, word := range words {
+		// mejor puntaje de esta palabra en cada producto
+		best := map[int]float64{}
+		for term, weight := range ix.matches(terms, word) {
+			ids := ix.postings[term]
+			if len(ids) == 0 {
+				// el vocabulario se leyo antes de tomar el lock
+				continue
+			}
+			idf := math.Log(1 + total/float64(len(ids)))
+			for id := range ids {
+				if s := weight * idf; s > best[id] {
+					best[id] = s
+				}
+			}
+		}
+		// un producto tiene que coincidir con todas las palabras
+		if scores == nil {
+			scores = best
+			continue
+		}
+		for id := range scores {
+			if s, ok := best[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{Id: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Id < results[j].Id
+	})
+	return results
+}
+
+// matches devuelve las palabras del vocabulario que coinciden con word y el
+// peso de cada coincidencia
+func (ix *Index) matches(terms []string, word string) map[string]float64 {
+	found := map[string]float64{}
+	if _, ok := ix.postings[word]; ok {
+		found[word] = exactWeight
+	}
+	if len(word) >= minPrefix {
+		// el vocabulario esta ordenado: los que empiezan con word son contiguos
+		for i := sort.SearchStrings(terms, word); i < len(terms) && strings.HasPrefix(terms[i], word); i++ {
+			if terms[i] != word {
+				// cuanto mas de la palabra se escribio, mas se parece
+				found[terms[i]] = prefixWeight * (1 + float64(len(word))/float64(len(terms[i])))
+			}
+		}
+	}
+	runes := []rune(word)
+	if limit := maxEdits(runes); limit > 0 {
+		for _, term := range terms {
+			if _, ok := found[term]; ok {
+				continue
+			}
+			if d := editDistance(runes, []rune(term), limit); d <= limit {
+				found[term] = fuzzyWeight / float64(d)
+			}
+		}
+	}
+	return found
+}
+
+// unique devuelve words sin repetidos, conservando el orden
+func unique(words []string) []string {
+	seen := map[string]bool{}
+	out := words[:0]
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+var catalogue = []domain.Product{
+	{Id: 1, Name: "Cake - Lemon Chiffon"},
+	{Id: 2, Name: "Flavouring Vanilla Artificial"},
+	{Id: 3, Name: "Tea - Vanilla Chai"},
+	{Id: 4, Name: "Crème Brûlée"},
+	{Id: 5, Name: "Cake Sheet Combo Party Pack"},
+	{Id: 6, Name: "Lemonade"},
+}
+
+func ids(results []Result) []int {
+	ids := []int{}
+	for _, r := range results {
+		ids = append(ids, r.Id)
+	}
+	return ids
+}
+
+func TestIndex_Search(t *testing.T) {
+	ix := NewIndex()
+	ix.Build(catalogue)
+
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"vanilla", []int{2, 3}},
+		{"VANILLA chai", []int{3}},
+		{"creme brulee", []int{4}},
+		{"crème", []int{4}},
+		{"flav", []int{2}},
+		{"vanila", []int{2, 3}},
+		{"artifical flavouring", []int{2}},
+		{"chiffno", []int{1}},
+		{"cake", []int{1, 5}},
+		{"tea cake", []int{}},
+		{"  -- ", []int{}},
+		{"te", []int{3}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ids(ix.Search(tt.text)), tt.text)
+	}
+}
+
+func TestIndex_ExactBeatsPrefixAndTypos(t *testing.T) {
+	ix := NewIndex()
+	ix.Build(catalogue)
+
+	// "lemon" es exacto en 1 y prefijo de "lemonade" en 6
+	results := ix.Search("lemon")
+	assert.Equal(t, []int{1, 6}, ids(results))
+	assert.Greater(t, results[0].Score, results[1].Score)
+}
+
+func TestIndex_AddRemove(t *testing.T) {
+	ix := NewIndex()
+	ix.Build(catalogue)
+
+	ix.Add(domain.Product{Id: 3, Name: "Tea - Green"})
+	assert.Equal(t, []int{2}, ids(ix.Search("vanilla")))
+	assert.Equal(t, []int{3}, ids(ix.Search("green")))
+
+	ix.Remove(2)
+	assert.Empty(t, ix.Search("vanilla"))
+	assert.Empty(t, ix.Search("flavouring"))
+	assert.Equal(t, len(catalogue)-1, ix.Len())
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"vanilla", "vanilla", 2, 0},
+		{"vanila", "vanilla", 2, 1},
+		{"chiffno", "chiffon", 2, 1},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 1, 2},
+		{"a", "abcdef", 2, 3},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, editDistance([]rune(tt.a), []rune(tt.b), tt.limit), tt.a+"/"+tt.b)
+	}
+}
@@ -0,0 +1,57 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonMoney es la forma de objeto de un importe en json
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON escribe los importes en la moneda por defecto como un numero
+// decimal exacto (555.99), compatible con los precios numericos de siempre,
+// y los de otras monedas como {"amount": 555.99, "currency": "USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.currency == "" || m.currency == DefaultCurrency() {
+		return []byte(m.Decimal()), nil
+	}
+	return json.Marshal(jsonMoney{Amount: json.Number(m.Decimal()), Currency: m.currency})
+}
+
+// UnmarshalJSON acepta un numero o un string en la moneda por defecto
+// ("555.99 USD" si el string trae la moneda) o un objeto con amount y
+// currency. Los decimales de mas se redondean con HalfEven.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+	text, currency := "", DefaultCurrency()
+	switch {
+	case len(data) > 0 && data[0] == '{':
+		var obj jsonMoney
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&obj); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		text, currency = obj.Amount.String(), obj.Currency
+	case len(data) > 0 && data[0] == '"':
+		if err := json.Unmarshal(data, &text); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	default:
+		text = string(data)
+	}
+	parsed, err := Parse(text, currency, HalfEven)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
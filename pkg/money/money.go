@@ -0,0 +1,343 @@
+// Package money representa importes de dinero en punto fijo: un entero de
+// unidades menores (centavos) y el codigo ISO 4217 de la moneda, para no
+// arrastrar los errores de redondeo de float64.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	// ErrInvalid indica un importe o una moneda que no se pudo interpretar
+	ErrInvalid = errors.New("invalid money")
+	// ErrCurrencyMismatch indica una operacion entre monedas distintas
+	ErrCurrencyMismatch = errors.New("currencies don't match")
+	// ErrOverflow indica un resultado que no entra en int64 unidades menores
+	ErrOverflow = errors.New("money amount overflow")
+)
+
+// Rounding es el modo de redondeo a la unidad menor de la moneda
+type Rounding int
+
+const (
+	// HalfEven redondea al par mas cercano en los empates (redondeo bancario)
+	HalfEven Rounding = iota
+	// HalfUp redondea los empates alejandose del cero
+	HalfUp
+)
+
+// ParseRounding interpreta "half-even" o "half-up"; "" es HalfEven
+func ParseRounding(s string) (Rounding, error) {
+	switch strings.ToLower(s) {
+	case "", "half-even":
+		return HalfEven, nil
+	case "half-up":
+		return HalfUp, nil
+	}
+	return 0, fmt.Errorf("%w: unknown rounding %q, must be half-even or half-up", ErrInvalid, s)
+}
+
+// exponents son los decimales de las monedas que no usan 2
+var exponents = map[string]int{
+	"CLP": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"PYG": 0,
+	"BHD": 3,
+	"KWD": 3,
+}
+
+// Exponent devuelve la cantidad de decimales de la moneda
+func Exponent(currency string) int {
+	if e, ok := exponents[currency]; ok {
+		return e
+	}
+	return 2
+}
+
+// defaultCurrency es la moneda de los importes que llegan sin moneda, como
+// los precios numericos de products.json
+var defaultCurrency atomic.Value
+
+func init() {
+	defaultCurrency.Store("ARS")
+}
+
+// SetDefaultCurrency cambia la moneda por defecto (ARS si no se configura)
+func SetDefaultCurrency(code string) error {
+	code, err := normalizeCurrency(code)
+	if err != nil {
+		return err
+	}
+	defaultCurrency.Store(code)
+	return nil
+}
+
+// DefaultCurrency devuelve la moneda por defecto
+func DefaultCurrency() string {
+	return defaultCurrency.Load().(string)
+}
+
+// normalizeCurrency valida un codigo de tres letras y lo pasa a mayusculas
+func normalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("%w: currency must be a 3 letter code, got %q", ErrInvalid, code)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("%w: currency must be a 3 letter code, got %q", ErrInvalid, code)
+		}
+	}
+	return code, nil
+}
+
+// Money es un importe en unidades menores de una moneda. El valor cero no
+// tiene moneda y se trata como cero en cualquier moneda.
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// of arma un Money; el cero queda siempre sin moneda para que un importe
+// cero sea igual a Money{} despues de pasar por json o por la base
+func of(amount int64, currency string) Money {
+	if amount == 0 {
+		return Money{}
+	}
+	return Money{amount: amount, currency: currency}
+}
+
+// New crea un importe de minor unidades menores de currency
+func New(minor int64, currency string) (Money, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return of(minor, currency), nil
+}
+
+// MustNew es New para importes conocidos al compilar; entra en panico si la
+// moneda es invalida
+func MustNew(minor int64, currency string) Money {
+	m, err := New(minor, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Parse interpreta un decimal como "555.99" o "-1e3", opcionalmente seguido
+// de la moneda ("555.99 USD"); sin moneda se usa currency. Los decimales de
+// mas se redondean con mode.
+func Parse(s, currency string, mode Rounding) (Money, error) {
+	s = strings.TrimSpace(s)
+	if fields := strings.Fields(s); len(fields) == 2 {
+		s, currency = fields[0], fields[1]
+	}
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.Trim(s, "0123456789.eE+-") != "" {
+		return Money{}, fmt.Errorf("%w: %q is not a decimal amount", ErrInvalid, s)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(Exponent(currency))), nil)
+	minor, err := round(r.Mul(r, new(big.Rat).SetInt(scale)), mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return of(minor, currency), nil
+}
+
+// FromFloat convierte un float64 de currency, redondeando con mode. Solo
+// sirve para leer datos viejos; los calculos se hacen con Money.
+func FromFloat(f float64, currency string, mode Rounding) (Money, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Money{}, fmt.Errorf("%w: %v", ErrInvalid, f)
+	}
+	return Parse(strconv.FormatFloat(f, 'f', -1, 64), currency, mode)
+}
+
+// round redondea r a un entero con mode
+func round(r *big.Rat, mode Rounding) (int64, error) {
+	num, den := r.Num(), r.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() != 0 {
+		// compara 2*|resto| con el divisor para saber si pasa de la mitad
+		twice := new(big.Int).Abs(rem)
+		twice.Lsh(twice, 1)
+		away := false
+		switch c := twice.Cmp(den); {
+		case c > 0:
+			away = true
+		case c == 0:
+			away = mode == HalfUp || quo.Bit(0) == 1
+		}
+		if away {
+			quo.Add(quo, big.NewInt(int64(num.Sign())))
+		}
+	}
+	if !quo.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return quo.Int64(), nil
+}
+
+// Amount devuelve el importe en unidades menores
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+// Currency devuelve el codigo de la moneda; "" para el valor cero
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// IsZero indica si el importe es cero
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+// IsPositive indica si el importe es mayor que cero
+func (m Money) IsPositive() bool {
+	return m.amount > 0
+}
+
+// Compare ordena por moneda y despues por importe: -1, 0 o 1. El cero cuenta
+// como de la moneda por defecto; no convierte entre monedas.
+func (m Money) Compare(o Money) int {
+	if c := strings.Compare(m.orderCurrency(), o.orderCurrency()); c != 0 {
+		return c
+	}
+	switch {
+	case m.amount < o.amount:
+		return -1
+	case m.amount > o.amount:
+		return 1
+	}
+	return 0
+}
+
+// Comparable indica si m y o son de la misma moneda, contando el cero como de
+// la moneda por defecto
+func (m Money) Comparable(o Money) bool {
+	return m.orderCurrency() == o.orderCurrency()
+}
+
+// orderCurrency devuelve la moneda de m, o la por defecto para el cero
+func (m Money) orderCurrency() string {
+	if m.currency == "" {
+		return DefaultCurrency()
+	}
+	return m.currency
+}
+
+// sameCurrency devuelve la moneda comun de m y o; el cero se adapta a la otra
+func sameCurrency(m, o Money) (string, error) {
+	switch {
+	case m.currency == o.currency || o.currency == "":
+		return m.currency, nil
+	case m.currency == "":
+		return o.currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, o.currency)
+}
+
+// Add suma dos importes de la misma moneda
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := sameCurrency(m, o)
+	if err != nil {
+		return Money{}, err
+	}
+	sum := m.amount + o.amount
+	if (sum > m.amount) != (o.amount > 0) {
+		return Money{}, ErrOverflow
+	}
+	return of(sum, currency), nil
+}
+
+// Sub resta dos importes de la misma moneda
+func (m Money) Sub(o Money) (Money, error) {
+	if o.amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(Money{amount: -o.amount, currency: o.currency})
+}
+
+// Mul multiplica el importe por una cantidad entera
+func (m Money) Mul(n int64) (Money, error) {
+	return m.MulRatio(n, 1, HalfEven)
+}
+
+// MulRatio multiplica el importe por num/den (por ejemplo 121/100 para un
+// recargo del 21%) y redondea con mode
+func (m Money) MulRatio(num, den int64, mode Rounding) (Money, error) {
+	if den == 0 {
+		return Money{}, fmt.Errorf("%w: zero denominator", ErrInvalid)
+	}
+	r := new(big.Rat).SetFrac(big.NewInt(m.amount), big.NewInt(1))
+	r.Mul(r, big.NewRat(num, den))
+	amount, err := round(r, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return of(amount, m.currency), nil
+}
+
+// Decimal devuelve el importe como decimal exacto, por ejemplo "555.99"
+func (m Money) Decimal() string {
+	exp := Exponent(m.currency)
+	if m.currency == "" {
+		exp = Exponent(DefaultCurrency())
+	}
+	sign := ""
+	amount := m.amount
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(uint64(absInt64(amount)), 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// absInt64 devuelve |n| sin desbordar en math.MinInt64
+func absInt64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// String devuelve el importe con su moneda, por ejemplo "555.99 ARS"
+func (m Money) String() string {
+	if m.currency == "" {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + m.currency
+}
+
+// Float64 devuelve una aproximacion del importe, para columnas viejas o
+// clientes que la necesiten; no se usa para calcular
+func (m Money) Float64() float64 {
+	f, _ := strconv.ParseFloat(m.Decimal(), 64)
+	return f
+}
+
+// Value guarda el importe en unidades menores
+func (m Money) Value() (driver.Value, error) {
+	return m.amount, nil
+}
@@ -0,0 +1,170 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in       string
+		currency string
+		mode     Rounding
+		want     Money
+	}{
+		{"555.99", "ARS", HalfEven, MustNew(55599, "ARS")},
+		{"0.1", "ars", HalfEven, MustNew(10, "ARS")},
+		{"-1e3", "ARS", HalfEven, MustNew(-100000, "ARS")},
+		{"12.5 USD", "ARS", HalfEven, MustNew(1250, "USD")},
+		{"1000", "JPY", HalfEven, MustNew(1000, "JPY")},
+		{"1.2345", "KWD", HalfEven, MustNew(1234, "KWD")},
+		// la mitad va al par con HalfEven y se aleja del cero con HalfUp
+		{"0.125", "ARS", HalfEven, MustNew(12, "ARS")},
+		{"0.135", "ARS", HalfEven, MustNew(14, "ARS")},
+		{"0.125", "ARS", HalfUp, MustNew(13, "ARS")},
+		{"-0.125", "ARS", HalfUp, MustNew(-13, "ARS")},
+		{"0.1251", "ARS", HalfEven, MustNew(13, "ARS")},
+		{"0", "ARS", HalfEven, Money{}},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.in, c.currency, c.mode)
+		assert.Nil(t, err, c.in)
+		assert.Equal(t, c.want, got, c.in)
+	}
+
+	for _, in := range []string{"", "abc", "1,5", "0x10", "1/3", "1 DOLLARS"} {
+		_, err := Parse(in, "ARS", HalfEven)
+		assert.ErrorIs(t, err, ErrInvalid, in)
+	}
+	_, err := Parse("1e30", "ARS", HalfEven)
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestFromFloat(t *testing.T) {
+	// 0.1 + 0.2 en float64 es 0.30000000000000004
+	m, err := FromFloat(0.1+0.2, "ARS", HalfEven)
+	assert.Nil(t, err)
+	assert.Equal(t, MustNew(30, "ARS"), m)
+
+	_, err = FromFloat(math.NaN(), "ARS", HalfEven)
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a, b := MustNew(1010, "ARS"), MustNew(2020, "ARS")
+	sum, err := a.Add(b)
+	assert.Nil(t, err)
+	assert.Equal(t, "30.30 ARS", sum.String())
+
+	diff, err := a.Sub(b)
+	assert.Nil(t, err)
+	assert.Equal(t, "-10.10", diff.Decimal())
+
+	// el cero se adapta a cualquier moneda
+	sum, err = Money{}.Add(MustNew(5, "USD"))
+	assert.Nil(t, err)
+	assert.Equal(t, MustNew(5, "USD"), sum)
+
+	_, err = a.Add(MustNew(1, "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = MustNew(math.MaxInt64, "ARS").Add(MustNew(1, "ARS"))
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = MustNew(-1, "ARS").Sub(MustNew(math.MinInt64, "ARS"))
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	// 1263.23 * 1.21 = 1528.5083
+	total, err := MustNew(126323, "ARS").MulRatio(121, 100, HalfEven)
+	assert.Nil(t, err)
+	assert.Equal(t, "1528.51", total.Decimal())
+	total, err = MustNew(25, "ARS").MulRatio(1, 10, HalfEven)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total.Amount())
+	total, err = MustNew(25, "ARS").MulRatio(1, 10, HalfUp)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), total.Amount())
+	_, err = a.MulRatio(1, 0, HalfEven)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	triple, err := a.Mul(3)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3030), triple.Amount())
+}
+
+func TestMoney_Compare(t *testing.T) {
+	// por moneda y despues por importe, con el cero en la moneda por defecto
+	ordered := []Money{MustNew(-500, "ARS"), {}, MustNew(100, "ARS"), MustNew(-1, "USD"), MustNew(50, "USD")}
+	for i := range ordered {
+		for j := range ordered {
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			assert.Equal(t, want, ordered[i].Compare(ordered[j]), "%s vs %s", ordered[i], ordered[j])
+		}
+	}
+	assert.True(t, Money{}.Comparable(MustNew(1, "ARS")))
+	assert.False(t, MustNew(1, "ARS").Comparable(MustNew(1, "USD")))
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	assert.Equal(t, "0.05", MustNew(5, "ARS").Decimal())
+	assert.Equal(t, "-0.05", MustNew(-5, "ARS").Decimal())
+	assert.Equal(t, "0.00", Money{}.Decimal())
+	assert.Equal(t, "1500", MustNew(1500, "CLP").Decimal())
+	assert.Equal(t, "1.500", MustNew(1500, "BHD").Decimal())
+	assert.Equal(t, "-92233720368547758.08", MustNew(math.MinInt64, "ARS").Decimal())
+}
+
+func TestMoney_JSON(t *testing.T) {
+	type product struct {
+		Price Money `json:"price"`
+	}
+	for in, want := range map[string]Money{
+		`{"price": 555.99}`:                                MustNew(55599, "ARS"),
+		`{"price": "555.99"}`:                              MustNew(55599, "ARS"),
+		`{"price": "12 USD"}`:                              MustNew(1200, "USD"),
+		`{"price": {"amount": 12.5, "currency": "USD"}}`:   MustNew(1250, "USD"),
+		`{"price": 0.125}`:                                 MustNew(12, "ARS"),
+		`{"price": null}`:                                  {},
+		`{"price": 0}`:                                     {},
+		`{"price": 35.9000000000000000000000000000000001}`: MustNew(3590, "ARS"),
+	} {
+		var p product
+		assert.Nil(t, json.Unmarshal([]byte(in), &p), in)
+		assert.Equal(t, want, p.Price, in)
+
+		out, err := json.Marshal(p)
+		assert.Nil(t, err)
+		var back product
+		assert.Nil(t, json.Unmarshal(out, &back))
+		assert.Equal(t, want, back.Price, string(out))
+	}
+
+	out, err := json.Marshal(product{MustNew(55599, "ARS")})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"price":555.99}`, string(out))
+	out, err = json.Marshal(product{MustNew(1250, "USD")})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"price":{"amount":12.50,"currency":"USD"}}`, string(out))
+
+	for _, in := range []string{`{"price": true}`, `{"price": "abc"}`, `{"price": {"amount": 1, "currency": "dollars"}}`} {
+		var p product
+		assert.ErrorIs(t, json.Unmarshal([]byte(in), &p), ErrInvalid, in)
+	}
+}
+
+func TestParseRounding(t *testing.T) {
+	for in, want := range map[string]Rounding{"": HalfEven, "half-even": HalfEven, "half-up": HalfUp} {
+		got, err := ParseRounding(in)
+		assert.Nil(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseRounding("down")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,267 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operation es una operacion de un JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply aplica a doc las operaciones del JSON Patch ops, todas o ninguna
+func Apply(doc, ops []byte) ([]byte, error) {
+	var operations []Operation
+	if err := json.Unmarshal(ops, &operations); err != nil {
+		return nil, fmt.Errorf("%w: the patch must be an array of operations: %v", ErrInvalid, err)
+	}
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range operations {
+		if target, err = op.apply(target); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func (op Operation) apply(doc any) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: %s without path", ErrInvalid, op.Op)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %s without value", ErrInvalid, op.Op)
+		}
+		if value, err = decode(op.Value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: %s without from", ErrInvalid, op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" && isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: can't move %s into itself", ErrInvalid, *op.From)
+		}
+		if value, err = get(doc, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			value = clone(value)
+		} else if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, fmt.Errorf("%w: %s is not %s", ErrTestFailed, *op.Path, op.Value)
+		}
+		return doc, nil
+	}
+}
+
+// pointer es un JSON Pointer (RFC 6901) ya separado en tokens
+type pointer []string
+
+func parsePointer(s string) (pointer, error) {
+	if s == "" {
+		return pointer{}, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalid, s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: invalid escape in pointer %q", ErrInvalid, s)
+			}
+		}
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func (p pointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteString("/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+func isPrefix(prefix, p pointer) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != p[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// index lee el indice de un arreglo de n elementos
+func index(token string, n int, end bool) (int, bool) {
+	if token == "-" {
+		return n, end
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, false
+	}
+	if end {
+		return i, i <= n
+	}
+	return i, i < n
+}
+
+// get devuelve el valor al que apunta p
+func get(doc any, p pointer) (any, error) {
+	node := doc
+	for _, token := range p {
+		switch x := node.(type) {
+		case map[string]any:
+			child, ok := x[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPath, p)
+			}
+			node = child
+		case []any:
+			i, ok := index(token, len(x), false)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPath, p)
+			}
+			node = x[i]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPath, p)
+		}
+	}
+	return node, nil
+}
+
+// update reemplaza el padre de p por lo que devuelve fn
+func update(doc any, p pointer, full pointer, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(p) == 1 {
+		return fn(doc, p[0])
+	}
+	switch x := doc.(type) {
+	case map[string]any:
+		child, ok := x[p[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPath, full)
+		}
+		child, err := update(child, p[1:], full, fn)
+		if err != nil {
+			return nil, err
+		}
+		x[p[0]] = child
+		return x, nil
+	case []any:
+		i, ok := index(p[0], len(x), false)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPath, full)
+		}
+		child, err := update(x[i], p[1:], full, fn)
+		if err != nil {
+			return nil, err
+		}
+		x[i] = child
+		return x, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPath, full)
+}
+
+// add agrega value en p
+func add(doc any, p pointer, value any) (any, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+	return update(doc, p, p, func(parent any, token string) (any, error) {
+		switch x := parent.(type) {
+		case map[string]any:
+			x[token] = value
+			return x, nil
+		case []any:
+			i, ok := index(token, len(x), true)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPath, p)
+			}
+			x = append(x, nil)
+			copy(x[i+1:], x[i:])
+			x[i] = value
+			return x, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPath, p)
+	})
+}
+
+// remove borra el valor al que apunta p, que tiene que existir
+func remove(doc any, p pointer) (any, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: can't remove the whole document", ErrInvalid)
+	}
+	return update(doc, p, p, func(parent any, token string) (any, error) {
+		switch x := parent.(type) {
+		case map[string]any:
+			if _, ok := x[token]; !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPath, p)
+			}
+			delete(x, token)
+			return x, nil
+		case []any:
+			i, ok := index(token, len(x), false)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPath, p)
+			}
+			return append(x[:i], x[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPath, p)
+	})
+}
@@ -0,0 +1,166 @@
+// Package patch aplica JSON Merge Patch (RFC 7396) y JSON Patch (RFC 6902)
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalid indica un patch mal formado
+	ErrInvalid = errors.New("invalid patch")
+	// ErrPath indica una operacion sobre un path que no existe
+	ErrPath = errors.New("patch path not found")
+	// ErrTestFailed indica una operacion test cuyo valor no coincide
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// decode lee un unico valor json conservando los numeros como json.Number
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the json value")
+	}
+	return v, nil
+}
+
+// MergePatch aplica a doc el JSON Merge Patch patch
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	obj, ok := target.(map[string]any)
+	if !ok {
+		obj = map[string]any{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(obj, name)
+			continue
+		}
+		obj[name] = merge(obj[name], value)
+	}
+	return obj
+}
+
+// equal compara dos valores json, los numeros por su valor
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		return equalNumbers(x.String(), y.String())
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for name, value := range x {
+			other, ok := y[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// equalNumbers compara dos numeros json por su valor sin expandir el exponente
+func equalNumbers(a, b string) bool {
+	x, okx := normalizeNumber(a)
+	y, oky := normalizeNumber(b)
+	if !okx || !oky {
+		return a == b
+	}
+	return x == y
+}
+
+// number es un numero como signo, digitos significativos y exponente
+type number struct {
+	negative bool
+	digits   string
+	exp      int64
+}
+
+// normalizeNumber quita los ceros de mas para que dos numeros iguales coincidan
+func normalizeNumber(s string) (number, bool) {
+	var n number
+	if strings.HasPrefix(s, "-") {
+		n.negative, s = true, s[1:]
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp, err := strconv.ParseInt(strings.TrimPrefix(s[i+1:], "+"), 10, 64)
+		if err != nil {
+			return number{}, false
+		}
+		n.exp, s = exp, s[:i]
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		n.exp -= int64(len(s) - i - 1)
+		s = s[:i] + s[i+1:]
+	}
+	s = strings.TrimLeft(s, "0")
+	if s == "" {
+		return number{}, true
+	}
+	trimmed := strings.TrimRight(s, "0")
+	n.exp += int64(len(s) - len(trimmed))
+	n.digits = trimmed
+	return n, true
+}
+
+// clone copia un valor json para que add y copy no compartan objetos
+func clone(v any) any {
+	switch x := v.(type) {
+	case []any:
+		c := make([]any, len(x))
+		for i := range x {
+			c[i] = clone(x[i])
+		}
+		return c
+	case map[string]any:
+		c := make(map[string]any, len(x))
+		for name, value := range x {
+			c[name] = clone(value)
+		}
+		return c
+	}
+	return v
+}
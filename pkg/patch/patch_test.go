@@ -0,0 +1,66 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	// ejemplo de la seccion 3 de RFC 7396
+	doc := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	patch := `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`
+	got, err := MergePatch([]byte(doc), []byte(patch))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`, string(got))
+
+	// los ceros y false se aplican y los numeros no pierden precision
+	got, err = MergePatch([]byte(`{"quantity":5,"is_published":true,"price":1}`), []byte(`{"quantity":0,"is_published":false,"price":555.99}`))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"is_published":false,"price":555.99,"quantity":0}`, string(got))
+
+	_, err = MergePatch([]byte(`{}`), []byte(`{"a":`))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+		err                    error
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, nil},
+		{"add into array", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, nil},
+		{"append", `{"foo":[1]}`, `[{"op":"add","path":"/foo/-","value":2}]`, `{"foo":[1,2]}`, nil},
+		{"add null", `{}`, `[{"op":"add","path":"/a","value":null}]`, `{"a":null}`, nil},
+		{"remove", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, nil},
+		{"remove from array", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, nil},
+		{"replace", `{"baz":"qux"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo"}`, nil},
+		{"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil},
+		{"move in array", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, nil},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`, nil},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"test","path":"/a~1b","value":1},{"op":"remove","path":"/m~0n"}]`, `{"a/b":1}`, nil},
+		{"test numbers by value", `{"price":10}`, `[{"op":"test","path":"/price","value":10.0}]`, `{"price":10}`, nil},
+		{"test numbers with exponent", `{"price":1500,"zero":0}`, `[{"op":"test","path":"/price","value":1.5e3},{"op":"test","path":"/zero","value":-0.0e5}]`, `{"price":1500,"zero":0}`, nil},
+		{"test huge exponent", `{"price":10}`, `[{"op":"test","path":"/price","value":1e999999999}]`, "", ErrTestFailed},
+		{"test failed", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "", ErrTestFailed},
+		{"all or nothing", `{"a":1}`, `[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":1}]`, "", ErrTestFailed},
+		{"missing target", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, "", ErrPath},
+		{"missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "", ErrPath},
+		{"index out of range", `{"foo":[1]}`, `[{"op":"add","path":"/foo/2","value":2}]`, "", ErrPath},
+		{"leading zero", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/01"}]`, "", ErrPath},
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a","value":1}]`, "", ErrInvalid},
+		{"value required", `{}`, `[{"op":"add","path":"/a"}]`, "", ErrInvalid},
+		{"move into child", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, "", ErrInvalid},
+		{"bad escape", `{}`, `[{"op":"remove","path":"/a~2"}]`, "", ErrInvalid},
+		{"not an array", `{}`, `{"op":"remove","path":"/a"}`, "", ErrInvalid},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+		if tt.err != nil {
+			assert.ErrorIs(t, err, tt.err, tt.name)
+			continue
+		}
+		assert.Nil(t, err, tt.name)
+		assert.JSONEq(t, tt.want, string(got), tt.name)
+	}
+}
@@ -0,0 +1,67 @@
+package query
+
+import (
+	"sort"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+// compareKeys compara los valores de orden a y b segun keys
+func compareKeys(a, b []any, keys []SortKey) int {
+	for i, k := range keys {
+		c := compareValues(a[i], b[i])
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// sortValues devuelve los valores de p para cada criterio de keys
+func sortValues(p domain.Product, keys []SortKey) []any {
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		values[i] = Value(p, k.Field)
+	}
+	return values
+}
+
+// Apply ejecuta q sobre products en memoria. Lo usan los stores que no
+// saben ejecutar la Query por su cuenta.
+func Apply(products []domain.Product, q Query) (Page, error) {
+	keys := q.OrderBy()
+	after, err := DecodeCursor(q)
+	if err != nil {
+		return Page{}, err
+	}
+
+	filtered := Filter(products, q.Filter)
+	sorted := make([]domain.Product, len(filtered))
+	copy(sorted, filtered)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareKeys(sortValues(sorted[i], keys), sortValues(sorted[j], keys), keys) < 0
+	})
+
+	page := Page{Total: len(sorted)}
+	start := q.Offset
+	if after != nil {
+		start = sort.Search(len(sorted), func(i int) bool {
+			return compareKeys(sortValues(sorted[i], keys), after, keys) > 0
+		})
+	}
+	if start > len(sorted) {
+		start = len(sorted)
+	}
+	end := len(sorted)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+	page.Items = sorted[start:end]
+	if end < len(sorted) && end > start {
+		page.NextCursor = EncodeCursor(q, sorted[end-1])
+	}
+	return page, nil
+}
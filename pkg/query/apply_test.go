@@ -0,0 +1,72 @@
+package query
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+var products = []domain.Product{
+	{Id: 1, Name: "b", Price: money.MustNew(2000, "ARS")},
+	{Id: 2, Name: "a", Price: money.MustNew(1000, "ARS")},
+	{Id: 3, Name: "c", Price: money.MustNew(2000, "ARS")},
+	{Id: 4, Name: "a", Price: money.MustNew(2000, "ARS")},
+	{Id: 5, Name: "d", Price: money.MustNew(500, "ARS")},
+}
+
+func ids(items []domain.Product) []int {
+	var ids []int
+	for _, p := range items {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+func TestApply_MultiKeySort(t *testing.T) {
+	q, err := Parse(url.Values{"sort": {"-price,name"}})
+	assert.Nil(t, err)
+	page, err := Apply(products, q)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{4, 1, 3, 2, 5}, ids(page.Items))
+	assert.Equal(t, 5, page.Total)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestApply_CursorWalksEveryItemOnce(t *testing.T) {
+	q, err := Parse(url.Values{"sort": {"-price,name"}, "limit": {"2"}})
+	assert.Nil(t, err)
+	var seen []int
+	for {
+		page, err := Apply(products, q)
+		assert.Nil(t, err)
+		seen = append(seen, ids(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []int{4, 1, 3, 2, 5}, seen)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, values := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"abc"}},
+		{"offset": {"-1"}},
+		{"sort": {"nope"}},
+		{"fields": {"id,nope"}},
+		{"cursor": {"x"}, "offset": {"2"}},
+	} {
+		_, err := Parse(values)
+		assert.ErrorIs(t, err, ErrInvalid, values.Encode())
+	}
+
+	// un cursor generado con otro orden no sirve
+	q, _ := Parse(url.Values{"sort": {"name"}})
+	q.Cursor = EncodeCursor(Query{}, products[0])
+	_, err := Apply(products, q)
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+)
+
+// cursor guarda los valores de orden del ultimo elemento entregado, junto
+// con el orden con el que se generaron para rechazar cursores ajenos
+type cursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// sortSpec serializa los criterios de orden
+func sortSpec(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// EncodeCursor arma el cursor que apunta a continuacion de p
+func EncodeCursor(q Query, p domain.Product) string {
+	keys := q.OrderBy()
+	c := cursor{Sort: sortSpec(keys)}
+	for _, k := range keys {
+		raw, _ := json.Marshal(Value(p, k.Field))
+		c.Values = append(c.Values, raw)
+	}
+	bytes, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// DecodeCursor devuelve los valores del cursor de q, alineados con
+// q.OrderBy(); nil si q no trae cursor
+func DecodeCursor(q Query) ([]any, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	bytes, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, invalid("malformed cursor")
+	}
+	var c cursor
+	if err := json.Unmarshal(bytes, &c); err != nil {
+		return nil, invalid("malformed cursor")
+	}
+	keys := q.OrderBy()
+	if c.Sort != sortSpec(keys) || len(c.Values) != len(keys) {
+		return nil, invalid("cursor belongs to a different sort")
+	}
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		kind, _ := FieldKind(k.Field)
+		var err error
+		switch kind {
+		case KindInt:
+			var v int
+			err = json.Unmarshal(c.Values[i], &v)
+			values[i] = v
+		case KindMoney:
+			var v money.Money
+			err = json.Unmarshal(c.Values[i], &v)
+			values[i] = v
+		case KindString:
+			var v string
+			err = json.Unmarshal(c.Values[i], &v)
+			values[i] = v
+		case KindBool:
+			var v bool
+			err = json.Unmarshal(c.Values[i], &v)
+			values[i] = v
+		case KindDate:
+			var v domain.Date
+			err = json.Unmarshal(c.Values[i], &v)
+			values[i] = v
+		}
+		if err != nil {
+			return nil, invalid("malformed cursor")
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,123 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+)
+
+// Kind es el tipo de dato de un campo de Product
+type Kind int
+
+const (
+	KindInt Kind = iota
+	KindMoney
+	KindString
+	KindBool
+	// KindDate es una fecha de calendario (domain.Date)
+	KindDate
+)
+
+// fields son los campos de Product por su nombre json, que coincide con el
+// nombre de la columna en los backends sql
+var fields = map[string]Kind{
+	"id":               KindInt,
+	"name":             KindString,
+	"quantity":         KindInt,
+	"code_value":       KindString,
+	"is_published":     KindBool,
+	"expiration":       KindDate,
+	"price":            KindMoney,
+	"version":          KindInt,
+	"lifecycle_reason": KindString,
+	"reserved":         KindInt,
+}
+
+// IsField indica si name es un campo de Product
+func IsField(name string) bool {
+	_, ok := fields[name]
+	return ok
+}
+
+// FieldKind devuelve el tipo del campo name
+func FieldKind(name string) (Kind, bool) {
+	kind, ok := fields[name]
+	return kind, ok
+}
+
+// Value devuelve el valor del campo de p como int, string, bool,
+// domain.Date o money.Money
+func Value(p domain.Product, field string) any {
+	switch field {
+	case "id":
+		return p.Id
+	case "name":
+		return p.Name
+	case "quantity":
+		return p.Quantity
+	case "code_value":
+		return p.CodeValue
+	case "is_published":
+		return p.IsPublished
+	case "expiration":
+		return p.Expiration
+	case "price":
+		return p.Price
+	case "version":
+		return p.Version
+	case "lifecycle_reason":
+		return p.LifecycleReason
+	case "reserved":
+		return p.Reserved
+	}
+	return nil
+}
+
+// compareValues compara dos valores del mismo campo: -1, 0 o 1
+func compareValues(a, b any) int {
+	switch x := a.(type) {
+	case int:
+		y := b.(int)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	case money.Money:
+		// por moneda y despues por unidades menores, sin convertir monedas
+		return x.Compare(b.(money.Money))
+	case string:
+		return strings.Compare(x, b.(string))
+	case domain.Date:
+		return x.Compare(b.(domain.Date))
+	case bool:
+		y := b.(bool)
+		switch {
+		case !x && y:
+			return -1
+		case x && !y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Project devuelve solo los campos pedidos de p, con su representacion json
+func Project(p domain.Product, fields []string) map[string]json.RawMessage {
+	bytes, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(bytes, &all); err != nil {
+		return nil
+	}
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		projected[f] = all[f]
+	}
+	return projected
+}
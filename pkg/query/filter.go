@@ -0,0 +1,118 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+)
+
+// Op es un operador de comparacion de un filtro
+type Op string
+
+const (
+	OpEq       Op = "="
+	OpNe       Op = "!="
+	OpGt       Op = ">"
+	OpGe       Op = ">="
+	OpLt       Op = "<"
+	OpLe       Op = "<="
+	OpContains Op = "~"
+	OpPrefix   Op = "^="
+)
+
+// Expr es un nodo del arbol de un filtro: And, Or, Not o Cmp
+type Expr interface {
+	expr()
+}
+
+// And se cumple si se cumplen los dos lados
+type And struct {
+	Left, Right Expr
+}
+
+// Or se cumple si se cumple alguno de los lados
+type Or struct {
+	Left, Right Expr
+}
+
+// Not niega la expresion
+type Not struct {
+	Expr Expr
+}
+
+// Cmp compara un campo con un valor. Value ya esta convertido al tipo del
+// campo: int, string, bool, domain.Date o money.Money.
+type Cmp struct {
+	Field string
+	Op    Op
+	Value any
+}
+
+func (And) expr() {}
+func (Or) expr()  {}
+func (Not) expr() {}
+func (Cmp) expr() {}
+
+// Match evalua e sobre p; un filtro nil acepta todos los productos
+func Match(e Expr, p domain.Product) bool {
+	switch e := e.(type) {
+	case nil:
+		return true
+	case And:
+		return Match(e.Left, p) && Match(e.Right, p)
+	case Or:
+		return Match(e.Left, p) || Match(e.Right, p)
+	case Not:
+		return !Match(e.Expr, p)
+	case Cmp:
+		return e.match(p)
+	}
+	return false
+}
+
+// match evalua la comparacion sobre p
+func (c Cmp) match(p domain.Product) bool {
+	value := Value(p, c.Field)
+	switch c.Op {
+	case OpContains:
+		return strings.Contains(strings.ToLower(value.(string)), strings.ToLower(c.Value.(string)))
+	case OpPrefix:
+		return strings.HasPrefix(value.(string), c.Value.(string))
+	}
+
+	if m, ok := value.(money.Money); ok && !m.Comparable(c.Value.(money.Money)) {
+		// un importe de otra moneda no es mayor, menor ni igual
+		return c.Op == OpNe
+	}
+	cmp := compareValues(value, c.Value)
+	switch c.Op {
+	case OpEq:
+		return cmp == 0
+	case OpNe:
+		return cmp != 0
+	case OpGt:
+		return cmp > 0
+	case OpGe:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLe:
+		return cmp <= 0
+	}
+	return false
+}
+
+// Filter devuelve los productos que cumplen e
+func Filter(products []domain.Product, e Expr) []domain.Product {
+	if e == nil {
+		return products
+	}
+	filtered := []domain.Product{}
+	for _, p := range products {
+		if Match(e, p) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
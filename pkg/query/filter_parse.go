@@ -0,0 +1,265 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+)
+
+const (
+	// maxFilterLength y maxFilterDepth acotan el trabajo que puede pedir un
+	// filtro que llega desde un request
+	maxFilterLength = 2000
+	maxFilterDepth  = 32
+)
+
+// tokenKind es el tipo de un token del filtro
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// opChars son los caracteres que forman operadores
+const opChars = "=!<>~^"
+
+// operators son los operadores validos, los de dos caracteres primero
+var operators = []Op{OpNe, OpGe, OpLe, OpPrefix, OpEq, OpGt, OpLt, OpContains}
+
+// tokenize separa el filtro en palabras, strings entre comillas, operadores
+// y parentesis
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j == len(s) {
+				return nil, invalid("filter: unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{tokenString, b.String(), i})
+			i = j + 1
+		case strings.IndexByte(opChars, c) >= 0:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(s[i:], string(candidate)) {
+					op = string(candidate)
+					break
+				}
+			}
+			if op == "" {
+				return nil, invalid("filter: unknown operator at %d", i)
+			}
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r()\""+opChars, rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokenWord, s[i:j], i})
+			i = j
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(s)}), nil
+}
+
+// parser es un parser descendente para la gramatica
+//
+//	or   = and { OR and }
+//	and  = unary { AND unary }
+//	unary = NOT unary | "(" or ")" | field op value
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// ParseFilter interpreta un filtro como
+//
+//	price>=100 AND is_published=true AND name~"cake"
+//
+// Los operadores son = != > >= < <= ~ (contiene, sin distinguir mayusculas)
+// y ^= (empieza con); AND, OR y NOT se pueden agrupar con parentesis. Los
+// valores se validan contra el tipo del campo y las fechas se aceptan en
+// los formatos de domain.ParseDate. Un filtro vacio devuelve nil.
+func ParseFilter(s string) (Expr, error) {
+	if len(s) > maxFilterLength {
+		return nil, invalid("filter is longer than %d characters", maxFilterLength)
+	}
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, nil
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, invalid("filter: unexpected %q at %d", t.text, t.pos)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword indica si el proximo token es la palabra reservada kw
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokenWord && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxFilterDepth {
+		return nil, invalid("filter is nested deeper than %d levels", maxFilterDepth)
+	}
+
+	if p.keyword("NOT") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{e}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, invalid("filter: expected ) at %d", t.pos)
+		}
+		return e, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *parser) parseCmp() (Expr, error) {
+	field := p.next()
+	if field.kind != tokenWord {
+		return nil, invalid("filter: expected a field at %d", field.pos)
+	}
+	kind, ok := FieldKind(field.text)
+	if !ok {
+		return nil, invalid("filter: unknown field %q", field.text)
+	}
+	op := p.next()
+	if op.kind != tokenOp {
+		return nil, invalid("filter: expected an operator after %s", field.text)
+	}
+	literal := p.next()
+	if literal.kind != tokenWord && literal.kind != tokenString {
+		return nil, invalid("filter: expected a value after %s%s", field.text, op.text)
+	}
+
+	c := Cmp{Field: field.text, Op: Op(op.text)}
+	switch {
+	case (c.Op == OpContains || c.Op == OpPrefix) && kind != KindString:
+		return nil, invalid("filter: %s only applies to text fields", op.text)
+	case kind == KindBool && c.Op != OpEq && c.Op != OpNe:
+		return nil, invalid("filter: %s can only be compared with = or !=", field.text)
+	}
+	value, err := convertLiteral(kind, literal)
+	if err != nil {
+		return nil, invalid("filter: invalid value %q for %s", literal.text, field.text)
+	}
+	c.Value = value
+	return c, nil
+}
+
+// convertLiteral convierte el valor de una comparacion al tipo del campo
+func convertLiteral(kind Kind, literal token) (any, error) {
+	switch kind {
+	case KindInt:
+		return strconv.Atoi(literal.text)
+	case KindMoney:
+		// los importes del filtro estan en la moneda por defecto salvo que
+		// se indique, como "10.5 USD"
+		return money.Parse(literal.text, money.DefaultCurrency(), money.HalfEven)
+	case KindBool:
+		return strconv.ParseBool(literal.text)
+	case KindDate:
+		return domain.ParseDate(literal.text)
+	}
+	return literal.text, nil
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+var cakes = []domain.Product{
+	{Id: 1, Name: "Chocolate Cake", CodeValue: "CK01", Quantity: 5, Price: money.MustNew(15000, "ARS"), IsPublished: true, Expiration: domain.NewDate(2021, time.December, 15)},
+	{Id: 2, Name: "Carrot cake", CodeValue: "CK02", Quantity: 50, Price: money.MustNew(9000, "ARS"), IsPublished: true, Expiration: domain.NewDate(2023, time.February, 1)},
+	{Id: 3, Name: "Bread", CodeValue: "BR01", Quantity: 20, Price: money.MustNew(12000, "ARS"), IsPublished: false, Expiration: domain.NewDate(2022, time.January, 3)},
+	{Id: 4, Name: "Cake \"deluxe\"", CodeValue: "CK03", Quantity: 1, Price: money.MustNew(30000, "ARS"), IsPublished: false, Expiration: domain.NewDate(2024, time.June, 20)},
+}
+
+func TestParseFilter_Match(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []int
+	}{
+		{`price>=100 AND is_published=true AND name~"cake"`, []int{1}},
+		{`name~cake`, []int{1, 2, 4}},
+		{`code_value^=CK AND NOT quantity<5`, []int{1, 2}},
+		{`is_published=false OR price<100`, []int{2, 3, 4}},
+		{`(is_published=false OR price<100) and quantity>=20`, []int{2, 3}},
+		{`expiration>=01/01/2022 AND expiration<2024-01-01`, []int{2, 3}},
+		{`expiration=2022-01-03`, []int{3}},
+		{`name="Cake \"deluxe\""`, []int{4}},
+		{`id!=1 AND id!=2`, []int{3, 4}},
+	}
+	for _, tt := range tests {
+		e, err := ParseFilter(tt.filter)
+		assert.Nil(t, err, tt.filter)
+		var got []int
+		for _, p := range Filter(cakes, e) {
+			got = append(got, p.Id)
+		}
+		assert.Equal(t, tt.want, got, tt.filter)
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{
+		`price>`,
+		`price>>1`,
+		`nope=1`,
+		`price>abc`,
+		`quantity=1.5`,
+		`is_published>true`,
+		`price~1`,
+		`expiration<31/02/2022`,
+		`name="cake`,
+		`(price>1`,
+		`price>1 price<2`,
+		`price>1 AND`,
+	} {
+		_, err := ParseFilter(filter)
+		assert.ErrorIs(t, err, ErrInvalid, filter)
+	}
+
+	deep := ""
+	for i := 0; i < maxFilterDepth+1; i++ {
+		deep += "("
+	}
+	_, err := ParseFilter(deep + "id=1")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestParseFilter_Empty(t *testing.T) {
+	e, err := ParseFilter("  ")
+	assert.Nil(t, err)
+	assert.Nil(t, e)
+	assert.Len(t, Filter(cakes, e), len(cakes))
+}
+
+func TestParseFilter_Currencies(t *testing.T) {
+	products := []domain.Product{
+		{Id: 1, Price: money.MustNew(15000, "ARS")},
+		{Id: 2, Price: money.MustNew(500, "USD")},
+		{Id: 3},
+	}
+	tests := []struct {
+		filter string
+		want   []int
+	}{
+		{`price>1`, []int{1}},
+		{`price<=1`, []int{3}},
+		{`price=0`, []int{3}},
+		{`price!=150`, []int{2, 3}},
+		{`price>="1 USD"`, []int{2}},
+	}
+	for _, tt := range tests {
+		e, err := ParseFilter(tt.filter)
+		assert.Nil(t, err, tt.filter)
+		var got []int
+		for _, p := range Filter(products, e) {
+			got = append(got, p.Id)
+		}
+		assert.Equal(t, tt.want, got, tt.filter)
+	}
+}
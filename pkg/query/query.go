@@ -0,0 +1,111 @@
+// Package query describe como listar productos (filtro, paginado, orden y campos)
+// de forma independiente del backend, para que cada store pueda ejecutarlo
+// a su manera o delegar en Apply.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+// MaxLimit es el tamaño maximo de pagina que se acepta
+const MaxLimit = 1000
+
+// ErrInvalid envuelve todos los errores de parametros invalidos
+var ErrInvalid = errors.New("invalid query")
+
+// SortKey es un criterio de orden: un campo y su direccion
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Query es un pedido de listado. Limit 0 significa sin limite. Cursor y
+// Offset son excluyentes. Filter nil no filtra.
+type Query struct {
+	Filter Expr
+	Limit  int
+	Offset int
+	Cursor string
+	Sort   []SortKey
+	Fields []string
+}
+
+// Page es el resultado de ejecutar una Query
+type Page struct {
+	Items []domain.Product
+	// Total es la cantidad de productos que cumplen el filtro, antes de paginar
+	Total int
+	// NextCursor apunta al elemento siguiente al ultimo de Items; vacio si
+	// no hay mas
+	NextCursor string
+}
+
+// invalid arma un error de parametro envolviendo ErrInvalid
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+}
+
+// Parse arma una Query a partir de los parametros filter (ver ParseFilter),
+// limit, offset, cursor, sort (por ejemplo "price,-name") y fields (por
+// ejemplo "id,name,price")
+func Parse(values url.Values) (Query, error) {
+	var q Query
+	var err error
+	if q.Filter, err = ParseFilter(values.Get("filter")); err != nil {
+		return Query{}, err
+	}
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 || q.Limit > MaxLimit {
+			return Query{}, invalid("limit must be a number between 1 and %d", MaxLimit)
+		}
+	}
+	if v := values.Get("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil || q.Offset < 0 {
+			return Query{}, invalid("offset must be a positive number")
+		}
+	}
+	q.Cursor = values.Get("cursor")
+	if q.Cursor != "" && q.Offset > 0 {
+		return Query{}, invalid("cursor and offset can't be used together")
+	}
+	if v := values.Get("sort"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			key := SortKey{Field: strings.TrimSpace(field)}
+			if strings.HasPrefix(key.Field, "-") {
+				key.Field, key.Desc = key.Field[1:], true
+			}
+			if !IsField(key.Field) {
+				return Query{}, invalid("can't sort by %q", key.Field)
+			}
+			q.Sort = append(q.Sort, key)
+		}
+	}
+	if v := values.Get("fields"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if !IsField(field) {
+				return Query{}, invalid("unknown field %q", field)
+			}
+			q.Fields = append(q.Fields, field)
+		}
+	}
+	return q, nil
+}
+
+// OrderBy devuelve los criterios de orden completos: los pedidos mas el id
+// como desempate, asi el orden es total y los cursores son estables
+func (q Query) OrderBy() []SortKey {
+	keys := append([]SortKey{}, q.Sort...)
+	for _, k := range keys {
+		if k.Field == "id" {
+			return keys
+		}
+	}
+	return append(keys, SortKey{Field: "id"})
+}
@@ -0,0 +1,49 @@
+package sheet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// bom es la marca de orden de bytes que Excel agrega a los csv en utf-8
+var bom = []byte("\xef\xbb\xbf")
+
+// ReadCSV lee un csv separado por comas o por punto y coma, como lo exporta
+// Excel con configuracion regional en espanol. El separador se deduce de la
+// primera linea; las filas pueden tener distinta cantidad de celdas.
+func ReadCSV(r io.Reader, limits Limits) ([][]string, error) {
+	limits = limits.orDefault()
+	br := bufio.NewReader(r)
+	if start, _ := br.Peek(len(bom)); bytes.Equal(start, bom) {
+		_, _ = br.Discard(len(bom))
+	}
+	head, _ := br.Peek(br.Size())
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	if bytes.Count(head, []byte(";")) > bytes.Count(head, []byte(",")) {
+		reader.Comma = ';'
+	}
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if len(rows) == limits.Rows {
+			return nil, limits.tooManyRows()
+		}
+		if len(record) > limits.Columns {
+			return nil, limits.tooManyColumns(len(record) - 1)
+		}
+		rows = append(rows, record)
+	}
+}
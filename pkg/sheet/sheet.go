@@ -0,0 +1,68 @@
+// Package sheet lee planillas csv y xlsx como una matriz de celdas de texto,
+// sin dependencias externas: solo la primera hoja de los xlsx y solo los
+// valores, no las formulas ni los estilos salvo el formato de fecha.
+package sheet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrInvalid indica un archivo que no es un csv o xlsx legible
+	ErrInvalid = errors.New("invalid spreadsheet")
+	// ErrTooManyRows indica una planilla con mas filas que Limits.Rows
+	ErrTooManyRows = errors.New("too many rows")
+	// ErrTooManyColumns indica una celda mas alla de Limits.Columns
+	ErrTooManyColumns = errors.New("too many columns")
+)
+
+// Limits acota lo que se lee de una planilla. Se controlan mientras se lee,
+// antes de reservar memoria para las filas o celdas que los exceden, asi que
+// una planilla nunca ocupa mas de Rows*Columns celdas. Un limite en 0 es el
+// de Excel.
+type Limits struct {
+	// Rows es la cantidad maxima de filas, contando los encabezados
+	Rows int
+	// Columns es la cantidad maxima de columnas
+	Columns int
+}
+
+// orDefault completa los limites en 0 con los de Excel
+func (l Limits) orDefault() Limits {
+	if l.Rows <= 0 || l.Rows > maxRows {
+		l.Rows = maxRows
+	}
+	if l.Columns <= 0 || l.Columns > maxColumns {
+		l.Columns = maxColumns
+	}
+	return l
+}
+
+// tooManyRows es el error de una planilla con mas de l.Rows filas
+func (l Limits) tooManyRows() error {
+	return fmt.Errorf("%w: the sheet can't have more than %d rows", ErrTooManyRows, l.Rows)
+}
+
+// tooManyColumns es el error de una celda en la columna col (desde 0)
+func (l Limits) tooManyColumns(col int) error {
+	return fmt.Errorf("%w: the sheet can't have more than %d columns, found column %d", ErrTooManyColumns, l.Columns, col+1)
+}
+
+// zipMagic es el comienzo de todo archivo zip, como los xlsx
+var zipMagic = []byte("PK\x03\x04")
+
+// Read lee un csv o un xlsx segun su contenido: los xlsx son archivos zip
+// y cualquier otra cosa se lee como csv
+func Read(r io.Reader, limits Limits) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, zipMagic) {
+		return ReadXLSX(data, limits)
+	}
+	return ReadCSV(bytes.NewReader(data), limits)
+}
@@ -0,0 +1,131 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// buildXLSX arma un xlsx minimo con las partes dadas ademas del libro
+func buildXLSX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	all := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Productos" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/data.xml"/>
+</Relationships>`,
+	}
+	for name, content := range parts {
+		all[name] = content
+	}
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range all {
+		f, err := w.Create(name)
+		assert.Nil(t, err)
+		_, err = f.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestReadCSV(t *testing.T) {
+	rows, err := ReadCSV(strings.NewReader("name,price\n\"Queso, rallado\",10.5\nsolo\n"), Limits{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"name", "price"}, {"Queso, rallado", "10.5"}, {"solo"}}, rows)
+
+	// exportado por Excel en espanol: BOM y punto y coma
+	rows, err = ReadCSV(strings.NewReader("\xef\xbb\xbfnombre;precio\r\nYerba;1,5\r\n"), Limits{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"nombre", "precio"}, {"Yerba", "1,5"}}, rows)
+
+	_, err = ReadCSV(strings.NewReader("a,\"b\n"), Limits{})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = ReadCSV(strings.NewReader("a\nb\nc\n"), Limits{Rows: 2})
+	assert.ErrorIs(t, err, ErrTooManyRows)
+	_, err = ReadCSV(strings.NewReader("a,b,c\n"), Limits{Columns: 2})
+	assert.ErrorIs(t, err, ErrTooManyColumns)
+}
+
+func TestReadXLSX(t *testing.T) {
+	data := buildXLSX(t, map[string]string{
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>name</t></si><si><t>expiration</t></si><si><r><t>Queso </t></r><r><t>azul</t></r></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/><numFmt numFmtId="165" formatCode="&quot;day&quot; 0"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="14"/><xf numFmtId="165"/></cellXfs></styleSheet>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="inlineStr"><is><t>published</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" s="1"><v>45292</v></c><c r="C2" s="3"><v>7</v></c><c r="D2" t="b"><v>1</v></c></row>
+<row r="4"><c r="B4" s="2"><v>45351.5</v></c><c r="C4"><v>555.99</v></c></row>
+</sheetData></worksheet>`,
+	})
+	rows, err := ReadXLSX(data, Limits{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"name", "expiration", "", "published"},
+		{"Queso azul", "2024-01-01", "7", "true"},
+		nil,
+		{"", "2024-02-29", "555.99"},
+	}, rows)
+
+	// Read reconoce el xlsx por el contenido
+	read, err := Read(bytes.NewReader(data), Limits{})
+	assert.Nil(t, err)
+	assert.Equal(t, rows, read)
+}
+
+func TestReadXLSX_Invalid(t *testing.T) {
+	_, err := ReadXLSX([]byte("PK\x03\x04 not a zip"), Limits{})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// sin la hoja referenciada por el libro
+	_, err = ReadXLSX(buildXLSX(t, nil), Limits{})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = ReadXLSX(buildXLSX(t, map[string]string{
+		"xl/worksheets/data.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>3</v></c></row></sheetData></worksheet>`,
+	}), Limits{})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestReadXLSX_Limits(t *testing.T) {
+	// cada fila tiene una sola celda en la ultima columna de Excel: sin
+	// limites de columnas serian 16384 celdas por fila
+	var sheetData strings.Builder
+	for i := 1; i <= 20000; i++ {
+		fmt.Fprintf(&sheetData, `<row r="%d"><c r="XFD%d"><v>1</v></c></row>`, i, i)
+	}
+	farRight := buildXLSX(t, map[string]string{
+		"xl/worksheets/data.xml": `<worksheet><sheetData>` + sheetData.String() + `</sheetData></worksheet>`,
+	})
+	_, err := ReadXLSX(farRight, Limits{Rows: 10001, Columns: 100})
+	assert.ErrorIs(t, err, ErrTooManyColumns)
+	assert.Contains(t, err.Error(), "column 16384")
+
+	// la fila 1048576 corta la lectura sin completar las anteriores
+	lastRow := buildXLSX(t, map[string]string{
+		"xl/worksheets/data.xml": `<worksheet><sheetData><row r="1"><c r="A1"><v>1</v></c></row><row r="1048576"><c r="A1048576"><v>1</v></c></row></sheetData></worksheet>`,
+	})
+	_, err = ReadXLSX(lastRow, Limits{Rows: 10001, Columns: 100})
+	assert.ErrorIs(t, err, ErrTooManyRows)
+
+	rows, err := ReadXLSX(lastRow, Limits{})
+	assert.Nil(t, err)
+	assert.Len(t, rows, 1048576)
+
+	// mas textos compartidos que celdas posibles
+	_, err = ReadXLSX(buildXLSX(t, map[string]string{
+		"xl/sharedStrings.xml":   `<sst><si><t>a</t></si><si><t>b</t></si><si><t>c</t></si></sst>`,
+		"xl/worksheets/data.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c></row></sheetData></worksheet>`,
+	}), Limits{Rows: 1, Columns: 2})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,394 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPartSize acota lo que se descomprime de cada parte del xlsx
+	maxPartSize = 64 << 20
+	// maxRows y maxColumns son los limites de una hoja de Excel
+	maxRows    = 1 << 20
+	maxColumns = 1 << 14
+)
+
+// excelEpoch es el dia 0 de las fechas de Excel (sistema 1900, con el
+// 29/02/1900 que no existio ya descontado)
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelId string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText es un texto con formato: el texto plano o la concatenacion de
+// sus tramos
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	s := t.Text
+	for _, r := range t.Runs {
+		s += r.Text
+	}
+	return s
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		Id   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtId int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// xlsxFile es un xlsx abierto con sus partes por nombre
+type xlsxFile map[string]*zip.File
+
+// ReadXLSX devuelve las celdas de la primera hoja de un libro xlsx. Las
+// filas y celdas vacias intermedias se completan con "" para que rows[i]
+// sea la fila i+1 de la planilla. Las celdas con formato de fecha se
+// devuelven como yyyy-mm-dd y los booleanos como true o false. La hoja se
+// lee celda por celda y una fila o columna mas alla de limits corta la
+// lectura antes de completar los huecos.
+func ReadXLSX(data []byte, limits Limits) ([][]string, error) {
+	limits = limits.orDefault()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	file := make(xlsxFile, len(zr.File))
+	for _, f := range zr.File {
+		file[strings.TrimPrefix(f.Name, "/")] = f
+	}
+	sheetPath, err := file.firstSheet()
+	if err != nil {
+		return nil, err
+	}
+	shared, err := file.sharedStrings(limits.Rows * limits.Columns)
+	if err != nil {
+		return nil, err
+	}
+	var styles xlsxStyles
+	if err := file.decode("xl/styles.xml", &styles, true); err != nil {
+		return nil, err
+	}
+	ws := worksheetReader{shared: shared, dates: dateStyles(styles), limits: limits}
+	err = file.open(sheetPath, func(r io.Reader) error {
+		return ws.read(xml.NewDecoder(r))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ws.rows, nil
+}
+
+// worksheetReader arma las filas de una hoja a medida que se leen sus
+// elementos
+type worksheetReader struct {
+	shared []xlsxText
+	dates  map[int]bool
+	limits Limits
+	rows   [][]string
+}
+
+// read recorre los elementos row y c de la hoja
+func (w *worksheetReader) read(d *xml.Decoder) error {
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "row":
+			if err := w.startRow(start); err != nil {
+				return err
+			}
+		case "c":
+			if len(w.rows) == 0 {
+				return fmt.Errorf("%w: cell outside a row", ErrInvalid)
+			}
+			var c xlsxCell
+			if err := d.DecodeElement(&c, &start); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalid, err)
+			}
+			if err := w.addCell(c); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// startRow agrega la fila del elemento start, completando las filas vacias
+// que la preceden
+func (w *worksheetReader) startRow(start xml.StartElement) error {
+	n := len(w.rows) + 1
+	for _, attr := range start.Attr {
+		if attr.Name.Local != "r" {
+			continue
+		}
+		var err error
+		if n, err = strconv.Atoi(attr.Value); err != nil {
+			return fmt.Errorf("%w: invalid row number %q", ErrInvalid, attr.Value)
+		}
+	}
+	if n < len(w.rows)+1 {
+		return fmt.Errorf("%w: invalid row number %d", ErrInvalid, n)
+	}
+	if n > w.limits.Rows {
+		return w.limits.tooManyRows()
+	}
+	for len(w.rows) < n {
+		w.rows = append(w.rows, nil)
+	}
+	return nil
+}
+
+// addCell guarda c en la ultima fila, completando las celdas vacias que la
+// preceden
+func (w *worksheetReader) addCell(c xlsxCell) error {
+	cells := w.rows[len(w.rows)-1]
+	col := len(cells)
+	if c.Ref != "" {
+		var err error
+		if col, err = columnIndex(c.Ref); err != nil {
+			return err
+		}
+	}
+	if col >= w.limits.Columns {
+		return w.limits.tooManyColumns(col)
+	}
+	value, err := cellValue(c, w.shared, w.dates)
+	if err != nil {
+		return err
+	}
+	for len(cells) < col {
+		cells = append(cells, "")
+	}
+	if col < len(cells) {
+		cells[col] = value
+	} else {
+		cells = append(cells, value)
+	}
+	w.rows[len(w.rows)-1] = cells
+	return nil
+}
+
+// sharedStrings lee los textos compartidos del libro, que son a lo sumo
+// max: uno por celda
+func (f xlsxFile) sharedStrings(max int) ([]xlsxText, error) {
+	const name = "xl/sharedStrings.xml"
+	if _, ok := f[name]; !ok {
+		return nil, nil
+	}
+	var items []xlsxText
+	err := f.open(name, func(r io.Reader) error {
+		d := xml.NewDecoder(r)
+		for {
+			token, err := d.Token()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+			}
+			start, ok := token.(xml.StartElement)
+			if !ok || start.Name.Local != "si" {
+				continue
+			}
+			if len(items) == max {
+				return fmt.Errorf("%w: %s has more strings than cells", ErrInvalid, name)
+			}
+			var item xlsxText
+			if err := d.DecodeElement(&item, &start); err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+			}
+			items = append(items, item)
+		}
+	})
+	return items, err
+}
+
+// firstSheet devuelve el nombre de la parte con la primera hoja del libro
+func (f xlsxFile) firstSheet() (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	var wb xlsxWorkbook
+	var rels xlsxRelationships
+	if err := f.decode("xl/workbook.xml", &wb, false); err != nil {
+		return "", err
+	}
+	if err := f.decode("xl/_rels/workbook.xml.rels", &rels, true); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", fmt.Errorf("%w: the workbook has no sheets", ErrInvalid)
+	}
+	for _, rel := range rels.Items {
+		if rel.Id != wb.Sheets[0].RelId {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+// decode lee la parte name como xml en v. Si optional, que no exista no es
+// un error.
+func (f xlsxFile) decode(name string, v any, optional bool) error {
+	if _, ok := f[name]; !ok && optional {
+		return nil
+	}
+	return f.open(name, func(r io.Reader) error {
+		if err := xml.NewDecoder(r).Decode(v); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+		}
+		return nil
+	})
+}
+
+// open llama a fn con el contenido descomprimido de la parte name, acotado a
+// maxPartSize
+func (f xlsxFile) open(name string, fn func(r io.Reader) error) error {
+	part, ok := f[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrInvalid, name)
+	}
+	rc, err := part.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+	}
+	defer rc.Close()
+	return fn(io.LimitReader(rc, maxPartSize))
+}
+
+// cellValue devuelve el texto de una celda segun su tipo
+func cellValue(c xlsxCell, shared []xlsxText, dates map[int]bool) (string, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(shared) {
+			return "", fmt.Errorf("%w: cell %s references a missing string", ErrInvalid, c.Ref)
+		}
+		return shared[i].String(), nil
+	case "inlineStr":
+		return c.Inline.String(), nil
+	case "b":
+		return strconv.FormatBool(c.Value == "1"), nil
+	case "", "n":
+		if dates[c.Style] {
+			if serial, err := strconv.ParseFloat(c.Value, 64); err == nil {
+				return excelEpoch.AddDate(0, 0, int(math.Floor(serial))).Format("2006-01-02"), nil
+			}
+		}
+	}
+	return c.Value, nil
+}
+
+// dateStyles devuelve los estilos de celda cuyo formato numerico es una
+// fecha
+func dateStyles(styles xlsxStyles) map[int]bool {
+	codes := make(map[int]string, len(styles.NumFmts))
+	for _, f := range styles.NumFmts {
+		codes[f.Id] = f.Code
+	}
+	dates := map[int]bool{}
+	for i, xf := range styles.CellXfs {
+		if isDateFormat(xf.NumFmtId, codes[xf.NumFmtId]) {
+			dates[i] = true
+		}
+	}
+	return dates
+}
+
+// isDateFormat dice si el formato numerico id muestra una fecha: los
+// predefinidos de fecha o uno propio con dias o anios
+func isDateFormat(id int, code string) bool {
+	if (id >= 14 && id <= 17) || id == 22 {
+		return true
+	}
+	if code == "" {
+		return false
+	}
+	// lo que esta entre comillas o corchetes y lo escapado no es parte del
+	// formato
+	var plain strings.Builder
+	quoted, bracket, escaped := false, false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted:
+			quoted = r != '"'
+		case bracket:
+			bracket = r != ']'
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = true
+		case r == '[':
+			bracket = true
+		default:
+			plain.WriteRune(r)
+		}
+	}
+	return strings.ContainsAny(plain.String(), "dy")
+}
+
+// columnIndex devuelve la columna (desde 0) de una referencia como "AB12"
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A') + 1
+		if col > maxColumns {
+			return 0, fmt.Errorf("%w: invalid cell reference %q", ErrInvalid, ref)
+		}
+	}
+	if col == 0 {
+		return 0, fmt.Errorf("%w: invalid cell reference %q", ErrInvalid, ref)
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic escribe data en un archivo temporal del mismo directorio,
+// lo sincroniza a disco y lo renombra sobre path. Un lector (o un crash)
+// ve el archivo anterior completo o el nuevo completo, nunca uno a medias.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	// si algo falla antes del rename no dejamos basura en el directorio
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir persiste la entrada de directorio creada por el rename
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// algunos sistemas (windows) no permiten fsync sobre directorios
+	_ = d.Sync()
+	return nil
+}
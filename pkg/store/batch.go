@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+// ErrAborted indica un cambio valido de un lote atomico que no se aplico
+// porque otro cambio del lote fallo
+var ErrAborted = domain.Conflict("batch_aborted", "batch aborted by another change")
+
+// Change es un cambio de un lote: OpCreate u OpUpdate con Product, u
+// OpDelete con Id y la Version que tiene que estar guardada
+type Change struct {
+	Op      string
+	Id      int
+	Version int
+	Product domain.Product
+}
+
+// ChangeResult es el resultado de un Change: el producto como quedo
+// guardado (el borrado, para OpDelete) o el motivo por el que no se aplico
+type ChangeResult struct {
+	Product domain.Product
+	Err     error
+}
+
+// Batcher lo implementan los stores que aplican un lote de cambios en una
+// sola escritura. Los cambios se aplican en orden, con las mismas reglas que
+// AddOne, UpdateOne y DeleteOne, asi un code_value repetido dentro del lote
+// tambien es ErrDuplicate. Con atomic, si alguno falla no se aplica ninguno
+// y los validos quedan con ErrAborted; si no, se aplican los que no fallan.
+// El error que se devuelve aparte es de la escritura en si, y en ese caso no
+// se aplico nada.
+type Batcher interface {
+	Apply(ctx context.Context, changes []Change, atomic bool) ([]ChangeResult, error)
+}
+
+// applyChanges aplica changes sobre una copia de products, con lastID como
+// el ultimo id entregado. Devuelve los productos y el ultimo id resultantes,
+// el resultado de cada cambio y si se aplico alguno.
+func applyChanges(products []domain.Product, lastID int, changes []Change, atomic bool) ([]domain.Product, int, []ChangeResult, bool) {
+	out := make([]domain.Product, len(products))
+	copy(out, products)
+	startID := lastID
+	results := make([]ChangeResult, len(changes))
+	failed, applied := false, false
+	for i, c := range changes {
+		var r ChangeResult
+		out, lastID, r = applyChange(out, lastID, c)
+		results[i] = r
+		if r.Err != nil {
+			failed = true
+		} else {
+			applied = true
+		}
+	}
+	if atomic && failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = ChangeResult{Err: ErrAborted}
+			}
+		}
+		return products, startID, results, false
+	}
+	return out, lastID, results, applied
+}
+
+// applyChange aplica un cambio; si falla products queda como estaba
+func applyChange(products []domain.Product, lastID int, c Change) ([]domain.Product, int, ChangeResult) {
+	index := -1
+	id := c.Id
+	if c.Op == OpUpdate {
+		id = c.Product.Id
+	}
+	for i, p := range products {
+		if p.Id == id {
+			index = i
+			break
+		}
+	}
+	switch c.Op {
+	case OpCreate:
+		p := c.Product
+		if codeValueTaken(products, 0, p.CodeValue) {
+			return products, lastID, ChangeResult{Err: ErrDuplicate}
+		}
+		lastID++
+		p.Id, p.Version = lastID, 1
+		return append(products, p), lastID, ChangeResult{Product: p}
+	case OpUpdate:
+		p := c.Product
+		switch {
+		case index < 0:
+			return products, lastID, ChangeResult{Err: ErrNotFound}
+		case codeValueTaken(products, p.Id, p.CodeValue):
+			return products, lastID, ChangeResult{Err: ErrDuplicate}
+		}
+		if err := checkVersion(products[index], p); err != nil {
+			return products, lastID, ChangeResult{Err: err}
+		}
+		p.Version++
+		products[index] = p
+		return products, lastID, ChangeResult{Product: p}
+	case OpDelete:
+		if index < 0 {
+			return products, lastID, ChangeResult{Err: ErrNotFound}
+		}
+		if err := checkVersion(products[index], domain.Product{Version: c.Version}); err != nil {
+			return products, lastID, ChangeResult{Err: err}
+		}
+		deleted := products[index]
+		return append(products[:index], products[index+1:]...), lastID, ChangeResult{Product: deleted}
+	}
+	return products, lastID, ChangeResult{Err: errors.New("unknown change " + c.Op)}
+}
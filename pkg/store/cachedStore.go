@@ -0,0 +1,341 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+// fileStamp identifica una version del archivo por mtime, tamaño y hash del
+// contenido
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+// revision convierte el stamp en una Revision del store
+func (f fileStamp) revision() Revision {
+	return Revision{Tag: f.hash, ModTime: f.modTime}
+}
+
+// sameFile indica si dos stamps tienen el mismo mtime y tamaño
+func (f fileStamp) sameFile(g fileStamp) bool {
+	return f.modTime.Equal(g.modTime) && f.size == g.size
+}
+
+// contentHash devuelve el hash de data que se usa como Tag
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// statFile devuelve el fileStamp actual de path
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// errUnchanged corta un mutate que no tiene nada para guardar
+var errUnchanged = errors.New("nothing to save")
+
+// cachedStore mantiene los productos decodificados en memoria junto con un
+// indice id -> posicion. Las escrituras pasan por el jsonStore subyacente
+// (mismo lock y misma escritura atomica) y un poller recarga el cache si el
+// archivo cambia por fuera del proceso.
+type cachedStore struct {
+	file *jsonStore
+
+	mu       sync.RWMutex
+	products []domain.Product
+	index    map[int]int
+	stamp    fileStamp
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewCachedStore crea un store con cache sobre el archivo json en path. El
+// archivo se revisa cada interval; con interval <= 0 no se hace polling y
+// los cambios externos solo se detectan al escribir.
+func NewCachedStore(path string, interval time.Duration) (Store, error) {
+	c := &cachedStore{
+		file: &jsonStore{pathToFile: path},
+		stop: make(chan struct{}),
+	}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go c.poll(interval)
+	}
+	return c, nil
+}
+
+// Close detiene el poller
+func (c *cachedStore) Close() error {
+	c.closeOnce.Do(func() { close(c.stop) })
+	return nil
+}
+
+// poll revisa periodicamente si el archivo cambio y recarga el cache
+func (c *cachedStore) poll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			_ = c.reloadIfChanged()
+		}
+	}
+}
+
+// reload lee el archivo completo y reemplaza el cache
+func (c *cachedStore) reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.read(c.loadLocked)
+}
+
+// reloadIfChanged recarga el cache solo si el archivo cambio de mtime o tamaño
+func (c *cachedStore) reloadIfChanged() error {
+	stamp, err := statFile(c.file.pathToFile)
+	if err != nil {
+		return err
+	}
+	c.mu.RLock()
+	changed := !stamp.sameFile(c.stamp)
+	c.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+	return c.reload()
+}
+
+// loadLocked lee el archivo y reconstruye el cache; requiere c.mu y el lock
+// del archivo tomados
+func (c *cachedStore) loadLocked() error {
+	stamp, err := statFile(c.file.pathToFile)
+	if err != nil {
+		return err
+	}
+	products, hash, err := c.file.loadFile()
+	if err != nil {
+		return err
+	}
+	stamp.hash = hash
+	c.setLocked(products, stamp)
+	return nil
+}
+
+// refreshLocked recarga el cache si el archivo cambio desde la ultima lectura;
+// requiere c.mu y el lock del archivo tomados
+func (c *cachedStore) refreshLocked() error {
+	stamp, err := statFile(c.file.pathToFile)
+	if err != nil {
+		return err
+	}
+	if stamp.sameFile(c.stamp) {
+		return nil
+	}
+	return c.loadLocked()
+}
+
+// setLocked reemplaza el cache y reconstruye el indice
+func (c *cachedStore) setLocked(products []domain.Product, stamp fileStamp) {
+	c.products = products
+	c.index = make(map[int]int, len(products))
+	for i, p := range products {
+		if _, ok := c.index[p.Id]; !ok {
+			c.index[p.Id] = i
+		}
+	}
+	c.stamp = stamp
+}
+
+// mutate aplica fn sobre una copia del cache actualizado, guarda el resultado
+// en disco y recien entonces lo publica en memoria
+func (c *cachedStore) mutate(fn func(products []domain.Product) ([]domain.Product, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.write(func() error {
+		if err := c.refreshLocked(); err != nil {
+			return err
+		}
+		products := make([]domain.Product, len(c.products))
+		copy(products, c.products)
+		products, err := fn(products)
+		if err != nil {
+			return err
+		}
+		return c.saveLocked(products)
+	})
+}
+
+// saveLocked escribe products en disco y actualiza el cache
+func (c *cachedStore) saveLocked(products []domain.Product) error {
+	hash, err := c.file.saveFile(products)
+	if err != nil {
+		return err
+	}
+	stamp, err := statFile(c.file.pathToFile)
+	if err != nil {
+		return err
+	}
+	stamp.hash = hash
+	c.setLocked(products, stamp)
+	return nil
+}
+
+// loadProducts devuelve una copia de los productos en cache
+func (c *cachedStore) loadProducts() ([]domain.Product, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	products := make([]domain.Product, len(c.products))
+	copy(products, c.products)
+	return products, nil
+}
+
+// GetAll devuelve todos los productos
+func (c *cachedStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.loadProducts()
+}
+
+// GetOne devuelve un producto por su id
+func (c *cachedStore) GetOne(ctx context.Context, id int) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	i, ok := c.index[id]
+	if !ok {
+		return domain.Product{}, ErrNotFound
+	}
+	return c.products[i], nil
+}
+
+// AddOne agrega un nuevo producto
+func (c *cachedStore) AddOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	err := c.mutate(func(products []domain.Product) ([]domain.Product, error) {
+		if codeValueTaken(products, 0, product.CodeValue) {
+			return nil, ErrDuplicate
+		}
+		id, err := c.file.nextID(products)
+		if err != nil {
+			return nil, err
+		}
+		product.Id = id
+		product.Version = 1
+		return append(products, product), nil
+	})
+	if err != nil {
+		return domain.Product{}, err
+	}
+	return product, nil
+}
+
+// UpdateOne actualiza un producto
+func (c *cachedStore) UpdateOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	err := c.mutate(func(products []domain.Product) ([]domain.Product, error) {
+		i, ok := c.index[product.Id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		if codeValueTaken(products, product.Id, product.CodeValue) {
+			return nil, ErrDuplicate
+		}
+		if err := checkVersion(products[i], product); err != nil {
+			return nil, err
+		}
+		product.Version++
+		products[i] = product
+		return products, nil
+	})
+	if err != nil {
+		return domain.Product{}, err
+	}
+	return product, nil
+}
+
+// DeleteOne elimina un producto
+func (c *cachedStore) DeleteOne(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.mutate(func(products []domain.Product) ([]domain.Product, error) {
+		i, ok := c.index[id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		if err := c.file.advanceSequence(products); err != nil {
+			return nil, err
+		}
+		return append(products[:i], products[i+1:]...), nil
+	})
+}
+
+// Apply aplica el lote con una sola escritura del archivo
+func (c *cachedStore) Apply(ctx context.Context, changes []Change, atomic bool) ([]ChangeResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var results []ChangeResult
+	err := c.mutate(func(products []domain.Product) ([]domain.Product, error) {
+		var applied bool
+		var err error
+		products, applied, results, err = c.file.applyChanges(products, changes, atomic)
+		if err == nil && !applied {
+			err = errUnchanged
+		}
+		return products, err
+	})
+	if err != nil && !errors.Is(err, errUnchanged) {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Revision corresponde al archivo que esta en cache
+func (c *cachedStore) Revision(ctx context.Context) (Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return Revision{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stamp.revision(), nil
+}
+
+// CheckIntegrity delega en el archivo subyacente
+func (c *cachedStore) CheckIntegrity(ctx context.Context) (IntegrityReport, error) {
+	return c.file.CheckIntegrity(ctx)
+}
+
+// Repair repara el archivo subyacente y recarga el cache
+func (c *cachedStore) Repair(ctx context.Context) (IntegrityReport, error) {
+	report, err := c.file.Repair(ctx)
+	if err != nil {
+		return report, err
+	}
+	return report, c.reload()
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedStore_WriteThrough(t *testing.T) {
+	ctx := context.Background()
+	_, path := createTestStore(t, 5)
+	c, err := NewCachedStore(path, 0)
+	assert.Nil(t, err)
+
+	_, err = c.AddOne(ctx, domain.Product{Name: "new", CodeValue: "NEW"})
+	assert.Nil(t, err)
+	assert.Nil(t, c.DeleteOne(ctx, 2))
+	p, err := c.GetOne(ctx, 3)
+	assert.Nil(t, err)
+	p.Name = "updated"
+	_, err = c.UpdateOne(ctx, p)
+	assert.Nil(t, err)
+
+	// lo que quedo en disco coincide con el cache
+	onDisk, err := NewStore(path).GetAll(ctx)
+	assert.Nil(t, err)
+	cached, err := c.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, onDisk, cached)
+	assert.Equal(t, 6, cached[len(cached)-1].Id)
+
+	_, err = c.GetOne(ctx, 2)
+	assert.NotNil(t, err)
+}
+
+func TestCachedStore_ReloadsOnExternalChange(t *testing.T) {
+	ctx := context.Background()
+	_, path := createTestStore(t, 5)
+	c, err := NewCachedStore(path, 10*time.Millisecond)
+	assert.Nil(t, err)
+	defer c.(*cachedStore).Close()
+
+	bytes, _ := json.Marshal([]domain.Product{{Id: 42, Name: "edited by hand"}})
+	assert.Nil(t, os.WriteFile(path, bytes, 0644))
+
+	assert.Eventually(t, func() bool {
+		p, err := c.GetOne(ctx, 42)
+		return err == nil && p.Name == "edited by hand"
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Collection guarda documentos de tipo T por id, en memoria o en un archivo
+// json con el mismo esquema que el store de productos: lock exclusivo para
+// escribir y reemplazo atomico del archivo. La usan los modulos que no son
+// productos (carritos, ordenes) y no necesitan backends intercambiables.
+type Collection[T any] struct {
+	path string
+	mu   sync.RWMutex
+	// data es el contenido cuando la coleccion vive solo en memoria
+	data collectionData[T]
+}
+
+// collectionData es el contenido persistido de una Collection
+type collectionData[T any] struct {
+	LastID int       `json:"last_id"`
+	Items  map[int]T `json:"items"`
+}
+
+// NewCollection crea una coleccion guardada en el archivo json path; con
+// path vacio los documentos viven solo en memoria
+func NewCollection[T any](path string) *Collection[T] {
+	return &Collection[T]{path: path, data: collectionData[T]{Items: map[int]T{}}}
+}
+
+// load lee el contenido actual; requiere c.mu tomado
+func (c *Collection[T]) load() (collectionData[T], error) {
+	if c.path == "" {
+		data := collectionData[T]{LastID: c.data.LastID, Items: make(map[int]T, len(c.data.Items))}
+		for id, item := range c.data.Items {
+			data.Items[id] = item
+		}
+		return data, nil
+	}
+	data := collectionData[T]{Items: map[int]T{}}
+	file, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return data, err
+	}
+	if err := json.Unmarshal(file, &data); err != nil {
+		return data, err
+	}
+	if data.Items == nil {
+		data.Items = map[int]T{}
+	}
+	return data, nil
+}
+
+// save reemplaza el contenido; requiere c.mu tomado en modo exclusivo
+func (c *Collection[T]) save(data collectionData[T]) error {
+	if c.path == "" {
+		c.data = data
+		return nil
+	}
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, bytes, 0644)
+}
+
+// Get devuelve el documento id; ok es false si no existe
+func (c *Collection[T]) Get(ctx context.Context, id int) (item T, ok bool, err error) {
+	err = c.View(ctx, func(tx *CollectionTx[T]) error {
+		item, ok = tx.Get(id)
+		return nil
+	})
+	return item, ok, err
+}
+
+// View ejecuta fn sobre una copia de solo lectura de los documentos
+func (c *Collection[T]) View(ctx context.Context, fn func(tx *CollectionTx[T]) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.path != "" {
+		unlock, err := lockFile(c.path+".lock", false)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	data, err := c.load()
+	if err != nil {
+		return err
+	}
+	return fn(&CollectionTx[T]{data: data})
+}
+
+// Update ejecuta fn con los documentos bloqueados para escritura y guarda
+// los cambios solo si fn no devuelve error, de modo que la secuencia leer
+// -> validar -> guardar no se intercala con otra. En memoria los documentos
+// se copian superficialmente: fn debe reemplazar los slices y punteros que
+// cambia en lugar de modificarlos.
+func (c *Collection[T]) Update(ctx context.Context, fn func(tx *CollectionTx[T]) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path != "" {
+		unlock, err := lockFile(c.path+".lock", true)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	data, err := c.load()
+	if err != nil {
+		return err
+	}
+	tx := &CollectionTx[T]{data: data}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if !tx.dirty {
+		return nil
+	}
+	return c.save(tx.data)
+}
+
+// CollectionTx es la vista de los documentos dentro de View o Update
+type CollectionTx[T any] struct {
+	data  collectionData[T]
+	dirty bool
+}
+
+// Get devuelve el documento id; ok es false si no existe
+func (tx *CollectionTx[T]) Get(id int) (T, bool) {
+	item, ok := tx.data.Items[id]
+	return item, ok
+}
+
+// All devuelve los ids de los documentos en orden ascendente
+func (tx *CollectionTx[T]) All() []int {
+	ids := make([]int, 0, len(tx.data.Items))
+	for id := range tx.data.Items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// NextID reserva un id nuevo; los ids no se reutilizan aunque se borre el
+// documento
+func (tx *CollectionTx[T]) NextID() int {
+	tx.data.LastID++
+	tx.dirty = true
+	return tx.data.LastID
+}
+
+// Put guarda item con el id dado
+func (tx *CollectionTx[T]) Put(id int, item T) {
+	tx.data.Items[id] = item
+	tx.dirty = true
+}
+
+// Delete borra el documento id
+func (tx *CollectionTx[T]) Delete(id int) {
+	delete(tx.data.Items, id)
+	tx.dirty = true
+}
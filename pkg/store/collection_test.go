@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type doc struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestCollection_File(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "docs.json")
+	c := NewCollection[doc](path)
+
+	var first int
+	assert.Nil(t, c.Update(ctx, func(tx *CollectionTx[doc]) error {
+		first = tx.NextID()
+		tx.Put(first, doc{Name: "a"})
+		tx.Put(tx.NextID(), doc{Name: "b"})
+		return nil
+	}))
+	assert.Nil(t, c.Update(ctx, func(tx *CollectionTx[doc]) error {
+		tx.Delete(2)
+		return nil
+	}))
+
+	// un error descarta los cambios
+	boom := errors.New("boom")
+	assert.ErrorIs(t, c.Update(ctx, func(tx *CollectionTx[doc]) error {
+		tx.Put(first, doc{Name: "changed"})
+		return boom
+	}), boom)
+
+	// otra instancia sobre el mismo archivo ve lo guardado y no reutiliza ids
+	reopened := NewCollection[doc](path)
+	d, ok, err := reopened.Get(ctx, first)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, doc{Name: "a"}, d)
+	assert.Nil(t, reopened.Update(ctx, func(tx *CollectionTx[doc]) error {
+		assert.Equal(t, []int{1}, tx.All())
+		assert.Equal(t, 3, tx.NextID())
+		return nil
+	}))
+}
+
+func TestCollection_ConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "docs.json")} {
+		c := NewCollection[doc](path)
+		assert.Nil(t, c.Update(ctx, func(tx *CollectionTx[doc]) error {
+			tx.Put(tx.NextID(), doc{})
+			return nil
+		}))
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Nil(t, c.Update(ctx, func(tx *CollectionTx[doc]) error {
+					d, _ := tx.Get(1)
+					d.Count++
+					tx.Put(1, d)
+					return nil
+				}))
+			}()
+		}
+		wg.Wait()
+		d, _, err := c.Get(ctx, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 20, d.Count, path)
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+// InvalidDatesError lista los productos cuya fecha de expiracion no se
+// pudo interpretar al migrar un archivo json
+type InvalidDatesError struct {
+	// Dates guarda el texto original de cada fecha invalida por id
+	Dates map[int]string
+}
+
+func (e *InvalidDatesError) Error() string {
+	ids := make([]int, 0, len(e.Dates))
+	for id := range e.Dates {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("id %d: %q", id, e.Dates[id])
+	}
+	return fmt.Sprintf("%d invalid expiration dates (%s)", len(e.Dates), strings.Join(parts, ", "))
+}
+
+// MigrateJSONDates reescribe las fechas de expiracion del archivo json en path
+// con el formato configurado en domain
+func MigrateJSONDates(path string) (int, error) {
+	s := &jsonStore{pathToFile: path}
+	var migrated int
+	err := s.write(func() error {
+		file, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// primero se leen como texto, para poder informar todas las invalidas
+		var raw []struct {
+			Id         int    `json:"id"`
+			Expiration string `json:"expiration"`
+		}
+		if err := json.Unmarshal(file, &raw); err != nil {
+			return err
+		}
+		invalid := map[int]string{}
+		for _, p := range raw {
+			if _, err := domain.ParseDate(p.Expiration); err != nil {
+				invalid[p.Id] = p.Expiration
+			}
+		}
+		if len(invalid) > 0 {
+			return &InvalidDatesError{Dates: invalid}
+		}
+
+		products, err := s.loadProducts()
+		if err != nil {
+			return err
+		}
+		migrated = len(products)
+		return s.saveProducts(products)
+	})
+	return migrated, err
+}
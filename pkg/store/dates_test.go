@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateJSONDates(t *testing.T) {
+	defer domain.SetDateFormat("dd/mm/yyyy")
+	path := filepath.Join(t.TempDir(), "products.json")
+	data := `[{"id":1,"name":"a","code_value":"A","expiration":"15/12/2021"},{"id":2,"name":"b","code_value":"B","expiration":"1/2/2023"}]`
+	assert.Nil(t, os.WriteFile(path, []byte(data), 0644))
+
+	assert.Nil(t, domain.SetDateFormat("yyyy-mm-dd"))
+	n, err := MigrateJSONDates(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	file, _ := os.ReadFile(path)
+	assert.Contains(t, string(file), `"expiration":"2021-12-15"`)
+	assert.Contains(t, string(file), `"expiration":"2023-02-01"`)
+
+	products, err := NewStore(path).GetAll(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, domain.NewDate(2023, time.February, 1), products[1].Expiration)
+}
+
+func TestMigrateJSONDates_ReportsEveryInvalidDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	data := `[{"id":1,"expiration":"31/02/2022"},{"id":2,"expiration":"15/12/2021"},{"id":3,"expiration":"99/99/2020"}]`
+	assert.Nil(t, os.WriteFile(path, []byte(data), 0644))
+
+	_, err := MigrateJSONDates(path)
+	var invalid *InvalidDatesError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Equal(t, map[int]string{1: "31/02/2022", 3: "99/99/2020"}, invalid.Dates)
+	file, _ := os.ReadFile(path)
+	assert.Equal(t, data, string(file))
+}
+
+func TestSQLiteStore_MigratesOldDates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.db")
+	s, err := NewSQLiteStore(path)
+	assert.Nil(t, err)
+	s.(*sqliteStore).Close()
+
+	// una fila escrita cuando expiration era un string libre
+	db, err := sql.Open("sqlite", path)
+	assert.Nil(t, err)
+	_, err = db.Exec(`INSERT INTO products (id, name, quantity, code_value, is_published, expiration, price, version) VALUES (1, 'a', 1, 'A', 0, '5/1/2022', 1, 1)`)
+	assert.Nil(t, err)
+	db.Close()
+
+	s, err = NewSQLiteStore(path)
+	assert.Nil(t, err)
+	defer s.(*sqliteStore).Close()
+	var stored string
+	assert.Nil(t, s.(*sqliteStore).db.QueryRow("SELECT expiration FROM products WHERE id = 1").Scan(&stored))
+	assert.Equal(t, "2022-01-05", stored)
+	p, err := s.GetOne(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.NewDate(2022, time.January, 5), p.Expiration)
+}
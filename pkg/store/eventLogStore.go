@@ -0,0 +1,500 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+func init() {
+	Register("eventlog", func(cfg Config) (Store, error) {
+		compactEvery := defaultCompactEvery
+		if v := cfg.Options["compact_every"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid compact_every %q: %w", v, err)
+			}
+			compactEvery = n
+		}
+		return NewEventLogStore(cfg.DSN, compactEvery)
+	})
+}
+
+// defaultCompactEvery es la cantidad de eventos entre snapshots
+const defaultCompactEvery = 1000
+
+// Operaciones que se registran en el log
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Event es una linea del log: un alta, modificacion o baja de un producto
+type Event struct {
+	Seq     int64           `json:"seq"`
+	Op      string          `json:"op"`
+	Time    time.Time       `json:"time"`
+	Id      int             `json:"id"`
+	Product *domain.Product `json:"product,omitempty"`
+}
+
+// Historian lo implementan los stores que conservan el historial de cambios
+type Historian interface {
+	History(ctx context.Context, id int) ([]Event, error)
+}
+
+// snapshot es el estado completo al momento del evento Seq
+type snapshot struct {
+	Seq      int64            `json:"seq"`
+	Time     time.Time        `json:"time"`
+	LastID   int              `json:"last_id"`
+	Products []domain.Product `json:"products"`
+}
+
+// eventLogStore guarda cada cambio como una linea NDJSON y el estado en memoria
+type eventLogStore struct {
+	path         string
+	compactEvery int
+
+	mu       sync.RWMutex
+	log      logFile
+	unlock   func()
+	seq      int64
+	lastID   int
+	products map[int]domain.Product
+	codes    map[string]int
+	pending  int
+	modTime  time.Time
+}
+
+// logFile es lo que el store usa del archivo del log; *os.File lo cumple
+type logFile interface {
+	io.WriteCloser
+	Sync() error
+	Stat() (os.FileInfo, error)
+	Truncate(size int64) error
+}
+
+// openLog abre el log para agregar eventos
+func openLog(path string) (logFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// NewEventLogStore abre (o crea) el log en path con un flock exclusivo
+func NewEventLogStore(path string, compactEvery int) (Store, error) {
+	if compactEvery <= 0 {
+		compactEvery = defaultCompactEvery
+	}
+	unlock, err := lockFile(path+".lock", true)
+	if err != nil {
+		return nil, err
+	}
+	s := &eventLogStore{
+		path:         path,
+		compactEvery: compactEvery,
+		unlock:       unlock,
+		products:     map[int]domain.Product{},
+		codes:        map[string]int{},
+	}
+	if err := s.recover(); err != nil {
+		unlock()
+		return nil, err
+	}
+	s.log, err = openLog(path)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close cierra el log y libera el flock
+func (s *eventLogStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	if s.log != nil {
+		err = s.log.Close()
+	}
+	s.unlock()
+	return err
+}
+
+// snapshotPath devuelve la ruta del snapshot
+func (s *eventLogStore) snapshotPath() string {
+	return s.path + ".snapshot"
+}
+
+// recover carga el snapshot y reproduce el log
+func (s *eventLogStore) recover() error {
+	file, err := os.ReadFile(s.snapshotPath())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		var snap snapshot
+		if err := json.Unmarshal(file, &snap); err != nil {
+			return fmt.Errorf("reading snapshot: %w", err)
+		}
+		s.seq, s.lastID, s.modTime = snap.Seq, snap.LastID, snap.Time
+		for _, p := range snap.Products {
+			s.products[p.Id] = p
+			s.codes[p.CodeValue] = p.Id
+		}
+	}
+	return s.replay()
+}
+
+// replay aplica los eventos del log posteriores al snapshot
+func (s *eventLogStore) replay() error {
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	for line := 1; ; line++ {
+		raw, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(raw) > 0 {
+				// ultima linea sin '\n': el append no llego a completarse
+				return f.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var e Event
+		if err := json.Unmarshal(bytes.TrimSpace(raw), &e); err != nil {
+			return fmt.Errorf("%s:%d: corrupted event: %w", s.path, line, err)
+		}
+		offset += int64(len(raw))
+		if e.Seq <= s.seq {
+			// ya incluido en el snapshot
+			continue
+		}
+		s.apply(e)
+		s.pending++
+	}
+}
+
+// apply actualiza los productos y el indice de code_value con el evento
+func (s *eventLogStore) apply(e Event) {
+	s.seq = e.Seq
+	s.modTime = e.Time
+	if old, ok := s.products[e.Id]; ok && s.codes[old.CodeValue] == e.Id {
+		delete(s.codes, old.CodeValue)
+	}
+	switch e.Op {
+	case OpCreate, OpUpdate:
+		s.products[e.Id] = *e.Product
+		s.codes[e.Product.CodeValue] = e.Id
+		if e.Id > s.lastID {
+			s.lastID = e.Id
+		}
+	case OpDelete:
+		delete(s.products, e.Id)
+	}
+}
+
+// codeValueTaken indica si codeValue pertenece a un producto distinto de id
+func (s *eventLogStore) codeValueTaken(id int, codeValue string) bool {
+	owner, ok := s.codes[codeValue]
+	return ok && owner != id
+}
+
+// append guarda los eventos en el log y los aplica; requiere s.mu tomado
+func (s *eventLogStore) append(events ...Event) error {
+	now := time.Now().UTC()
+	var lines []byte
+	for i := range events {
+		events[i].Seq = s.seq + int64(i) + 1
+		events[i].Time = now
+		line, err := json.Marshal(events[i])
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	if s.log == nil {
+		var err error
+		if s.log, err = openLog(s.path); err != nil {
+			return err
+		}
+	}
+	info, err := s.log.Stat()
+	if err != nil {
+		return err
+	}
+	if err := s.write(lines); err != nil {
+		// sin lineas a medias ni eventos guardados que no se aplicaron
+		_ = s.log.Truncate(info.Size())
+		return err
+	}
+	for _, e := range events {
+		s.apply(e)
+		s.pending++
+	}
+	if s.pending >= s.compactEvery {
+		// el evento ya esta guardado: si falla se reintenta en el proximo
+		if err := s.compactLocked(); err != nil {
+			log.Printf("event log %s: compaction failed: %v", s.path, err)
+		}
+	}
+	return nil
+}
+
+// write escribe lines en el log y las sincroniza a disco
+func (s *eventLogStore) write(lines []byte) error {
+	if _, err := s.log.Write(lines); err != nil {
+		return err
+	}
+	return s.log.Sync()
+}
+
+// Compact escribe un snapshot del estado actual y archiva el log
+func (s *eventLogStore) Compact(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compactLocked()
+}
+
+// compactLocked guarda el snapshot y despues rota el log
+func (s *eventLogStore) compactLocked() error {
+	bytes, err := json.Marshal(snapshot{Seq: s.seq, Time: s.modTime, LastID: s.lastID, Products: s.sorted()})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.snapshotPath(), bytes, 0644); err != nil {
+		return err
+	}
+	closeErr := s.log.Close()
+	var renameErr error
+	if closeErr == nil {
+		renameErr = os.Rename(s.path, fmt.Sprintf("%s.%d", s.path, s.seq))
+	}
+	// se reabre aunque haya fallado, para seguir agregando al log actual
+	if s.log, err = openLog(s.path); err != nil {
+		return err
+	}
+	if err := errors.Join(closeErr, renameErr); err != nil {
+		return err
+	}
+	s.pending = 0
+	return syncDir(filepath.Dir(s.path))
+}
+
+// sorted devuelve los productos ordenados por id
+func (s *eventLogStore) sorted() []domain.Product {
+	products := make([]domain.Product, 0, len(s.products))
+	for _, p := range s.products {
+		products = append(products, p)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].Id < products[j].Id })
+	return products
+}
+
+// History devuelve en orden los eventos de un producto, archivados incluidos
+func (s *eventLogStore) History(ctx context.Context, id int) ([]Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	segments, err := filepath.Glob(s.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	type segment struct {
+		path string
+		seq  int64
+	}
+	var archived []segment
+	for _, path := range segments {
+		seq, err := strconv.ParseInt(filepath.Ext(path)[1:], 10, 64)
+		if err != nil {
+			// .snapshot, .lock, etc.
+			continue
+		}
+		archived = append(archived, segment{path, seq})
+	}
+	sort.Slice(archived, func(i, j int) bool { return archived[i].seq < archived[j].seq })
+
+	events := []Event{}
+	for _, seg := range append(archived, segment{path: s.path}) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		found, err := readEvents(seg.path, id)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, found...)
+	}
+	return events, nil
+}
+
+// readEvents lee los eventos completos de path que corresponden a id
+func readEvents(path string, id int) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if e.Id == id {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Revision es el numero del ultimo evento aplicado
+func (s *eventLogStore) Revision(ctx context.Context) (Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return Revision{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Revision{Tag: strconv.FormatInt(s.seq, 10), ModTime: s.modTime}, nil
+}
+
+// GetAll devuelve todos los productos ordenados por id
+func (s *eventLogStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted(), nil
+}
+
+// GetOne devuelve un producto por su id
+func (s *eventLogStore) GetOne(ctx context.Context, id int) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.products[id]
+	if !ok {
+		return domain.Product{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// AddOne agrega un nuevo producto
+func (s *eventLogStore) AddOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.codeValueTaken(0, product.CodeValue) {
+		return domain.Product{}, ErrDuplicate
+	}
+	product.Id = s.lastID + 1
+	product.Version = 1
+	if err := s.append(Event{Op: OpCreate, Id: product.Id, Product: &product}); err != nil {
+		return domain.Product{}, err
+	}
+	return product, nil
+}
+
+// UpdateOne actualiza un producto
+func (s *eventLogStore) UpdateOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.products[product.Id]
+	if !ok {
+		return domain.Product{}, ErrNotFound
+	}
+	if s.codeValueTaken(product.Id, product.CodeValue) {
+		return domain.Product{}, ErrDuplicate
+	}
+	if err := checkVersion(stored, product); err != nil {
+		return domain.Product{}, err
+	}
+	product.Version++
+	if err := s.append(Event{Op: OpUpdate, Id: product.Id, Product: &product}); err != nil {
+		return domain.Product{}, err
+	}
+	return product, nil
+}
+
+// DeleteOne elimina un producto
+func (s *eventLogStore) DeleteOne(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.products[id]; !ok {
+		return ErrNotFound
+	}
+	return s.append(Event{Op: OpDelete, Id: id})
+}
+
+// Apply agrega un evento por cada cambio aplicado, todos en un solo write
+func (s *eventLogStore) Apply(ctx context.Context, changes []Change, atomic bool) ([]ChangeResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _, results, applied := applyChanges(s.sorted(), s.lastID, changes, atomic)
+	if !applied {
+		return results, nil
+	}
+	var events []Event
+	for i, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		e := Event{Op: changes[i].Op, Id: r.Product.Id}
+		if changes[i].Op != OpDelete {
+			p := r.Product
+			e.Product = &p
+		}
+		events = append(events, e)
+	}
+	if err := s.append(events...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLogStore_ReplayAndCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.log")
+	s, err := NewEventLogStore(path, 3)
+	assert.Nil(t, err)
+
+	a, err := s.AddOne(ctx, domain.Product{Name: "a", CodeValue: "A"})
+	assert.Nil(t, err)
+	b, err := s.AddOne(ctx, domain.Product{Name: "b", CodeValue: "B"})
+	assert.Nil(t, err)
+	a.Name = "a2"
+	// tercer evento: dispara la compactacion
+	_, err = s.UpdateOne(ctx, a)
+	assert.Nil(t, err)
+	assert.Nil(t, s.DeleteOne(ctx, b.Id))
+	_, err = s.AddOne(ctx, domain.Product{Name: "dup", CodeValue: "A"})
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.Nil(t, s.(*eventLogStore).Close())
+
+	s, err = NewEventLogStore(path, 3)
+	assert.Nil(t, err)
+	defer s.(*eventLogStore).Close()
+	products, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.Product{{Id: 1, Name: "a2", CodeValue: "A", Version: 2}}, products)
+
+	// el id de b no se reutiliza
+	c, err := s.AddOne(ctx, domain.Product{Name: "c", CodeValue: "C"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, c.Id)
+
+	history, err := s.(Historian).History(ctx, a.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, OpCreate, history[0].Op)
+	assert.Equal(t, OpUpdate, history[1].Op)
+}
+
+func TestEventLogStore_PartialLastLine(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.log")
+	s, err := NewEventLogStore(path, 100)
+	assert.Nil(t, err)
+	_, err = s.AddOne(ctx, domain.Product{Name: "a", CodeValue: "A"})
+	assert.Nil(t, err)
+	assert.Nil(t, s.(*eventLogStore).Close())
+
+	// simula un crash en medio del append
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"seq":2,"op":"create","id":2,"prod`)
+	assert.Nil(t, err)
+	f.Close()
+
+	s, err = NewEventLogStore(path, 100)
+	assert.Nil(t, err)
+	products, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(products))
+	b, err := s.AddOne(ctx, domain.Product{Name: "b", CodeValue: "B"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, b.Id)
+	assert.Nil(t, s.(*eventLogStore).Close())
+
+	s, err = NewEventLogStore(path, 100)
+	assert.Nil(t, err)
+	defer s.(*eventLogStore).Close()
+	products, err = s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(products))
+}
+
+// failingLog escribe en el archivo pero falla el Sync mientras fail es true
+type failingLog struct {
+	logFile
+	fail bool
+}
+
+func (f *failingLog) Sync() error {
+	if f.fail {
+		return errors.New("input/output error")
+	}
+	return f.logFile.Sync()
+}
+
+func TestEventLogStore_FailedAppend(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.log")
+	s, err := NewEventLogStore(path, 100)
+	assert.Nil(t, err)
+	store := s.(*eventLogStore)
+	_, err = s.AddOne(ctx, domain.Product{Name: "a", CodeValue: "A"})
+	assert.Nil(t, err)
+
+	broken := &failingLog{logFile: store.log, fail: true}
+	store.log = broken
+	_, err = s.AddOne(ctx, domain.Product{Name: "b", CodeValue: "B"})
+	assert.NotNil(t, err)
+	products, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, products, 1)
+
+	// el reintento agrega el evento una sola vez
+	broken.fail = false
+	b, err := s.AddOne(ctx, domain.Product{Name: "b", CodeValue: "B"})
+	assert.Nil(t, err)
+	assert.Nil(t, store.Close())
+
+	s, err = NewEventLogStore(path, 100)
+	assert.Nil(t, err)
+	defer s.(*eventLogStore).Close()
+	products, err = s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, b, products[1])
+}
+
+func TestEventLogStore_FailedCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.log")
+	s, err := NewEventLogStore(path, 2)
+	assert.Nil(t, err)
+	defer s.(*eventLogStore).Close()
+
+	// el archivo del log archivado no se puede crear
+	assert.Nil(t, os.MkdirAll(filepath.Join(path+".2", "busy"), 0755))
+	_, err = s.AddOne(ctx, domain.Product{Name: "a", CodeValue: "A"})
+	assert.Nil(t, err)
+	b, err := s.AddOne(ctx, domain.Product{Name: "b", CodeValue: "B"})
+	assert.Nil(t, err, "the event was saved even if compaction failed")
+	assert.Equal(t, 2, s.(*eventLogStore).pending)
+
+	// el log sigue abierto y la compactacion se reintenta con el proximo
+	// evento
+	b.Name = "b2"
+	_, err = s.UpdateOne(ctx, b)
+	assert.Nil(t, err)
+	_, err = os.Stat(path + ".3")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, s.(*eventLogStore).pending)
+	products, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, products, 2)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package store
+
+// lockFile no tiene soporte de flock en esta plataforma; el mutex del store
+// sigue protegiendo los accesos dentro del mismo proceso.
+func lockFile(path string, exclusive bool) (func(), error) {
+	return func() {}, nil
+}
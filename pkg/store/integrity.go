@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IntegrityChecker lo implementan los stores que pueden verificar y
+// reparar su contenido al arrancar
+type IntegrityChecker interface {
+	CheckIntegrity(ctx context.Context) (IntegrityReport, error)
+	Repair(ctx context.Context) (IntegrityReport, error)
+}
+
+// DuplicateID describe un id que aparece mas de una vez
+type DuplicateID struct {
+	Id    int `json:"id"`
+	Count int `json:"count"`
+}
+
+// Reassignment describe un producto al que Repair le dio un id nuevo
+type Reassignment struct {
+	CodeValue string `json:"code_value"`
+	OldID     int    `json:"old_id"`
+	NewID     int    `json:"new_id"`
+}
+
+// IntegrityReport es el resultado de revisar el archivo de productos
+type IntegrityReport struct {
+	Products   int            `json:"products"`
+	MaxID      int            `json:"max_id"`
+	LastID     int            `json:"last_id"`
+	Duplicates []DuplicateID  `json:"duplicates"`
+	Reassigned []Reassignment `json:"reassigned,omitempty"`
+}
+
+// Ok indica si no se encontraron problemas
+func (r IntegrityReport) Ok() bool {
+	return len(r.Duplicates) == 0
+}
+
+// String resume el reporte en una linea apta para logs
+func (r IntegrityReport) String() string {
+	if r.Ok() && len(r.Reassigned) == 0 {
+		return fmt.Sprintf("%d products, max id %d, sequence %d, no duplicates", r.Products, r.MaxID, r.LastID)
+	}
+	var parts []string
+	for _, d := range r.Duplicates {
+		parts = append(parts, fmt.Sprintf("id %d x%d", d.Id, d.Count))
+	}
+	for _, re := range r.Reassigned {
+		parts = append(parts, fmt.Sprintf("%s: %d -> %d", re.CodeValue, re.OldID, re.NewID))
+	}
+	return fmt.Sprintf("%d products, max id %d, sequence %d: %s", r.Products, r.MaxID, r.LastID, strings.Join(parts, ", "))
+}
+
+// CheckIntegrity busca ids duplicados en el archivo sin modificarlo
+func (s *jsonStore) CheckIntegrity(ctx context.Context) (IntegrityReport, error) {
+	var report IntegrityReport
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	err := s.read(func() error {
+		products, err := s.loadProducts()
+		if err != nil {
+			return err
+		}
+		seq, err := s.loadSequence()
+		if err != nil {
+			return err
+		}
+		counts := map[int]int{}
+		for _, p := range products {
+			counts[p.Id]++
+		}
+		for id, n := range counts {
+			if n > 1 {
+				report.Duplicates = append(report.Duplicates, DuplicateID{Id: id, Count: n})
+			}
+		}
+		sort.Slice(report.Duplicates, func(i, j int) bool {
+			return report.Duplicates[i].Id < report.Duplicates[j].Id
+		})
+		report.Products = len(products)
+		report.MaxID = maxID(products)
+		report.LastID = seq.LastID
+		return nil
+	})
+	return report, err
+}
+
+// Repair conserva la primera aparicion de cada id y asigna ids nuevos de la
+// secuencia al resto. Tambien adelanta la secuencia si quedo por detras del
+// maximo id presente.
+func (s *jsonStore) Repair(ctx context.Context) (IntegrityReport, error) {
+	var report IntegrityReport
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	err := s.write(func() error {
+		products, err := s.loadProducts()
+		if err != nil {
+			return err
+		}
+		seq, err := s.loadSequence()
+		if err != nil {
+			return err
+		}
+		if max := maxID(products); max > seq.LastID {
+			seq.LastID = max
+		}
+		seen := map[int]bool{}
+		counts := map[int]int{}
+		for i, p := range products {
+			counts[p.Id]++
+			if !seen[p.Id] {
+				seen[p.Id] = true
+				continue
+			}
+			seq.LastID++
+			products[i].Id = seq.LastID
+			report.Reassigned = append(report.Reassigned, Reassignment{CodeValue: p.CodeValue, OldID: p.Id, NewID: seq.LastID})
+		}
+		for id, n := range counts {
+			if n > 1 {
+				report.Duplicates = append(report.Duplicates, DuplicateID{Id: id, Count: n})
+			}
+		}
+		sort.Slice(report.Duplicates, func(i, j int) bool {
+			return report.Duplicates[i].Id < report.Duplicates[j].Id
+		})
+		if err := s.saveSequence(seq); err != nil {
+			return err
+		}
+		if len(report.Reassigned) > 0 {
+			if err := s.saveProducts(products); err != nil {
+				return err
+			}
+		}
+		report.Products = len(products)
+		report.MaxID = maxID(products)
+		report.LastID = seq.LastID
+		return nil
+	})
+	return report, err
+}
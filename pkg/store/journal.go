@@ -0,0 +1,182 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Journal guarda documentos de tipo T que no cambian, uno por linea NDJSON
+type Journal[T any] struct {
+	path string
+	open func(path string) (logFile, error)
+	mu   sync.Mutex
+	// offset son los bytes del archivo ya cargados en items
+	items  []T
+	offset int64
+}
+
+// NewJournal crea un journal en el archivo path, o en memoria si path es vacio
+func NewJournal[T any](path string) *Journal[T] {
+	return &Journal[T]{path: path, open: openLog}
+}
+
+// load agrega a j.items las lineas escritas desde la ultima carga
+func (j *Journal[T]) load() error {
+	if j.path == "" {
+		return nil
+	}
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		j.items, j.offset = nil, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < j.offset {
+		// el archivo se reemplazo
+		j.items, j.offset = nil, 0
+	}
+	if info.Size() == j.offset {
+		return nil
+	}
+	if _, err := f.Seek(j.offset, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(f)
+	for {
+		raw, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// una linea sin '\n' es un append que no se completo
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var item T
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&item); err != nil {
+			return fmt.Errorf("%s: corrupted line at byte %d: %w", j.path, j.offset, err)
+		}
+		j.items = append(j.items, item)
+		j.offset += int64(len(raw))
+	}
+}
+
+// View ejecuta fn sobre los documentos guardados
+func (j *Journal[T]) View(ctx context.Context, fn func(tx *JournalTx[T]) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.path != "" {
+		unlock, err := lockFile(j.path+".lock", false)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	if err := j.load(); err != nil {
+		return err
+	}
+	return fn(&JournalTx[T]{items: j.items})
+}
+
+// Update ejecuta fn con el journal bloqueado y agrega lo nuevo si fn no falla
+func (j *Journal[T]) Update(ctx context.Context, fn func(tx *JournalTx[T]) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.path != "" {
+		unlock, err := lockFile(j.path+".lock", true)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	if err := j.load(); err != nil {
+		return err
+	}
+	tx := &JournalTx[T]{items: j.items}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.added) == 0 {
+		return nil
+	}
+	if err := j.append(tx.added); err != nil {
+		return err
+	}
+	j.items = append(j.items, tx.added...)
+	return nil
+}
+
+// append escribe items al final del archivo y los sincroniza a disco
+func (j *Journal[T]) append(items []T) error {
+	if j.path == "" {
+		return nil
+	}
+	var lines []byte
+	for _, item := range items {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	f, err := j.open(j.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// descarta una linea a medias de un append anterior que no se completo
+	if err := f.Truncate(j.offset); err != nil {
+		return err
+	}
+	if _, err = f.Write(lines); err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		_ = f.Truncate(j.offset)
+		return err
+	}
+	j.offset += int64(len(lines))
+	return nil
+}
+
+// JournalTx es la vista de los documentos dentro de View o Update
+type JournalTx[T any] struct {
+	items []T
+	added []T
+}
+
+// All devuelve los documentos en el orden en que se agregaron
+func (tx *JournalTx[T]) All() []T {
+	return append(tx.items[:len(tx.items):len(tx.items)], tx.added...)
+}
+
+// NextID devuelve la posicion del proximo documento en el journal, desde 1
+func (tx *JournalTx[T]) NextID() int {
+	return len(tx.items) + len(tx.added) + 1
+}
+
+// Add agrega item al final del journal
+func (tx *JournalTx[T]) Add(item T) {
+	tx.added = append(tx.added, item)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal_File(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "docs.jsonl")
+	j := NewJournal[doc](path)
+
+	assert.Nil(t, j.Update(ctx, func(tx *JournalTx[doc]) error {
+		assert.Equal(t, 1, tx.NextID())
+		tx.Add(doc{Name: "a", Count: 1})
+		assert.Equal(t, 2, tx.NextID())
+		tx.Add(doc{Name: "b", Count: tx.NextID()})
+		return nil
+	}))
+	boom := errors.New("boom")
+	assert.ErrorIs(t, j.Update(ctx, func(tx *JournalTx[doc]) error {
+		tx.Add(doc{Name: "discarded"})
+		return boom
+	}), boom)
+
+	// otro proceso agrega despues de un append que quedo a medias
+	other := NewJournal[doc](path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"name":"tor`)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+	assert.Nil(t, other.Update(ctx, func(tx *JournalTx[doc]) error {
+		assert.Len(t, tx.All(), 2)
+		tx.Add(doc{Name: "c"})
+		return nil
+	}))
+
+	// cada linea es un documento y la que quedo a medias se descarto
+	file, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"name\":\"a\",\"count\":1}\n{\"name\":\"b\",\"count\":2}\n{\"name\":\"c\",\"count\":0}\n", string(file))
+	assert.Nil(t, j.View(ctx, func(tx *JournalTx[doc]) error {
+		assert.Equal(t, []doc{{Name: "a", Count: 1}, {Name: "b", Count: 2}, {Name: "c"}}, tx.All())
+		assert.Equal(t, 4, tx.NextID())
+		return nil
+	}))
+}
+
+func TestJournal_FailedAppend(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "docs.jsonl")
+	j := NewJournal[doc](path)
+	assert.Nil(t, j.Update(ctx, func(tx *JournalTx[doc]) error {
+		tx.Add(doc{Name: "a"})
+		return nil
+	}))
+
+	j.open = func(path string) (logFile, error) {
+		f, err := openLog(path)
+		return &failingLog{logFile: f, fail: true}, err
+	}
+	assert.NotNil(t, j.Update(ctx, func(tx *JournalTx[doc]) error {
+		tx.Add(doc{Name: "b"})
+		return nil
+	}))
+
+	// el archivo no conserva la linea que no se pudo sincronizar
+	reopened := NewJournal[doc](path)
+	assert.Nil(t, reopened.View(ctx, func(tx *JournalTx[doc]) error {
+		assert.Equal(t, []doc{{Name: "a"}}, tx.All())
+		return nil
+	}))
+	assert.Nil(t, j.View(ctx, func(tx *JournalTx[doc]) error {
+		assert.Equal(t, []doc{{Name: "a"}}, tx.All())
+		return nil
+	}))
+}
@@ -1,48 +1,103 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/fgiudicatti-meli/web-server/internal/domain"
 )
 
-type Store interface {
-	GetAll() ([]domain.Product, error)
-	GetOne(id int) (domain.Product, error)
-	AddOne(product domain.Product) error
-	UpdateOne(product domain.Product) error
-	DeleteOne(id int) error
-	saveProducts(products []domain.Product) error
-	loadProducts() ([]domain.Product, error)
+func init() {
+	Register("json", openJSON)
+}
+
+// openJSON abre el backend "json". Con la opcion cache=true los productos se
+// mantienen en memoria y el archivo se revisa cada poll (por ejemplo "2s").
+func openJSON(cfg Config) (Store, error) {
+	if cfg.Options["cache"] != "true" {
+		return NewStore(cfg.DSN), nil
+	}
+	var interval time.Duration
+	if poll := cfg.Options["poll"]; poll != "" {
+		var err error
+		if interval, err = time.ParseDuration(poll); err != nil {
+			return nil, err
+		}
+	}
+	return NewCachedStore(cfg.DSN, interval)
 }
 
 type jsonStore struct {
 	pathToFile string
+	// mu serializa las mutaciones dentro del proceso; el flock sobre
+	// pathToFile+".lock" hace lo mismo entre procesos
+	mu sync.RWMutex
 }
 
 // loadProducts carga los productos desde un archivo json
 func (s *jsonStore) loadProducts() ([]domain.Product, error) {
+	products, _, err := s.loadFile()
+	return products, err
+}
+
+// loadFile carga los productos junto con el hash del contenido del archivo
+func (s *jsonStore) loadFile() ([]domain.Product, string, error) {
 	var products []domain.Product
 	file, err := os.ReadFile(s.pathToFile)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	err = json.Unmarshal([]byte(file), &products)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return products, nil
+	return products, contentHash(file), nil
 }
 
-// saveProducts guarda los productos en un archivo json
+// saveProducts guarda los productos en un archivo json de forma atomica
 func (s *jsonStore) saveProducts(products []domain.Product) error {
+	_, err := s.saveFile(products)
+	return err
+}
+
+// saveFile guarda los productos y devuelve el hash del contenido escrito
+func (s *jsonStore) saveFile(products []domain.Product) (string, error) {
 	bytes, err := json.Marshal(products)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(s.pathToFile, bytes, 0644); err != nil {
+		return "", err
+	}
+	return contentHash(bytes), nil
+}
+
+// read ejecuta fn con un lock compartido sobre el archivo
+func (s *jsonStore) read(fn func() error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	unlock, err := lockFile(s.pathToFile+".lock", false)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.pathToFile, bytes, 0644)
+	defer unlock()
+	return fn()
+}
+
+// write ejecuta fn con un lock exclusivo sobre el archivo, de modo que
+// la secuencia leer -> modificar -> guardar no se intercale con otra
+func (s *jsonStore) write(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.pathToFile+".lock", true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
 }
 
 // NewJsonStore crea un nuevo store de products
@@ -53,8 +108,15 @@ func NewStore(path string) Store {
 }
 
 // GetAll devuelve todos los productos
-func (s *jsonStore) GetAll() ([]domain.Product, error) {
-	products, err := s.loadProducts()
+func (s *jsonStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var products []domain.Product
+	err := s.read(func() (err error) {
+		products, err = s.loadProducts()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -62,8 +124,8 @@ func (s *jsonStore) GetAll() ([]domain.Product, error) {
 }
 
 // GetOne devuelve un producto por su id
-func (s *jsonStore) GetOne(id int) (domain.Product, error) {
-	products, err := s.loadProducts()
+func (s *jsonStore) GetOne(ctx context.Context, id int) (domain.Product, error) {
+	products, err := s.GetAll(ctx)
 	if err != nil {
 		return domain.Product{}, err
 	}
@@ -72,46 +134,167 @@ func (s *jsonStore) GetOne(id int) (domain.Product, error) {
 			return product, nil
 		}
 	}
-	return domain.Product{}, errors.New("product not found")
+	return domain.Product{}, ErrNotFound
 }
 
 // AddOne agrega un nuevo producto
-func (s *jsonStore) AddOne(product domain.Product) error {
-	products, err := s.loadProducts()
+func (s *jsonStore) AddOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	err := s.write(func() error {
+		products, err := s.loadProducts()
+		if err != nil {
+			return err
+		}
+		if codeValueTaken(products, 0, product.CodeValue) {
+			return ErrDuplicate
+		}
+		id, err := s.nextID(products)
+		if err != nil {
+			return err
+		}
+		product.Id = id
+		product.Version = 1
+		products = append(products, product)
+		return s.saveProducts(products)
+	})
 	if err != nil {
-		return err
+		return domain.Product{}, err
 	}
-	product.Id = len(products) + 1
-	products = append(products, product)
-	return s.saveProducts(products)
+	return product, nil
 }
 
 // UpdateOne actualiza un producto
-func (s *jsonStore) UpdateOne(product domain.Product) error {
-	products, err := s.loadProducts()
+func (s *jsonStore) UpdateOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	err := s.write(func() error {
+		products, err := s.loadProducts()
+		if err != nil {
+			return err
+		}
+		if codeValueTaken(products, product.Id, product.CodeValue) {
+			return ErrDuplicate
+		}
+		for i, p := range products {
+			if p.Id == product.Id {
+				if err := checkVersion(p, product); err != nil {
+					return err
+				}
+				product.Version++
+				products[i] = product
+				return s.saveProducts(products)
+			}
+		}
+		return ErrNotFound
+	})
 	if err != nil {
+		return domain.Product{}, err
+	}
+	return product, nil
+}
+
+// DeleteOne elimina un producto
+func (s *jsonStore) DeleteOne(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	for i, p := range products {
-		if p.Id == product.Id {
-			products[i] = product
-			return s.saveProducts(products)
+	return s.write(func() error {
+		products, err := s.loadProducts()
+		if err != nil {
+			return err
+		}
+		for i, p := range products {
+			if p.Id == id {
+				if err := s.advanceSequence(products); err != nil {
+					return err
+				}
+				products = append(products[:i], products[i+1:]...)
+				return s.saveProducts(products)
+			}
 		}
+		return ErrNotFound
+	})
+}
+
+// Apply aplica el lote con una sola lectura y una sola escritura del
+// archivo
+func (s *jsonStore) Apply(ctx context.Context, changes []Change, atomic bool) ([]ChangeResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	return errors.New("product not found")
+	var results []ChangeResult
+	err := s.write(func() error {
+		products, err := s.loadProducts()
+		if err != nil {
+			return err
+		}
+		var applied bool
+		products, applied, results, err = s.applyChanges(products, changes, atomic)
+		if err != nil || !applied {
+			return err
+		}
+		return s.saveProducts(products)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
-// DeleteOne elimina un producto
-func (s *jsonStore) DeleteOne(id int) error {
-	products, err := s.loadProducts()
+// applyChanges aplica el lote sobre products y persiste la secuencia si se
+// entregaron ids nuevos o se borro el id mas alto. Debe llamarse con el lock
+// de escritura tomado.
+func (s *jsonStore) applyChanges(products []domain.Product, changes []Change, atomic bool) ([]domain.Product, bool, []ChangeResult, error) {
+	seq, err := s.loadSequence()
 	if err != nil {
-		return err
+		return nil, false, nil, err
+	}
+	lastID := seq.LastID
+	if max := maxID(products); max > lastID {
+		lastID = max
+	}
+	products, lastID, results, applied := applyChanges(products, lastID, changes, atomic)
+	if applied && lastID != seq.LastID {
+		if err := s.saveSequence(sequence{LastID: lastID}); err != nil {
+			return nil, false, nil, err
+		}
+	}
+	return products, applied, results, nil
+}
+
+// Revision se deriva del hash del contenido del archivo
+func (s *jsonStore) Revision(ctx context.Context) (Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return Revision{}, err
+	}
+	var stamp fileStamp
+	err := s.read(func() error {
+		var err error
+		if stamp, err = statFile(s.pathToFile); err != nil {
+			return err
+		}
+		file, err := os.ReadFile(s.pathToFile)
+		if err != nil {
+			return err
+		}
+		stamp.hash = contentHash(file)
+		return nil
+	})
+	if err != nil {
+		return Revision{}, err
 	}
-	for i, p := range products {
-		if p.Id == id {
-			products = append(products[:i], products[i+1:]...)
-			return s.saveProducts(products)
+	return stamp.revision(), nil
+}
+
+// codeValueTaken indica si codeValue pertenece a un producto distinto de id
+func codeValueTaken(products []domain.Product, id int, codeValue string) bool {
+	for _, p := range products {
+		if p.CodeValue == codeValue && p.Id != id {
+			return true
 		}
 	}
-	return errors.New("product not found")
+	return false
 }
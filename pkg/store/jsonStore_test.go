@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestStore(t *testing.T, n int) (Store, string) {
+	t.Helper()
+	var products []domain.Product
+	for i := 1; i <= n; i++ {
+		products = append(products, domain.Product{
+			Id:         i,
+			Name:       fmt.Sprintf("product %d", i),
+			Quantity:   10,
+			CodeValue:  fmt.Sprintf("CV%d", i),
+			Expiration: domain.NewDate(2030, time.January, 1),
+			Price:      money.MustNew(1000, "ARS"),
+		})
+	}
+	bytes, err := json.Marshal(products)
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "products.json")
+	assert.Nil(t, os.WriteFile(path, bytes, 0644))
+	return NewStore(path), path
+}
+
+func TestJsonStore_ConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	const (
+		seed    = 100
+		adds    = 50
+		deletes = 30
+		updates = 50
+	)
+	s, path := createTestStore(t, seed)
+
+	var wg sync.WaitGroup
+	for i := 0; i < adds; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.AddOne(ctx, domain.Product{Name: "new", CodeValue: fmt.Sprintf("NEW%d", i), Quantity: 1, Price: money.MustNew(100, "ARS")})
+			assert.Nil(t, err)
+		}(i)
+	}
+	for i := 1; i <= deletes; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			assert.Nil(t, s.DeleteOne(ctx, id))
+		}(i)
+	}
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := seed - i%(seed-deletes)
+			p, err := s.GetOne(ctx, id)
+			assert.Nil(t, err)
+			p.Quantity++
+			_, err = s.UpdateOne(ctx, p)
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	file, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	var products []domain.Product
+	assert.Nil(t, json.Unmarshal(file, &products))
+	assert.Equal(t, seed+adds-deletes, len(products))
+
+	tmps, err := filepath.Glob(filepath.Join(filepath.Dir(path), ".*.tmp-*"))
+	assert.Nil(t, err)
+	assert.Empty(t, tmps)
+}
+
+func TestJsonStore_ConcurrentReadsDuringWrites(t *testing.T) {
+	ctx := context.Background()
+	s, _ := createTestStore(t, 200)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.AddOne(ctx, domain.Product{Name: "new", CodeValue: fmt.Sprintf("R%d", i)})
+			assert.Nil(t, err)
+		}(i)
+		go func() {
+			defer wg.Done()
+			products, err := s.GetAll(ctx)
+			assert.Nil(t, err)
+			assert.True(t, len(products) >= 200)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestJsonStore_AddOneNeverReusesIds(t *testing.T) {
+	ctx := context.Background()
+	s, path := createTestStore(t, 3)
+
+	assert.Nil(t, s.DeleteOne(ctx, 3))
+	_, err := s.AddOne(ctx, domain.Product{Name: "a", CodeValue: "A"})
+	assert.Nil(t, err)
+	products, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, products[len(products)-1].Id)
+
+	// la secuencia sobrevive a un reinicio aunque se borre el ultimo
+	assert.Nil(t, s.DeleteOne(ctx, 4))
+	s = NewStore(path)
+	_, err = s.AddOne(ctx, domain.Product{Name: "b", CodeValue: "B"})
+	assert.Nil(t, err)
+	products, err = s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, products[len(products)-1].Id)
+}
+
+func TestJsonStore_CheckIntegrityAndRepair(t *testing.T) {
+	ctx := context.Background()
+	products := []domain.Product{
+		{Id: 1, CodeValue: "A"}, {Id: 2, CodeValue: "B"}, {Id: 2, CodeValue: "C"}, {Id: 7, CodeValue: "D"},
+	}
+	bytes, _ := json.Marshal(products)
+	path := filepath.Join(t.TempDir(), "products.json")
+	assert.Nil(t, os.WriteFile(path, bytes, 0644))
+	checker := NewStore(path).(IntegrityChecker)
+
+	report, err := checker.CheckIntegrity(ctx)
+	assert.Nil(t, err)
+	assert.False(t, report.Ok())
+	assert.Equal(t, []DuplicateID{{Id: 2, Count: 2}}, report.Duplicates)
+
+	report, err = checker.Repair(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []Reassignment{{CodeValue: "C", OldID: 2, NewID: 8}}, report.Reassigned)
+
+	report, err = checker.CheckIntegrity(ctx)
+	assert.Nil(t, err)
+	assert.True(t, report.Ok())
+	assert.Equal(t, 8, report.LastID)
+}
+
+func TestJsonStore_RevisionFollowsContent(t *testing.T) {
+	ctx := context.Background()
+	s, path := createTestStore(t, 2)
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	before, err := s.(Revisioner).Revision(ctx)
+	assert.Nil(t, err)
+
+	// mismo tamaño y mismo mtime, contenido distinto
+	p, err := s.GetOne(ctx, 1)
+	assert.Nil(t, err)
+	p.Name = "product 9"
+	p, err = s.UpdateOne(ctx, p)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+	after, err := s.(Revisioner).Revision(ctx)
+	assert.Nil(t, err)
+	assert.NotEqual(t, before.Tag, after.Tag)
+
+	c, err := NewCachedStore(path, 0)
+	assert.Nil(t, err)
+	defer c.(*cachedStore).Close()
+	cached, err := c.(Revisioner).Revision(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, after.Tag, cached.Tag)
+	p.Name = "product 8"
+	_, err = c.UpdateOne(ctx, p)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+	cached, err = c.(Revisioner).Revision(ctx)
+	assert.Nil(t, err)
+	assert.NotEqual(t, after.Tag, cached.Tag)
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+func init() {
+	Register("memory", func(cfg Config) (Store, error) {
+		if cfg.DSN == "" {
+			return NewMemoryStore(nil), nil
+		}
+		return NewMemoryStoreFromFile(cfg.DSN)
+	})
+}
+
+// memoryStore guarda los productos solo en memoria. Sirve para tests y
+// entornos efimeros: nada de lo que se escribe sobrevive al proceso.
+type memoryStore struct {
+	mu       sync.RWMutex
+	products []domain.Product
+	lastID   int
+	// rev y modTime cambian con cada escritura
+	rev     int64
+	modTime time.Time
+}
+
+// NewMemoryStore crea un store en memoria con una copia de products
+func NewMemoryStore(products []domain.Product) Store {
+	s := &memoryStore{products: make([]domain.Product, len(products)), modTime: time.Now()}
+	copy(s.products, products)
+	s.lastID = maxID(products)
+	return s
+}
+
+// NewMemoryStoreFromFile crea un store en memoria con los productos del
+// fixture json en path; el archivo no se vuelve a tocar
+func NewMemoryStoreFromFile(path string) (Store, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var products []domain.Product
+	if err := json.Unmarshal(file, &products); err != nil {
+		return nil, err
+	}
+	return NewMemoryStore(products), nil
+}
+
+// indexOf devuelve la posicion del producto con id o -1
+func (s *memoryStore) indexOf(id int) int {
+	for i, p := range s.products {
+		if p.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// touch registra una escritura; requiere s.mu tomado
+func (s *memoryStore) touch() {
+	s.rev++
+	s.modTime = time.Now()
+}
+
+// Revision devuelve el contador de escrituras
+func (s *memoryStore) Revision(ctx context.Context) (Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return Revision{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Revision{Tag: strconv.FormatInt(s.rev, 10), ModTime: s.modTime}, nil
+}
+
+// GetAll devuelve una copia de todos los productos
+func (s *memoryStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	products := make([]domain.Product, len(s.products))
+	copy(products, s.products)
+	return products, nil
+}
+
+// GetOne devuelve un producto por su id
+func (s *memoryStore) GetOne(ctx context.Context, id int) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.indexOf(id)
+	if i < 0 {
+		return domain.Product{}, ErrNotFound
+	}
+	return s.products[i], nil
+}
+
+// AddOne agrega un nuevo producto
+func (s *memoryStore) AddOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if codeValueTaken(s.products, 0, product.CodeValue) {
+		return domain.Product{}, ErrDuplicate
+	}
+	s.lastID++
+	product.Id = s.lastID
+	product.Version = 1
+	s.products = append(s.products, product)
+	s.touch()
+	return product, nil
+}
+
+// UpdateOne actualiza un producto
+func (s *memoryStore) UpdateOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexOf(product.Id)
+	if i < 0 {
+		return domain.Product{}, ErrNotFound
+	}
+	if codeValueTaken(s.products, product.Id, product.CodeValue) {
+		return domain.Product{}, ErrDuplicate
+	}
+	if err := checkVersion(s.products[i], product); err != nil {
+		return domain.Product{}, err
+	}
+	product.Version++
+	s.products[i] = product
+	s.touch()
+	return product, nil
+}
+
+// DeleteOne elimina un producto
+func (s *memoryStore) DeleteOne(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexOf(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.products = append(s.products[:i], s.products[i+1:]...)
+	s.touch()
+	return nil
+}
+
+// Apply aplica el lote de una vez
+func (s *memoryStore) Apply(ctx context.Context, changes []Change, atomic bool) ([]ChangeResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	products, lastID, results, applied := applyChanges(s.products, s.lastID, changes, atomic)
+	if applied {
+		s.products, s.lastID = products, lastID
+		s.touch()
+	}
+	return results, nil
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+// sequence es el high-water-mark de ids asignados, persistido junto al
+// archivo de datos para que un id borrado nunca se vuelva a entregar
+type sequence struct {
+	LastID int `json:"last_id"`
+}
+
+// seqPath devuelve la ruta del archivo de secuencia
+func (s *jsonStore) seqPath() string {
+	return s.pathToFile + ".seq"
+}
+
+// loadSequence lee la secuencia persistida; si no existe arranca en 0
+func (s *jsonStore) loadSequence() (sequence, error) {
+	var seq sequence
+	file, err := os.ReadFile(s.seqPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return seq, nil
+	}
+	if err != nil {
+		return seq, err
+	}
+	err = json.Unmarshal(file, &seq)
+	return seq, err
+}
+
+// saveSequence guarda la secuencia de forma atomica
+func (s *jsonStore) saveSequence(seq sequence) error {
+	bytes, err := json.Marshal(seq)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.seqPath(), bytes, 0644)
+}
+
+// nextID reserva el siguiente id. Se toma el maximo entre la secuencia y
+// los ids presentes para tolerar archivos editados a mano o una secuencia
+// que quedo atrasada. La secuencia se persiste antes que los productos:
+// ante un crash a lo sumo queda un hueco, nunca un id repetido.
+// Debe llamarse con el lock de escritura tomado.
+func (s *jsonStore) nextID(products []domain.Product) (int, error) {
+	seq, err := s.loadSequence()
+	if err != nil {
+		return 0, err
+	}
+	if max := maxID(products); max > seq.LastID {
+		seq.LastID = max
+	}
+	seq.LastID++
+	if err := s.saveSequence(seq); err != nil {
+		return 0, err
+	}
+	return seq.LastID, nil
+}
+
+// advanceSequence adelanta la secuencia hasta el maximo id de products si
+// quedo por detras. Se llama antes de borrar para que el id eliminado quede
+// registrado aunque sea la primera escritura sobre el archivo.
+// Debe llamarse con el lock de escritura tomado.
+func (s *jsonStore) advanceSequence(products []domain.Product) error {
+	seq, err := s.loadSequence()
+	if err != nil {
+		return err
+	}
+	if max := maxID(products); max > seq.LastID {
+		seq.LastID = max
+		return s.saveSequence(seq)
+	}
+	return nil
+}
+
+// maxID devuelve el mayor id de la lista
+func maxID(products []domain.Product) int {
+	max := 0
+	for _, p := range products {
+		if p.Id > max {
+			max = p.Id
+		}
+	}
+	return max
+}
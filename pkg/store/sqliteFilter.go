@@ -0,0 +1,73 @@
+package store
+
+import (
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+)
+
+// sqliteFilter traduce un filtro a una condicion sql. ok es false si alguna
+// parte no tiene una traduccion con el mismo resultado que query.Match; en
+// ese caso el filtro se resuelve en memoria.
+func sqliteFilter(e query.Expr) (where string, args []any, ok bool) {
+	switch e := e.(type) {
+	case query.And:
+		return sqliteJoin(e.Left, e.Right, " AND ")
+	case query.Or:
+		return sqliteJoin(e.Left, e.Right, " OR ")
+	case query.Not:
+		where, args, ok := sqliteFilter(e.Expr)
+		return "NOT " + where, args, ok
+	case query.Cmp:
+		return sqliteCmp(e)
+	}
+	return "", nil, false
+}
+
+// sqliteJoin traduce los dos lados de un And o un Or
+func sqliteJoin(left, right query.Expr, op string) (string, []any, bool) {
+	l, lArgs, ok := sqliteFilter(left)
+	if !ok {
+		return "", nil, false
+	}
+	r, rArgs, ok := sqliteFilter(right)
+	if !ok {
+		return "", nil, false
+	}
+	return "(" + l + op + r + ")", append(lArgs, rArgs...), true
+}
+
+// sqliteCmp traduce una comparacion. Los nombres de campo ya fueron
+// validados por query.ParseFilter; los importes se comparan en unidades
+// menores y solo con los de su moneda, igual que query.Match.
+func sqliteCmp(c query.Cmp) (string, []any, bool) {
+	switch c.Op {
+	case query.OpContains:
+		// lower() de sqlite solo conoce ascii: con otros caracteres no
+		// coincidiria con strings.ToLower
+		if !isASCII(c.Value.(string)) {
+			return "", nil, false
+		}
+		return "(instr(lower(" + sqliteColumn(c.Field) + "), lower(?)) > 0)", []any{c.Value}, true
+	case query.OpPrefix:
+		prefix := c.Value.(string)
+		return "(substr(" + sqliteColumn(c.Field) + ", 1, length(?)) = ?)", []any{prefix, prefix}, true
+	}
+	if m, ok := c.Value.(money.Money); ok {
+		args := []any{moneyCurrency(m), m.Amount()}
+		if c.Op == query.OpNe {
+			return "(currency != ? OR " + sqliteColumn(c.Field) + " != ?)", args, true
+		}
+		return "(currency = ? AND " + sqliteColumn(c.Field) + " " + string(c.Op) + " ?)", args, true
+	}
+	return "(" + sqliteColumn(c.Field) + " " + string(c.Op) + " ?)", []any{c.Value}, true
+}
+
+// isASCII indica si s solo tiene caracteres ascii
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,626 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func init() {
+	Register("sqlite", func(cfg Config) (Store, error) {
+		return NewSQLiteStore(cfg.DSN)
+	})
+}
+
+// Importer lo implementan los stores que pueden cargar productos
+// conservando sus ids (por ejemplo al migrar desde products.json)
+type Importer interface {
+	Import(ctx context.Context, products []domain.Product) error
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS products (
+	id               INTEGER PRIMARY KEY AUTOINCREMENT,
+	name             TEXT    NOT NULL,
+	quantity         INTEGER NOT NULL,
+	code_value       TEXT    NOT NULL,
+	is_published     INTEGER NOT NULL DEFAULT 0,
+	expiration       TEXT    NOT NULL,
+	price            REAL    NOT NULL,
+	price_minor      INTEGER NOT NULL DEFAULT 0,
+	currency         TEXT    NOT NULL DEFAULT '',
+	version          INTEGER NOT NULL DEFAULT 0,
+	lifecycle_reason TEXT    NOT NULL DEFAULT '',
+	reserved         INTEGER NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX IF NOT EXISTS products_code_value ON products (code_value);
+`
+
+// sqliteMigrations agrega a bases existentes las columnas que el esquema fue
+// sumando; se aplican solo si la columna todavia no existe
+var sqliteMigrations = []struct {
+	column string
+	ddl    string
+}{
+	{"version", "ALTER TABLE products ADD COLUMN version INTEGER NOT NULL DEFAULT 0"},
+	{"lifecycle_reason", "ALTER TABLE products ADD COLUMN lifecycle_reason TEXT NOT NULL DEFAULT ''"},
+	{"price_minor", "ALTER TABLE products ADD COLUMN price_minor INTEGER NOT NULL DEFAULT 0"},
+	{"currency", "ALTER TABLE products ADD COLUMN currency TEXT NOT NULL DEFAULT ''"},
+	{"reserved", "ALTER TABLE products ADD COLUMN reserved INTEGER NOT NULL DEFAULT 0"},
+}
+
+// productColumns son las columnas que lee scanProduct. El precio se lee de
+// price_minor y currency; la columna price (REAL) se sigue escribiendo como
+// aproximacion para quien lea la base directamente.
+const productColumns = "id, name, quantity, code_value, is_published, expiration, price_minor, currency, version, lifecycle_reason, reserved"
+
+// sqliteColumns mapea los campos de query que no se llaman igual que su columna
+var sqliteColumns = map[string]string{"price": "price_minor"}
+
+// sqliteColumn devuelve la columna del campo field de query
+func sqliteColumn(field string) string {
+	if column, ok := sqliteColumns[field]; ok {
+		return column
+	}
+	return field
+}
+
+// priceCurrency devuelve la moneda con la que se guarda el precio de p
+func priceCurrency(p domain.Product) string {
+	return moneyCurrency(p.Price)
+}
+
+// moneyCurrency devuelve la moneda de m; el cero no tiene moneda y se guarda
+// en la moneda por defecto
+func moneyCurrency(m money.Money) string {
+	if currency := m.Currency(); currency != "" {
+		return currency
+	}
+	return money.DefaultCurrency()
+}
+
+// sqliteSortColumns devuelve las columnas por las que se ordena field: un
+// importe va por moneda y despues por unidades menores, como money.Compare
+func sqliteSortColumns(field string) []string {
+	if field == "price" {
+		return []string{"currency", sqliteColumn(field)}
+	}
+	return []string{sqliteColumn(field)}
+}
+
+// sqliteSortValues devuelve v como los valores de sus sqliteSortColumns
+func sqliteSortValues(v any) []any {
+	if m, ok := v.(money.Money); ok {
+		return []any{moneyCurrency(m), m.Amount()}
+	}
+	return []any{v}
+}
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore abre (o crea) la base sqlite en path y aplica el esquema
+func NewSQLiteStore(path string) (Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite admite un solo escritor; con una conexion las escrituras se
+	// serializan en el pool en lugar de fallar con SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA journal_mode = WAL; PRAGMA busy_timeout = 5000;"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrateSQLiteDates(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrateSQLitePrices(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// migrateSQLite aplica las sqliteMigrations que falten
+func migrateSQLite(db *sql.DB) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info('products')")
+	if err != nil {
+		return err
+	}
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, m := range sqliteMigrations {
+		if columns[m.column] {
+			continue
+		}
+		if _, err := db.Exec(m.ddl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSQLiteDates pasa a yyyy-mm-dd las fechas guardadas como dd/mm/yyyy
+func migrateSQLiteDates(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, expiration FROM products WHERE expiration LIKE '%/%'")
+	if err != nil {
+		return err
+	}
+	dates := map[int]domain.Date{}
+	for rows.Next() {
+		var id int
+		var date domain.Date
+		if err := rows.Scan(&id, &date); err != nil {
+			rows.Close()
+			return fmt.Errorf("product %d: %w", id, err)
+		}
+		dates[id] = date
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(dates) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for id, date := range dates {
+		if _, err := tx.Exec("UPDATE products SET expiration = ? WHERE id = ?", date, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// migrateSQLitePrices completa price_minor y currency en las filas escritas
+// cuando el precio era un float64, tomandolo como importe en la moneda por
+// defecto
+func migrateSQLitePrices(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, price FROM products WHERE currency = ''")
+	if err != nil {
+		return err
+	}
+	prices := map[int]money.Money{}
+	for rows.Next() {
+		var id int
+		var price float64
+		if err := rows.Scan(&id, &price); err != nil {
+			rows.Close()
+			return err
+		}
+		m, err := money.FromFloat(price, money.DefaultCurrency(), money.HalfEven)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("product %d: %w", id, err)
+		}
+		prices[id] = m
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(prices) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for id, price := range prices {
+		if _, err := tx.Exec("UPDATE products SET price_minor = ?, currency = ? WHERE id = ?", price, price.Currency(), id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close cierra la base de datos
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// rowScanner es la parte comun de *sql.Row y *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProduct lee un producto con las columnas de productColumns
+func scanProduct(row rowScanner) (domain.Product, error) {
+	var p domain.Product
+	var minor int64
+	var currency string
+	err := row.Scan(&p.Id, &p.Name, &p.Quantity, &p.CodeValue, &p.IsPublished, &p.Expiration, &minor, &currency, &p.Version, &p.LifecycleReason, &p.Reserved)
+	if err != nil {
+		return p, err
+	}
+	p.Price, err = money.New(minor, currency)
+	return p, err
+}
+
+// translateError convierte los errores de sqlite en los errores del paquete
+func translateError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// insertProducts inserta products conservando sus ids
+func insertProducts(ctx context.Context, tx *sql.Tx, products []domain.Product) error {
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO products ("+productColumns+", price) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range products {
+		if _, err := stmt.ExecContext(ctx, p.Id, p.Name, p.Quantity, p.CodeValue, p.IsPublished, p.Expiration, p.Price, priceCurrency(p), p.Version, p.LifecycleReason, p.Reserved, p.Price.Float64()); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+// Import agrega products conservando sus ids; si alguno falla (por ejemplo
+// un code_value repetido) no se importa ninguno
+func (s *sqliteStore) Import(ctx context.Context, products []domain.Product) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := insertProducts(ctx, tx, products); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAll devuelve todos los productos ordenados por id
+func (s *sqliteStore) GetAll(ctx context.Context) ([]domain.Product, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+productColumns+" FROM products ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	products := []domain.Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// GetOne devuelve un producto por su id
+func (s *sqliteStore) GetOne(ctx context.Context, id int) (domain.Product, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = ?", id)
+	p, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, ErrNotFound
+	}
+	return p, err
+}
+
+// sqlConn es la parte comun de *sql.DB y *sql.Tx
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// AddOne agrega un nuevo producto; el id lo asigna la base
+func (s *sqliteStore) AddOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	return addProduct(ctx, s.db, product)
+}
+
+// UpdateOne actualiza un producto si su version no cambio
+func (s *sqliteStore) UpdateOne(ctx context.Context, product domain.Product) (domain.Product, error) {
+	return updateProduct(ctx, s.db, product)
+}
+
+// DeleteOne elimina un producto
+func (s *sqliteStore) DeleteOne(ctx context.Context, id int) error {
+	_, err := deleteProduct(ctx, s.db, id, nil)
+	return err
+}
+
+// Apply aplica el lote en una transaccion. Sin atomic cada cambio corre en
+// su propio savepoint, asi el que falla se deshace sin tocar a los demas.
+func (s *sqliteStore) Apply(ctx context.Context, changes []Change, atomic bool) ([]ChangeResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	results := make([]ChangeResult, len(changes))
+	failed := false
+	for i, c := range changes {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT change"); err != nil {
+			return nil, err
+		}
+		r := applySQLiteChange(ctx, tx, c)
+		release := "RELEASE change"
+		if r.Err != nil {
+			failed = true
+			release = "ROLLBACK TO change; RELEASE change"
+		}
+		if _, err := tx.ExecContext(ctx, release); err != nil {
+			return nil, err
+		}
+		results[i] = r
+	}
+	if atomic && failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = ChangeResult{Err: ErrAborted}
+			}
+		}
+		return results, nil
+	}
+	return results, tx.Commit()
+}
+
+// applySQLiteChange ejecuta un cambio de un lote dentro de tx
+func applySQLiteChange(ctx context.Context, tx *sql.Tx, c Change) ChangeResult {
+	var p domain.Product
+	var err error
+	switch c.Op {
+	case OpCreate:
+		p, err = addProduct(ctx, tx, c.Product)
+	case OpUpdate:
+		p, err = updateProduct(ctx, tx, c.Product)
+	case OpDelete:
+		p, err = deleteProduct(ctx, tx, c.Id, &c.Version)
+	default:
+		err = errors.New("unknown change " + c.Op)
+	}
+	return ChangeResult{Product: p, Err: err}
+}
+
+// addProduct inserta un producto y completa su id y version
+func addProduct(ctx context.Context, db sqlConn, product domain.Product) (domain.Product, error) {
+	res, err := db.ExecContext(ctx,
+		"INSERT INTO products (name, quantity, code_value, is_published, expiration, price_minor, currency, price, version, lifecycle_reason, reserved) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)",
+		product.Name, product.Quantity, product.CodeValue, product.IsPublished, product.Expiration, product.Price, priceCurrency(product), product.Price.Float64(), product.LifecycleReason, product.Reserved,
+	)
+	if err != nil {
+		return domain.Product{}, translateError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return domain.Product{}, err
+	}
+	product.Id = int(id)
+	product.Version = 1
+	return product, nil
+}
+
+// updateProduct reemplaza un producto si su version no cambio
+func updateProduct(ctx context.Context, db sqlConn, product domain.Product) (domain.Product, error) {
+	res, err := db.ExecContext(ctx,
+		"UPDATE products SET name = ?, quantity = ?, code_value = ?, is_published = ?, expiration = ?, price_minor = ?, currency = ?, price = ?, lifecycle_reason = ?, reserved = ?, version = version + 1 WHERE id = ? AND version = ?",
+		product.Name, product.Quantity, product.CodeValue, product.IsPublished, product.Expiration, product.Price, priceCurrency(product), product.Price.Float64(), product.LifecycleReason, product.Reserved, product.Id, product.Version,
+	)
+	if err != nil {
+		return domain.Product{}, translateError(err)
+	}
+	if err := expectOneRow(res); err != nil {
+		// distinguimos un id inexistente de una version vieja
+		var version int
+		if db.QueryRowContext(ctx, "SELECT version FROM products WHERE id = ?", product.Id).Scan(&version) == nil {
+			return domain.Product{}, ErrVersionConflict
+		}
+		return domain.Product{}, err
+	}
+	product.Version++
+	return product, nil
+}
+
+// deleteProduct borra un producto y devuelve como estaba; con version, solo
+// si sigue en esa version
+func deleteProduct(ctx context.Context, db sqlConn, id int, version *int) (domain.Product, error) {
+	p, err := scanProduct(db.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if version != nil && *version != p.Version {
+		return domain.Product{}, ErrVersionConflict
+	}
+	res, err := db.ExecContext(ctx, "DELETE FROM products WHERE id = ? AND version = ?", id, p.Version)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if err := expectOneRow(res); err != nil {
+		if version != nil {
+			// cambio o se borro entre la lectura y el borrado
+			return domain.Product{}, ErrVersionConflict
+		}
+		return domain.Product{}, err
+	}
+	return p, nil
+}
+
+// List ejecuta la query en la base: el filtro, el orden y el paginado (por
+// offset o por cursor, con keyset sobre los criterios de orden) se resuelven
+// en sql. Si el filtro no se puede traducir se resuelve todo en memoria.
+func (s *sqliteStore) List(ctx context.Context, q query.Query) (query.Page, error) {
+	var filter string
+	var filterArgs []any
+	if q.Filter != nil {
+		var ok bool
+		if filter, filterArgs, ok = sqliteFilter(q.Filter); !ok {
+			products, err := s.GetAll(ctx)
+			if err != nil {
+				return query.Page{}, err
+			}
+			return query.Apply(products, q)
+		}
+	}
+
+	keys := q.OrderBy()
+	after, err := query.DecodeCursor(q)
+	if err != nil {
+		return query.Page{}, err
+	}
+
+	var page query.Page
+	count := "SELECT COUNT(*) FROM products"
+	if filter != "" {
+		count += " WHERE " + filter
+	}
+	if err := s.db.QueryRowContext(ctx, count, filterArgs...).Scan(&page.Total); err != nil {
+		return query.Page{}, err
+	}
+
+	stmt := "SELECT " + productColumns + " FROM products"
+	var conditions []string
+	args := append([]any{}, filterArgs...)
+	if filter != "" {
+		conditions = append(conditions, filter)
+	}
+	if after != nil {
+		where, whereArgs := keysetCondition(keys, after)
+		conditions = append(conditions, where)
+		args = append(args, whereArgs...)
+	}
+	if len(conditions) > 0 {
+		stmt += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	var order []string
+	for _, k := range keys {
+		// los nombres ya fueron validados por query.Parse y coinciden con las columnas
+		for _, column := range sqliteSortColumns(k.Field) {
+			if k.Desc {
+				column += " DESC"
+			}
+			order = append(order, column)
+		}
+	}
+	stmt += " ORDER BY " + strings.Join(order, ", ")
+	// pedimos uno de mas para saber si hay pagina siguiente
+	limit := -1
+	if q.Limit > 0 {
+		limit = q.Limit + 1
+	}
+	stmt += " LIMIT ? OFFSET ?"
+	args = append(args, limit, q.Offset)
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return query.Page{}, err
+	}
+	defer rows.Close()
+	page.Items = []domain.Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return query.Page{}, err
+		}
+		page.Items = append(page.Items, p)
+	}
+	if err := rows.Err(); err != nil {
+		return query.Page{}, err
+	}
+	if q.Limit > 0 && len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = query.EncodeCursor(q, page.Items[q.Limit-1])
+	}
+	return page, nil
+}
+
+// keysetCondition arma la condicion "viene despues de values" para un orden
+// con direcciones mixtas: (k1 > v1) OR (k1 = v1 AND k2 < v2) OR ...
+func keysetCondition(keys []query.SortKey, values []any) (string, []any) {
+	var columns []string
+	var desc []bool
+	var flat []any
+	for i, k := range keys {
+		for _, column := range sqliteSortColumns(k.Field) {
+			columns = append(columns, column)
+			desc = append(desc, k.Desc)
+		}
+		flat = append(flat, sqliteSortValues(values[i])...)
+	}
+	var ors []string
+	var args []any
+	for i, column := range columns {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, columns[j]+" = ?")
+			args = append(args, flat[j])
+		}
+		op := " > ?"
+		if desc[i] {
+			op = " < ?"
+		}
+		ands = append(ands, column+op)
+		args = append(args, flat[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// Revision resume la tabla en cantidad de filas, id maximo y suma de
+// versiones: cualquier alta, baja o modificacion cambia al menos uno. sqlite
+// no guarda la hora de modificacion, asi que ModTime queda en cero.
+func (s *sqliteStore) Revision(ctx context.Context) (Revision, error) {
+	var count, maxID, versions int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*), COALESCE(MAX(id), 0), COALESCE(SUM(version), 0) FROM products",
+	).Scan(&count, &maxID, &versions)
+	if err != nil {
+		return Revision{}, err
+	}
+	return Revision{Tag: fmt.Sprintf("%x-%x-%x", count, maxID, versions)}, nil
+}
+
+// expectOneRow traduce "ninguna fila afectada" en ErrNotFound
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/money"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStore_CRUD(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "products.db"))
+	assert.Nil(t, err)
+
+	err = s.(Importer).Import(ctx, []domain.Product{
+		{Id: 3, Name: "a", CodeValue: "A", Expiration: domain.NewDate(2030, time.January, 1)},
+		{Id: 7, Name: "b", CodeValue: "B", Expiration: domain.NewDate(2030, time.January, 1)},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, s.DeleteOne(ctx, 7))
+	_, err = s.AddOne(ctx, domain.Product{Name: "c", CodeValue: "C", Expiration: domain.NewDate(2030, time.January, 1), Price: money.MustNew(150, "ARS")})
+	assert.Nil(t, err)
+	products, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(products))
+	// autoincrement no reutiliza el id borrado
+	assert.Equal(t, 8, products[1].Id)
+
+	p, err := s.GetOne(ctx, 3)
+	assert.Nil(t, err)
+	p.IsPublished = true
+	_, err = s.UpdateOne(ctx, p)
+	assert.Nil(t, err)
+	p, err = s.GetOne(ctx, 3)
+	assert.Nil(t, err)
+	assert.True(t, p.IsPublished)
+
+	_, err = s.AddOne(ctx, domain.Product{Name: "dup", CodeValue: "A"})
+	assert.NotNil(t, err)
+	assert.NotNil(t, s.DeleteOne(ctx, 99))
+	_, err = s.GetOne(ctx, 99)
+	assert.NotNil(t, err)
+}
+
+func TestSQLiteStore_ListMatchesApply(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "products.db"))
+	assert.Nil(t, err)
+	var products []domain.Product
+	for i := 1; i <= 25; i++ {
+		currency := "ARS"
+		if i%5 == 0 {
+			currency = "USD"
+		}
+		products = append(products, domain.Product{
+			Id: i, Name: fmt.Sprintf("p%d", i%4), CodeValue: fmt.Sprintf("C%d", i),
+			Price: money.MustNew(int64(i%3)*100, currency), IsPublished: i%2 == 0, Expiration: domain.NewDate(2030, time.January, 1),
+		})
+	}
+	assert.Nil(t, s.(Importer).Import(ctx, products))
+
+	filters := []string{
+		"",
+		"price>=1 AND is_published=true",
+		`NOT name~"P1" OR code_value^=C2`,
+		"expiration>=2029-12-31",
+		"price<2 OR price!=1",
+	}
+	for _, filter := range filters {
+		for _, sort := range []string{"", "price,-name", "-is_published,price", "-id"} {
+			q, err := query.Parse(url.Values{"filter": {filter}, "sort": {sort}, "limit": {"4"}})
+			assert.Nil(t, err)
+			assertSameList(t, s, products, q)
+		}
+	}
+}
+
+// assertSameList recorre todas las paginas de q y compara el store con query.Apply
+func assertSameList(t *testing.T, s Store, products []domain.Product, q query.Query) {
+	ctx := context.Background()
+	for {
+		want, err := query.Apply(products, q)
+		assert.Nil(t, err)
+		got, err := s.(Lister).List(ctx, q)
+		assert.Nil(t, err)
+		assert.Equal(t, want, got)
+		if got.NextCursor == "" {
+			break
+		}
+		q.Cursor = got.NextCursor
+	}
+}
+
+func TestSQLiteFilter_Pushdown(t *testing.T) {
+	e, err := query.ParseFilter(`price>=100 AND (name~"cake" OR NOT code_value^=CK)`)
+	assert.Nil(t, err)
+	where, args, ok := sqliteFilter(e)
+	assert.True(t, ok)
+	assert.Equal(t, "((currency = ? AND price_minor >= ?) AND ((instr(lower(name), lower(?)) > 0) OR NOT (substr(code_value, 1, length(?)) = ?)))", where)
+	assert.Equal(t, []any{"ARS", int64(10000), "cake", "CK", "CK"}, args)
+
+	// las fechas se comparan como texto yyyy-mm-dd
+	e, err = query.ParseFilter("expiration<15/01/2024")
+	assert.Nil(t, err)
+	where, args, ok = sqliteFilter(e)
+	assert.True(t, ok)
+	assert.Equal(t, "(expiration < ?)", where)
+	assert.Equal(t, []any{domain.NewDate(2024, time.January, 15)}, args)
+}
+
+func TestSQLiteStore_MigratesFloatPrices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.db")
+	s, err := NewSQLiteStore(path)
+	assert.Nil(t, err)
+	s.(*sqliteStore).Close()
+
+	// una fila escrita cuando el precio era un float64
+	db, err := sql.Open("sqlite", path)
+	assert.Nil(t, err)
+	_, err = db.Exec(`INSERT INTO products (id, name, quantity, code_value, is_published, expiration, price, version) VALUES (1, 'a', 1, 'A', 0, '2030-01-01', 35.9, 1)`)
+	assert.Nil(t, err)
+	db.Close()
+
+	s, err = NewSQLiteStore(path)
+	assert.Nil(t, err)
+	defer s.(*sqliteStore).Close()
+	p, err := s.GetOne(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, money.MustNew(3590, "ARS"), p.Price)
+
+	page, err := s.(Lister).List(context.Background(), query.Query{Filter: query.Cmp{Field: "price", Op: query.OpGt, Value: money.MustNew(3589, "ARS")}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, page.Total)
+}
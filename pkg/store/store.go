@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/fgiudicatti-meli/web-server/pkg/query"
+)
+
+// Store es el contrato que cumple cualquier backend de productos. Todos los
+// metodos reciben el context del request y devuelven los errores tipados de
+// este paquete (ErrNotFound, ErrDuplicate, ErrVersionConflict) envueltos o tal cual, de modo que
+// quien llama puede usar errors.Is sin conocer el backend.
+type Store interface {
+	GetAll(ctx context.Context) ([]domain.Product, error)
+	GetOne(ctx context.Context, id int) (domain.Product, error)
+	// AddOne asigna el id y la version inicial y devuelve el producto tal
+	// como quedo guardado
+	AddOne(ctx context.Context, product domain.Product) (domain.Product, error)
+	// UpdateOne reemplaza el producto si product.Version es la guardada
+	UpdateOne(ctx context.Context, product domain.Product) (domain.Product, error)
+	DeleteOne(ctx context.Context, id int) error
+}
+
+var (
+	// ErrNotFound indica que no existe un producto con el id pedido
+	ErrNotFound = domain.NotFound("product_not_found", "product not found")
+	// ErrDuplicate indica que el code_value ya pertenece a otro producto
+	ErrDuplicate = domain.Conflict("code_value_taken", "code value already exists")
+	// ErrVersionConflict indica que el producto cambio desde que se leyo
+	ErrVersionConflict = domain.NewError(domain.KindPrecondition, "version_mismatch", "product version conflict")
+)
+
+// Revision identifica el estado completo del store en un momento dado
+type Revision struct {
+	// Tag cambia cada vez que se agrega, modifica o borra un producto
+	Tag string
+	// ModTime es el momento del ultimo cambio; cero si el backend no lo sabe
+	ModTime time.Time
+}
+
+// Revisioner lo implementan los stores que pueden informar su Revision sin
+// leer todos los productos, lo que permite responder 304 sin tocar los datos
+type Revisioner interface {
+	Revision(ctx context.Context) (Revision, error)
+}
+
+// Lister lo implementan los stores que saben ejecutar una query.Query (orden
+// y paginado) por su cuenta; para el resto se usa query.Apply sobre GetAll
+type Lister interface {
+	List(ctx context.Context, q query.Query) (query.Page, error)
+}
+
+// checkVersion compara la version que trae el llamador con la guardada
+func checkVersion(stored, product domain.Product) error {
+	if stored.Version != product.Version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Config es la configuracion generica con la que se abre un backend
+type Config struct {
+	// DSN indica donde viven los datos (ruta del archivo json, de la base
+	// sqlite, del fixture a cargar, etc.)
+	DSN string
+	// Options son parametros propios de cada backend
+	Options map[string]string
+}
+
+// Factory crea un Store a partir de la configuracion
+type Factory func(cfg Config) (Store, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register hace disponible un backend bajo name. Se llama desde el init del
+// paquete que implementa el backend; registrar dos veces el mismo nombre es
+// un error de programacion y entra en panico.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("store: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("store: Register called twice for backend " + name)
+	}
+	registry[name] = factory
+}
+
+// Open crea el Store registrado bajo name
+func Open(name string, cfg Config) (Store, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store backend %q (available: %v)", name, Backends())
+	}
+	return factory(cfg)
+}
+
+// Backends devuelve los nombres registrados ordenados
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
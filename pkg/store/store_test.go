@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen(t *testing.T) {
+	_, path := createTestStore(t, 2)
+
+	s, err := Open("json", Config{DSN: path})
+	assert.Nil(t, err)
+	products, err := s.GetAll(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(products))
+
+	s, err = Open("json", Config{DSN: path, Options: map[string]string{"cache": "true"}})
+	assert.Nil(t, err)
+	_, err = s.GetOne(context.Background(), 3)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = Open("nope", Config{})
+	assert.NotNil(t, err)
+	assert.Contains(t, Backends(), "sqlite")
+}
+
+func TestMemoryStore_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore([]domain.Product{{Id: 7, CodeValue: "SEED"}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.AddOne(ctx, domain.Product{CodeValue: fmt.Sprintf("C%d", i)})
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	products, err := s.GetAll(ctx)
+	assert.Nil(t, err)
+	ids := map[int]bool{}
+	for _, p := range products {
+		ids[p.Id] = true
+	}
+	assert.Equal(t, 51, len(ids))
+	assert.True(t, ids[57])
+}
+
+func TestStores_VersionConflict(t *testing.T) {
+	ctx := context.Background()
+	_, path := createTestStore(t, 2)
+	sqlite, err := NewSQLiteStore(filepath.Join(t.TempDir(), "products.db"))
+	assert.Nil(t, err)
+	stores := map[string]Store{
+		"json":   NewStore(path),
+		"memory": NewMemoryStore(nil),
+		"sqlite": sqlite,
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			p, err := s.AddOne(ctx, domain.Product{Name: "v", CodeValue: "VERSIONED"})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, p.Version)
+
+			stale := p
+			p.Name = "v2"
+			p, err = s.UpdateOne(ctx, p)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, p.Version)
+
+			_, err = s.UpdateOne(ctx, stale)
+			assert.ErrorIs(t, err, ErrVersionConflict)
+		})
+	}
+}
+
+func TestStores_Apply(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	_, path := createTestStore(t, 0)
+	_, cachedPath := createTestStore(t, 0)
+	cached, err := NewCachedStore(cachedPath, 0)
+	assert.Nil(t, err)
+	sqlite, err := NewSQLiteStore(filepath.Join(dir, "products.db"))
+	assert.Nil(t, err)
+	eventLog, err := NewEventLogStore(filepath.Join(dir, "products.log"), 0)
+	assert.Nil(t, err)
+	defer eventLog.(*eventLogStore).Close()
+	stores := map[string]Store{
+		"json":     NewStore(path),
+		"cached":   cached,
+		"memory":   NewMemoryStore(nil),
+		"sqlite":   sqlite,
+		"eventlog": eventLog,
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			batcher := s.(Batcher)
+			results, err := batcher.Apply(ctx, []Change{
+				{Op: OpCreate, Product: domain.Product{Name: "a", CodeValue: "A"}},
+				{Op: OpCreate, Product: domain.Product{Name: "b", CodeValue: "B"}},
+				{Op: OpCreate, Product: domain.Product{Name: "again", CodeValue: "A"}},
+			}, false)
+			assert.Nil(t, err)
+			assert.Nil(t, results[0].Err)
+			assert.Nil(t, results[1].Err)
+			assert.ErrorIs(t, results[2].Err, ErrDuplicate)
+			a, b := results[0].Product, results[1].Product
+			assert.Equal(t, 1, a.Version)
+			assert.NotEqual(t, a.Id, b.Id)
+
+			// todo o nada: el borrado inexistente aborta la modificacion
+			renamed := a
+			renamed.Name = "renamed"
+			results, err = batcher.Apply(ctx, []Change{
+				{Op: OpUpdate, Product: renamed},
+				{Op: OpDelete, Id: 999},
+			}, true)
+			assert.Nil(t, err)
+			assert.ErrorIs(t, results[0].Err, ErrAborted)
+			assert.ErrorIs(t, results[1].Err, ErrNotFound)
+			stored, err := s.GetOne(ctx, a.Id)
+			assert.Nil(t, err)
+			assert.Equal(t, "a", stored.Name)
+
+			results, err = batcher.Apply(ctx, []Change{
+				{Op: OpUpdate, Product: renamed},
+				{Op: OpUpdate, Product: renamed},
+				{Op: OpDelete, Id: b.Id, Version: b.Version + 1},
+				{Op: OpDelete, Id: b.Id, Version: b.Version},
+				{Op: OpCreate, Product: domain.Product{Name: "b again", CodeValue: "B"}},
+			}, false)
+			assert.Nil(t, err)
+			assert.Nil(t, results[0].Err)
+			assert.Equal(t, 2, results[0].Product.Version)
+			assert.ErrorIs(t, results[1].Err, ErrVersionConflict)
+			// el borrado con otra version no se aplica
+			assert.ErrorIs(t, results[2].Err, ErrVersionConflict)
+			assert.Nil(t, results[3].Err)
+			assert.Equal(t, "b", results[3].Product.Name)
+			assert.Nil(t, results[4].Err)
+			assert.Greater(t, results[4].Product.Id, b.Id)
+
+			products, err := s.GetAll(ctx)
+			assert.Nil(t, err)
+			names := []string{}
+			for _, p := range products {
+				names = append(names, p.Name)
+			}
+			assert.ElementsMatch(t, []string{"renamed", "b again"}, names)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+// Package storetest arma stores aislados para tests.
+package storetest
+
+import (
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/pkg/store"
+)
+
+// New devuelve un store en memoria cargado con el fixture json en path. El
+// fixture se lee en cada llamada y nunca se escribe, asi que cada test
+// arranca con los mismos datos sin importar el orden en que se ejecuten.
+func New(t testing.TB, fixture string) store.Store {
+	t.Helper()
+	s, err := store.NewMemoryStoreFromFile(fixture)
+	if err != nil {
+		t.Fatalf("storetest: loading fixture %s: %v", fixture, err)
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+// Package validate arma validaciones declarativas: cada Rule revisa un campo
+// de un valor con una lista de Check y Rules.Validate devuelve todas las
+// violaciones juntas, una por campo, en lugar de cortar en la primera.
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+)
+
+// Check es una condicion sobre un valor. Message no lleva el nombre del
+// campo, que se agrega al reportar: "is required", "must be positive".
+type Check[V any] struct {
+	Code    string
+	Message string
+	OK      func(v V) bool
+}
+
+// That crea un Check con una condicion cualquiera
+func That[V any](code, message string, ok func(v V) bool) Check[V] {
+	return Check[V]{Code: code, Message: message, OK: ok}
+}
+
+// Required falla con el valor cero del tipo
+func Required[V comparable]() Check[V] {
+	var zero V
+	return That("required", "is required", func(v V) bool { return v != zero })
+}
+
+type ordered interface {
+	~int | ~int64 | ~float64 | ~string
+}
+
+// Min falla con valores menores a min
+func Min[V ordered](min V) Check[V] {
+	return That("too_small", fmt.Sprintf("must be at least %v", min), func(v V) bool { return v >= min })
+}
+
+// Max falla con valores mayores a max
+func Max[V ordered](max V) Check[V] {
+	return That("too_large", fmt.Sprintf("must be at most %v", max), func(v V) bool { return v <= max })
+}
+
+// MaxLen falla con textos de mas de n caracteres
+func MaxLen(n int) Check[string] {
+	return That("too_long", fmt.Sprintf("must have at most %d characters", n), func(s string) bool { return utf8.RuneCountInString(s) <= n })
+}
+
+// Matches falla con textos que no cumplen re; description dice en el mensaje
+// que se espera, por ejemplo "letters and digits"
+func Matches(re *regexp.Regexp, description string) Check[string] {
+	return That("invalid_format", "must have only "+description, re.MatchString)
+}
+
+// Rule valida un campo de un T
+type Rule[T any] struct {
+	Field string
+	check func(v T) (domain.FieldError, bool)
+}
+
+// Field crea la regla del campo name: aplica checks en orden sobre el valor
+// que devuelve get y reporta el primero que falla. Con get devolviendo el
+// valor completo la regla puede comparar varios campos.
+func Field[T, V any](name string, get func(T) V, checks ...Check[V]) Rule[T] {
+	return Rule[T]{Field: name, check: func(v T) (domain.FieldError, bool) {
+		value := get(v)
+		for _, c := range checks {
+			if !c.OK(value) {
+				return domain.FieldError{Field: name, Code: c.Code, Message: name + " " + c.Message}, false
+			}
+		}
+		return domain.FieldError{}, true
+	}}
+}
+
+// Rules es un conjunto de reglas que se evaluan en orden
+type Rules[T any] []Rule[T]
+
+// Validate devuelve las violaciones de v, a lo sumo una por campo: si un
+// campo ya fallo sus otras reglas no se evaluan
+func (rs Rules[T]) Validate(v T) []domain.FieldError {
+	var errs []domain.FieldError
+	failed := map[string]bool{}
+	for _, r := range rs {
+		if failed[r.Field] {
+			continue
+		}
+		if e, ok := r.check(v); !ok {
+			errs = append(errs, e)
+			failed[r.Field] = true
+		}
+	}
+	return errs
+}
+
+// Only devuelve las reglas de los campos fields
+func (rs Rules[T]) Only(fields ...string) Rules[T] {
+	var only Rules[T]
+	for _, r := range rs {
+		for _, f := range fields {
+			if r.Field == f {
+				only = append(only, r)
+				break
+			}
+		}
+	}
+	return only
+}
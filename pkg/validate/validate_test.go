@@ -0,0 +1,45 @@
+package validate
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	Name  string
+	Min   int
+	Max   int
+	Notes string
+}
+
+func TestRules(t *testing.T) {
+	rules := Rules[item]{
+		Field("name", func(i item) string { return i.Name }, Required[string](), MaxLen(3), Matches(regexp.MustCompile(`^[a-z]+$`), "lowercase letters")),
+		Field("min", func(i item) int { return i.Min }, Min(0)),
+		Field("max", func(i item) int { return i.Max }, Max(10)),
+		Field("max", func(i item) item { return i }, That("below_min", "can't be lower than min", func(i item) bool { return i.Max >= i.Min })),
+	}
+	assert.Empty(t, rules.Validate(item{Name: "abc", Min: 1, Max: 2}))
+
+	assert.Equal(t, []domain.FieldError{
+		{Field: "name", Code: "required", Message: "name is required"},
+		{Field: "min", Code: "too_small", Message: "min must be at least 0"},
+	}, rules.Validate(item{Min: -1}))
+
+	// el primer check que falla es el unico del campo
+	assert.Equal(t, []domain.FieldError{
+		{Field: "name", Code: "too_long", Message: "name must have at most 3 characters"},
+		{Field: "max", Code: "too_large", Message: "max must be at most 10"},
+	}, rules.Validate(item{Name: "ABCD", Max: 11}))
+
+	assert.Equal(t, []domain.FieldError{
+		{Field: "name", Code: "invalid_format", Message: "name must have only lowercase letters"},
+		{Field: "max", Code: "below_min", Message: "max can't be lower than min"},
+	}, rules.Validate(item{Name: "ñú", Min: 5, Max: 4}))
+
+	assert.Empty(t, rules.Only("min").Validate(item{Min: 1}))
+	assert.Len(t, rules.Only("name", "max").Validate(item{Min: -1, Max: 11}), 2)
+}
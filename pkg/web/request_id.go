@@ -0,0 +1,56 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader es el header con el que llega y se devuelve el id de
+// correlacion de un request
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey es la clave del id en el contexto de gin
+const requestIDKey = "request_id"
+
+// maxRequestIDLength limita el id que acepta del cliente
+const maxRequestIDLength = 128
+
+// SetRequestID guarda el id de correlacion del request y lo devuelve en el
+// header X-Request-ID
+func SetRequestID(ctx *gin.Context, id string) {
+	ctx.Set(requestIDKey, id)
+	ctx.Header(RequestIDHeader, id)
+}
+
+// RequestID devuelve el id de correlacion del request, o "" si no tiene
+func RequestID(ctx *gin.Context) string {
+	return ctx.GetString(requestIDKey)
+}
+
+// NewRequestID genera un id de correlacion aleatorio
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID indica si el id que mando el cliente se puede usar: no
+// vacio, corto y solo con letras, numeros y - _ . para que no ensucie los
+// logs ni los headers
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -1,19 +1,49 @@
 package web
 
 import (
+	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
+	"github.com/fgiudicatti-meli/web-server/internal/domain"
 	"github.com/gin-gonic/gin"
 )
 
+// ProblemContentType es el content type de las respuestas fallidas
+const ProblemContentType = "application/problem+json"
+
+// ErrorResponse es el cuerpo de una respuesta fallida segun RFC 7807. Code
+// es un codigo estable para que los clientes distingan el error sin leer
+// Detail, Errors trae los campos invalidos de un error de validacion y
+// RequestID permite encontrar el request en los logs.
 type ErrorResponse struct {
-	Status  int    `json:"status"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
 }
 
 type Response struct {
-	Data interface{} `json:"data"`
+	Data       interface{} `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type Pagination struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit,omitempty"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Link es una entrada del header Link (RFC 8288)
+type Link struct {
+	Rel string
+	URL string
 }
 
 // Success escribe una respuesta exitosa
@@ -23,11 +53,78 @@ func Success(ctx *gin.Context, status int, data interface{}) {
 	})
 }
 
-// Failure escribe una respuesta fallida
-func Failure(ctx *gin.Context, status int, err error) {
-	ctx.JSON(status, ErrorResponse{
-		Message: err.Error(),
-		Status:  status,
-		Code:    http.StatusText(status),
+// SuccessPage escribe una respuesta paginada y publica los links de
+// navegacion en el header Link
+func SuccessPage(ctx *gin.Context, status int, data interface{}, pagination Pagination, links []Link) {
+	var header []string
+	for _, l := range links {
+		header = append(header, fmt.Sprintf("<%s>; rel=%q", l.URL, l.Rel))
+	}
+	if len(header) > 0 {
+		ctx.Header("Link", strings.Join(header, ", "))
+	}
+	ctx.JSON(status, Response{
+		Data:       data,
+		Pagination: &pagination,
 	})
 }
+
+// Failure escribe una respuesta fallida con el status indicado. Si err es
+// un domain.Error el codigo y los campos salen de el; si no, el codigo sale
+// del status. El detalle de los errores 5xx que no son de dominio solo va al
+// log, para no exponer fallas internas.
+func Failure(ctx *gin.Context, status int, err error) {
+	problem := ErrorResponse{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    err.Error(),
+		Instance:  ctx.Request.URL.Path,
+		Code:      ErrorCode(status, err),
+		RequestID: RequestID(ctx),
+	}
+	if e, ok := domain.AsError(err); ok {
+		problem.Errors = e.Fields
+	} else if status >= http.StatusInternalServerError {
+		log.Printf("request %s %s %s: %v", problem.RequestID, ctx.Request.Method, problem.Instance, err)
+		problem.Detail = problem.Title
+	}
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.JSON(status, problem)
+}
+
+// Error escribe una respuesta fallida con el status que corresponde a la
+// clase del domain.Error de err; un error que no es de dominio es un 500
+func Error(ctx *gin.Context, err error) {
+	Failure(ctx, Status(err), err)
+}
+
+// Status devuelve el status http de la clase del domain.Error de err
+func Status(err error) int {
+	e, ok := domain.AsError(err)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	switch e.Kind {
+	case domain.KindInvalid:
+		return http.StatusBadRequest
+	case domain.KindNotFound:
+		return http.StatusNotFound
+	case domain.KindConflict:
+		return http.StatusConflict
+	case domain.KindPrecondition:
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorCode devuelve el codigo estable de err: el del domain.Error o, si no
+// es de dominio, el texto del status en minusculas y con _, por ejemplo
+// "not_found"
+func ErrorCode(status int, err error) string {
+	if e, ok := domain.AsError(err); ok {
+		return e.Code
+	}
+	text := strings.ToLower(http.StatusText(status))
+	return strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+}